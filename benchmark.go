@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+// benchmarkReport holds the throughput of a scan measured by -benchmark.
+type benchmarkReport struct {
+	Nodes   int           // Nodes is the number of nodes scanned.
+	Files   int           // Files is the number of logs read, local files or remote logs.
+	Bytes   int64         // Bytes is the number of bytes the parser read from the logs, after decompression.
+	Entries int           // Entries is the number of entries left after filtering.
+	Scan    time.Duration // Scan is the time spent reading, parsing and filtering the logs.
+	Sort    time.Duration // Sort is the time spent sorting the entries.
+}
+
+// runBenchmark scans, filters and sorts the logs of the nodes like a normal run, timing each phase instead of printing
+// entries. After -timeout, it reports on the entries collected so far.
+func runBenchmark(s scanSetup, sortFunc func(LogEntries)) (benchmarkReport, error) {
+	ctx, cancel := pipeline.WithScanTimeout(s.ctx, s.timeout)
+	defer cancel()
+	report := benchmarkReport{Nodes: len(s.nodes)}
+	var filesRead, bytesRead int64
+	opts := s.opts
+	opts.FilesRead, opts.BytesRead = &filesRead, &bytesRead
+
+	var entries LogEntries
+	start := time.Now()
+	err := pipeline.StreamEntries(ctx, s.nodes, s.topLevelDir, s.queries, opts, pipeline.EntryBufferSize, func(entry *LogEntry) {
+		entries = append(entries, entry)
+	})
+	s.checkScan(ctx, len(entries))
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return report, err
+	}
+	entries = s.filter(entries)
+	report.Scan = time.Since(start)
+	report.Files = int(atomic.LoadInt64(&filesRead))
+	report.Bytes = atomic.LoadInt64(&bytesRead)
+	report.Entries = len(entries)
+
+	start = time.Now()
+	sortFunc(entries)
+	report.Sort = time.Since(start)
+
+	return report, nil
+}
+
+// Write writes the report to w, one metric per line.
+func (r benchmarkReport) Write(w io.Writer) error {
+	scanSeconds := r.Scan.Seconds()
+	var entriesPerSec, mbPerSec float64
+	if scanSeconds > 0 {
+		entriesPerSec = float64(r.Entries) / scanSeconds
+		mbPerSec = float64(r.Bytes) / (1 << 20) / scanSeconds
+	}
+
+	_, err := fmt.Fprintf(w, "nodes: %d\nfiles: %d\nbytes: %d\nentries: %d\nscan: %s\nsort: %s\ntotal: %s\nentries/sec: %.0f\nMB/sec: %.2f\n",
+		r.Nodes, r.Files, r.Bytes, r.Entries, r.Scan, r.Sort, r.Scan+r.Sort, entriesPerSec, mbPerSec)
+	return err
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+func TestRunBenchmark(t *testing.T) {
+	topLevelDir := t.TempDir()
+	content := "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n" +
+		"WARN  [main] 2023-07-14 16:00:01,000 Server.java:10 - Slow\n"
+	logFile := writeSystemLog(t, topLevelDir, "10.0.0.1", content)
+	writeSystemLog(t, topLevelDir, "10.0.0.2", content)
+	nodes := []Node{{Address: "10.0.0.1"}, {Address: "10.0.0.2"}, {Address: "10.0.0.3"}}
+
+	// the rotated log counts its decompressed bytes
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(filepath.Dir(logFile), "system.log.1.gz"), gz.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	setup := scanSetup{
+		ctx:         context.Background(),
+		nodes:       nodes,
+		topLevelDir: topLevelDir,
+		opts:        pipeline.ScanOptions{LogFiles: []string{"system.log", "system.log.1.gz"}},
+		filter:      pipeline.EntryFilters{MinLevel: WARN}.Apply,
+	}
+	report, err := runBenchmark(setup, func(entries LogEntries) { sort.Sort(ByDate{LogEntries: entries}) })
+	if err != nil {
+		t.Fatalf("runBenchmark() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	wantLines := []string{
+		`(?m)^nodes: 3$`,
+		`(?m)^files: 3$`,
+		`(?m)^bytes: ` + strconv.Itoa(3*len(content)) + `$`,
+		`(?m)^entries: 3$`,
+		`(?m)^scan: \S+$`,
+		`(?m)^sort: \S+$`,
+		`(?m)^total: \S+$`,
+		`(?m)^entries/sec: \d+$`,
+		`(?m)^MB/sec: \d+\.\d{2}$`,
+	}
+	for _, want := range wantLines {
+		if !regexp.MustCompile(want).MatchString(buf.String()) {
+			t.Errorf("Expected the report to match %s, got:\n%s", want, buf.String())
+		}
+	}
+}
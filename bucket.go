@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Bucket holds the entries dated within a time window.
+type Bucket struct {
+	Start   time.Time  // Start is the inclusive start of the window, a multiple of its width.
+	End     time.Time  // End is the exclusive end of the window.
+	Entries LogEntries // Entries are the entries of the window, sorted by date.
+}
+
+// bucketEntries groups the entries into consecutive windows of the given width, aligned on multiples of the width
+// since the zero time. Only windows holding entries are returned, in date order.
+func bucketEntries(entries LogEntries, width time.Duration) []Bucket {
+	sorted := append(LogEntries(nil), entries...)
+	sort.Stable(ByDate{LogEntries: sorted})
+
+	var buckets []Bucket
+	for _, entry := range sorted {
+		start := entry.Date.Truncate(width)
+		if len(buckets) == 0 || !buckets[len(buckets)-1].Start.Equal(start) {
+			buckets = append(buckets, Bucket{Start: start, End: start.Add(width)})
+		}
+		buckets[len(buckets)-1].Entries = append(buckets[len(buckets)-1].Entries, entry)
+	}
+	return buckets
+}
+
+// PrintBucketDetail writes, for each bucket, a header with its window and entry count followed by its first samples
+// entries.
+func PrintBucketDetail(w io.Writer, buckets []Bucket, samples int, opts FormatOptions) error {
+	for _, bucket := range buckets {
+		if _, err := fmt.Fprintf(w, "=== %s - %s: %d entries ===\n", bucket.Start.Format(time.RFC3339), bucket.End.Format(time.RFC3339), len(bucket.Entries)); err != nil {
+			return err
+		}
+		for i, entry := range bucket.Entries {
+			if i == samples {
+				break
+			}
+			if err := FormatEntry(w, entry, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintBucketDetail(t *testing.T) {
+	start := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	entry := func(offset time.Duration, message string) *LogEntry {
+		return &LogEntry{LogLevel: WARN, Date: start.Add(offset), NodeIP: "10.0.0.1", FilePath: "system.log", Message: message}
+	}
+	entries := LogEntries{
+		entry(12*time.Minute, "Timed out after 3000 ms"),
+		entry(time.Minute, "Timed out after 1000 ms"),
+		entry(2*time.Minute, "Timed out after 2000 ms"),
+		entry(3*time.Minute, "Compacted 4 sstables"),
+	}
+
+	buckets := bucketEntries(entries, 5*time.Minute)
+	if len(buckets) != 2 {
+		t.Fatalf("Expected 2 non-empty buckets, got %d", len(buckets))
+	}
+
+	var buf bytes.Buffer
+	if err := PrintBucketDetail(&buf, buckets, 2, FormatOptions{Format: FormatText}); err != nil {
+		t.Fatalf("PrintBucketDetail() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []struct{ prefix, suffix string }{
+		{"=== 2023-07-14T16:00:00Z - 2023-07-14T16:05:00Z: 3 entries ===", ""},
+		{"", "Timed out after 1000 ms"},
+		{"", "Timed out after 2000 ms"},
+		{"=== 2023-07-14T16:10:00Z - 2023-07-14T16:15:00Z: 1 entries ===", ""},
+		{"", "Timed out after 3000 ms"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d lines, got:\n%s", len(want), buf.String())
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, want[i].prefix) || !strings.HasSuffix(line, want[i].suffix) {
+			t.Errorf("Expected line %d to look like %q...%q, got %q", i, want[i].prefix, want[i].suffix, line)
+		}
+	}
+}
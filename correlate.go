@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Trace is a group of entries sharing a correlation ID, in chronological order.
+type Trace struct {
+	ID      string     // ID is the correlation ID shared by the entries.
+	Entries LogEntries // Entries are the entries of the trace, sorted by date.
+}
+
+// Latency returns the time elapsed between the first and the last entry of the trace.
+func (t Trace) Latency() time.Duration {
+	return t.Entries[len(t.Entries)-1].Date.Sub(t.Entries[0].Date)
+}
+
+// correlationID returns the ID re extracts from the message of the entry: its first capture group if it has one,
+// otherwise the whole match. It returns false if re doesn't match.
+func correlationID(entry *LogEntry, re *regexp.Regexp) (string, bool) {
+	match := re.FindStringSubmatch(entry.Message)
+	if match == nil {
+		return "", false
+	}
+	if len(match) > 1 {
+		return match[1], match[1] != ""
+	}
+	return match[0], true
+}
+
+// correlateEntries groups the entries by the correlation ID re extracts from their message, across nodes. Entries
+// without an ID are dropped. Traces are sorted by the date of their first entry, then by ID.
+func correlateEntries(entries LogEntries, re *regexp.Regexp) []Trace {
+	byID := make(map[string]LogEntries)
+	for _, entry := range entries {
+		if id, ok := correlationID(entry, re); ok {
+			byID[id] = append(byID[id], entry)
+		}
+	}
+
+	traces := make([]Trace, 0, len(byID))
+	for id, traceEntries := range byID {
+		sort.Stable(ByDate{LogEntries: traceEntries})
+		traces = append(traces, Trace{ID: id, Entries: traceEntries})
+	}
+	sort.Slice(traces, func(i, j int) bool {
+		first, other := traces[i].Entries[0].Date, traces[j].Entries[0].Date
+		if !first.Equal(other) {
+			return first.Before(other)
+		}
+		return traces[i].ID < traces[j].ID
+	})
+	return traces
+}
+
+// PrintTraces writes each trace to w under a header naming its ID, with its entries formatted with opts.
+func PrintTraces(w io.Writer, traces []Trace, opts FormatOptions) error {
+	for _, trace := range traces {
+		nodeSet := make(map[string]struct{})
+		for _, entry := range trace.Entries {
+			nodeSet[entry.NodeIP] = struct{}{}
+		}
+		if _, err := fmt.Fprintf(w, "=== %s (%d entries, %d nodes) ===\n", trace.ID, len(trace.Entries), len(nodeSet)); err != nil {
+			return err
+		}
+		for _, entry := range trace.Entries {
+			if err := FormatEntry(w, entry, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// slowTraces returns the traces with a latency of at least threshold, slowest first, then by ID.
+func slowTraces(traces []Trace, threshold time.Duration) []Trace {
+	var slow []Trace
+	for _, trace := range traces {
+		if trace.Latency() >= threshold {
+			slow = append(slow, trace)
+		}
+	}
+	sort.Slice(slow, func(i, j int) bool {
+		if slow[i].Latency() != slow[j].Latency() {
+			return slow[i].Latency() > slow[j].Latency()
+		}
+		return slow[i].ID < slow[j].ID
+	})
+	return slow
+}
+
+// PrintLatencies writes a line per trace to w with its ID, latency, number of entries and the dates of its first and
+// last entries.
+func PrintLatencies(w io.Writer, traces []Trace) error {
+	for _, trace := range traces {
+		first, last := trace.Entries[0], trace.Entries[len(trace.Entries)-1]
+		if _, err := fmt.Fprintf(w, "%s: %s (%d entries) %s %s:%d -> %s %s:%d\n", trace.ID, trace.Latency(), len(trace.Entries),
+			first.Date.Format(time.RFC3339Nano), first.NodeIP, first.LineNumber,
+			last.Date.Format(time.RFC3339Nano), last.NodeIP, last.LineNumber); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCorrelateEntries(t *testing.T) {
+	start := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	session := "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+	other := "0e02b2c3-58cc-4372-a567-f47ac10bd479"
+	entries := LogEntries{
+		{LogLevel: INFO, Date: start.Add(2 * time.Second), NodeIP: "10.0.0.2", Message: "Read response sent for session " + session},
+		{LogLevel: INFO, Date: start.Add(3 * time.Second), NodeIP: "10.0.0.1", Message: "Request complete for session " + other},
+		{LogLevel: INFO, Date: start, NodeIP: "10.0.0.1", Message: "Executing read for session " + session},
+		{LogLevel: WARN, Date: start.Add(time.Second), NodeIP: "10.0.0.1", Message: "Dropped mutations"},
+		{LogLevel: INFO, Date: start.Add(time.Second), NodeIP: "10.0.0.2", Message: "Read received for session " + session},
+	}
+	re := regexp.MustCompile(`session ([0-9a-f-]{36})`)
+
+	traces := correlateEntries(entries, re)
+	if len(traces) != 2 {
+		t.Fatalf("Expected 2 traces, got %d", len(traces))
+	}
+	if traces[0].ID != session || len(traces[0].Entries) != 3 {
+		t.Fatalf("Expected the first trace to hold the 3 entries of %s, got %+v", session, traces[0])
+	}
+	wantNodes := []string{"10.0.0.1", "10.0.0.2", "10.0.0.2"}
+	for i, entry := range traces[0].Entries {
+		if entry.NodeIP != wantNodes[i] || !entry.Date.Equal(start.Add(time.Duration(i)*time.Second)) {
+			t.Errorf("Expected entry %d from %s at %v, got %s at %v", i, wantNodes[i], start.Add(time.Duration(i)*time.Second), entry.NodeIP, entry.Date)
+		}
+	}
+	if traces[1].ID != other || len(traces[1].Entries) != 1 {
+		t.Errorf("Expected the second trace to hold the entry of %s, got %+v", other, traces[1])
+	}
+
+	var buf bytes.Buffer
+	if err := PrintTraces(&buf, traces, FormatOptions{Format: FormatText}); err != nil {
+		t.Fatalf("PrintTraces() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 6 || lines[0] != "=== "+session+" (3 entries, 2 nodes) ===" || lines[4] != "=== "+other+" (1 entries, 1 nodes) ===" {
+		t.Errorf("Unexpected trace output:\n%s", buf.String())
+	}
+}
+
+func TestCorrelationIDWholeMatch(t *testing.T) {
+	entry := &LogEntry{Message: "Tracing session abc123 started"}
+	if id, ok := correlationID(entry, regexp.MustCompile(`abc\d+`)); !ok || id != "abc123" {
+		t.Errorf("Expected ID abc123, got %q (found: %v)", id, ok)
+	}
+	if _, ok := correlationID(entry, regexp.MustCompile(`xyz\d+`)); ok {
+		t.Errorf("Expected no ID when the regex doesn't match")
+	}
+}
+
+func TestSlowTraces(t *testing.T) {
+	start := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	entries := LogEntries{
+		{Date: start, NodeIP: "10.0.0.1", LineNumber: 10, Message: "Request req-1 started"},
+		{Date: start.Add(1200 * time.Millisecond), NodeIP: "10.0.0.2", LineNumber: 20, Message: "Request req-1 completed"},
+		{Date: start.Add(100 * time.Millisecond), NodeIP: "10.0.0.1", LineNumber: 11, Message: "Request req-2 started"},
+		{Date: start.Add(150 * time.Millisecond), NodeIP: "10.0.0.1", LineNumber: 12, Message: "Request req-2 completed"},
+		{Date: start.Add(200 * time.Millisecond), NodeIP: "10.0.0.3", LineNumber: 30, Message: "Request req-3 started"},
+		{Date: start.Add(900 * time.Millisecond), NodeIP: "10.0.0.3", LineNumber: 31, Message: "Request req-3 completed"},
+		{Date: start.Add(300 * time.Millisecond), NodeIP: "10.0.0.3", LineNumber: 32, Message: "Request req-4 started"},
+	}
+	traces := correlateEntries(entries, regexp.MustCompile(`req-\d+`))
+
+	latencies := make(map[string]time.Duration)
+	for _, trace := range traces {
+		latencies[trace.ID] = trace.Latency()
+	}
+	want := map[string]time.Duration{"req-1": 1200 * time.Millisecond, "req-2": 50 * time.Millisecond, "req-3": 700 * time.Millisecond, "req-4": 0}
+	for id, latency := range want {
+		if latencies[id] != latency {
+			t.Errorf("Expected a latency of %v for %s, got %v", latency, id, latencies[id])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := PrintLatencies(&buf, slowTraces(traces, 500*time.Millisecond)); err != nil {
+		t.Fatalf("PrintLatencies() error = %v", err)
+	}
+	wantOutput := "req-1: 1.2s (2 entries) 2023-07-14T16:00:00Z 10.0.0.1:10 -> 2023-07-14T16:00:01.2Z 10.0.0.2:20\n" +
+		"req-3: 700ms (2 entries) 2023-07-14T16:00:00.2Z 10.0.0.3:30 -> 2023-07-14T16:00:00.9Z 10.0.0.3:31\n"
+	if buf.String() != wantOutput {
+		t.Errorf("PrintLatencies() = %q, want %q", buf.String(), wantOutput)
+	}
+}
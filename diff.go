@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// NodeDiff holds the entries of a node whose signatures only appear on one side of a bundle comparison.
+type NodeDiff struct {
+	Address    string     // Address is the address of the node.
+	OnlyBefore LogEntries // OnlyBefore holds one entry per signature only found in the first bundle.
+	OnlyAfter  LogEntries // OnlyAfter holds one entry per signature only found in the second bundle.
+}
+
+// uniqueSignatures returns the first entry (by date) for each signature in entries that is not present in other.
+func uniqueSignatures(entries, other LogEntries) LogEntries {
+	otherHashes := make(map[string]struct{}, len(other))
+	for _, entry := range other {
+		otherHashes[entry.Hash()] = struct{}{}
+	}
+
+	sorted := append(LogEntries(nil), entries...)
+	sort.Stable(ByDate{LogEntries: sorted})
+
+	var unique LogEntries
+	seen := make(map[string]struct{})
+	for _, entry := range sorted {
+		hash := entry.Hash()
+		if _, ok := otherHashes[hash]; ok {
+			continue
+		}
+		if _, ok := seen[hash]; ok {
+			continue
+		}
+		seen[hash] = struct{}{}
+		unique = append(unique, entry)
+	}
+	return unique
+}
+
+// DiffBundles compares the entries of two bundles node by node and returns, sorted by node address, the nodes that have
+// signatures unique to either side.
+func DiffBundles(before, after map[string]LogEntries) []NodeDiff {
+	addrSet := make(map[string]struct{})
+	for addr := range before {
+		addrSet[addr] = struct{}{}
+	}
+	for addr := range after {
+		addrSet[addr] = struct{}{}
+	}
+
+	addrs := make([]string, 0, len(addrSet))
+	for addr := range addrSet {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	var diffs []NodeDiff
+	for _, addr := range addrs {
+		diff := NodeDiff{
+			Address:    addr,
+			OnlyBefore: uniqueSignatures(before[addr], after[addr]),
+			OnlyAfter:  uniqueSignatures(after[addr], before[addr]),
+		}
+		if len(diff.OnlyBefore) > 0 || len(diff.OnlyAfter) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs
+}
+
+// PrintDiff writes the bundle differences to w, prefixing entries only in the first bundle with "-" and entries only in
+// the second bundle with "+".
+func PrintDiff(w io.Writer, diffs []NodeDiff) error {
+	for _, diff := range diffs {
+		if _, err := fmt.Fprintf(w, "%s:\n", diff.Address); err != nil {
+			return err
+		}
+		for _, entry := range diff.OnlyBefore {
+			if _, err := fmt.Fprintf(w, "- %s\n", entry.Message); err != nil {
+				return err
+			}
+		}
+		for _, entry := range diff.OnlyAfter {
+			if _, err := fmt.Fprintf(w, "+ %s\n", entry.Message); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+func TestDiffBundles(t *testing.T) {
+	before := t.TempDir()
+	after := t.TempDir()
+	nodes := []Node{{Address: "10.0.0.1", Datacenter: "DC1"}, {Address: "10.0.0.2", Datacenter: "DC1"}}
+
+	writeSystemLog(t, before, "10.0.0.1", "INFO  [main] 2023-07-05 13:00:00,000 Server.java:10 - Starting listening for clients\n"+
+		"WARN  [main] 2023-07-05 13:00:01,000 Gossiper.java:20 - Node /10.0.0.2 is down\n")
+	writeSystemLog(t, after, "10.0.0.1", "INFO  [main] 2023-07-06 13:00:00,000 Server.java:10 - Starting listening for clients\n"+
+		"ERROR [main] 2023-07-06 13:00:02,000 Compaction.java:30 - Compaction failed\n")
+	writeSystemLog(t, before, "10.0.0.2", "INFO  [main] 2023-07-05 13:00:00,000 Server.java:10 - Starting listening for clients\n")
+	writeSystemLog(t, after, "10.0.0.2", "INFO  [main] 2023-07-06 14:00:00,000 Server.java:10 - Starting listening for clients\n")
+
+	diffs := DiffBundles(pipeline.CollectNodeEntries(context.Background(), nodes, before, nil, pipeline.ScanOptions{}), pipeline.CollectNodeEntries(context.Background(), nodes, after, nil, pipeline.ScanOptions{}))
+
+	if len(diffs) != 1 {
+		t.Fatalf("Expected 1 node with differences, got %d", len(diffs))
+	}
+	if diffs[0].Address != "10.0.0.1" {
+		t.Errorf("Expected differences for node 10.0.0.1, got %s", diffs[0].Address)
+	}
+	if len(diffs[0].OnlyBefore) != 1 || diffs[0].OnlyBefore[0].LogLevel != WARN {
+		t.Errorf("Expected the WARN entry to only be in the first bundle, got %v", diffs[0].OnlyBefore)
+	}
+	if len(diffs[0].OnlyAfter) != 1 || diffs[0].OnlyAfter[0].LogLevel != ERROR {
+		t.Errorf("Expected the ERROR entry to only be in the second bundle, got %v", diffs[0].OnlyAfter)
+	}
+
+	var buf bytes.Buffer
+	if err := PrintDiff(&buf, diffs); err != nil {
+		t.Fatalf("PrintDiff() error = %v", err)
+	}
+	want := "10.0.0.1:\n" +
+		"- WARN  [main] 2023-07-05 13:00:01,000 Gossiper.java:20 - Node /10.0.0.2 is down\n" +
+		"+ ERROR [main] 2023-07-06 13:00:02,000 Compaction.java:30 - Compaction failed\n"
+	if buf.String() != want {
+		t.Errorf("PrintDiff() = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github/kenjords/wetlog/internal/pipeline"
+	"github/kenjords/wetlog/pkg/wetlog"
+)
+
+// defaultMergeSortBuffer is the default -merge-sort-buffer in MiB, which bounds the memory used to sort the entries
+// printed one at a time by date.
+const defaultMergeSortBuffer = 256
+
+// entryOverhead approximates the memory used by a LogEntry besides its strings and maps.
+const entryOverhead = 256
+
+// approxEntrySize approximates the memory used by the entry, to keep the buffer of a mergeSorter within its budget.
+func approxEntrySize(e *LogEntry) int {
+	size := entryOverhead + len(e.NodeIP) + len(e.Datacenter) + len(e.FilePath) + len(e.Message)
+	for name := range e.Metrics {
+		size += len(name) + 8
+	}
+	for key, value := range e.Fields {
+		size += len(key) + len(value)
+	}
+	return size
+}
+
+// maxMergeFanIn is the number of runs a mergeSorter reads at once, which bounds the number of files it keeps open.
+const maxMergeFanIn = 64
+
+// mergeSorter sorts entries by date within a memory budget. Entries are buffered until the budget is reached, then the
+// buffer is sorted and spilled to a temporary file as a run. Merge combines the runs, maxMergeFanIn at a time. Entries
+// with the same date keep the order they were added in.
+type mergeSorter struct {
+	dir      string     // dir is where the runs are written, the default temporary directory if empty.
+	budget   int        // budget is the approximate number of bytes of entries buffered before spilling a run.
+	buf      LogEntries // buf holds the entries added since the last spill.
+	bufBytes int        // bufBytes is the approximate size of the entries in buf.
+	runs     []string   // runs are the paths of the spilled runs, in the order their entries were added.
+}
+
+// newMergeSorter returns a mergeSorter buffering about budget bytes of entries and spilling runs to dir.
+func newMergeSorter(dir string, budget int) *mergeSorter {
+	return &mergeSorter{dir: dir, budget: budget}
+}
+
+// Add adds an entry, spilling the buffer to a run once it exceeds the budget.
+func (m *mergeSorter) Add(e *LogEntry) error {
+	m.buf = append(m.buf, e)
+	m.bufBytes += approxEntrySize(e)
+	if m.bufBytes < m.budget {
+		return nil
+	}
+	sort.Stable(ByDate{LogEntries: m.buf})
+	path, err := m.writeRun(func(enc *gob.Encoder) error {
+		for _, entry := range m.buf {
+			if err := enc.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	m.runs = append(m.runs, path)
+	m.buf, m.bufBytes = nil, 0
+	return nil
+}
+
+// writeRun writes a new run with write and closes it, returning its path. The run is removed if write fails.
+func (m *mergeSorter) writeRun(write func(enc *gob.Encoder) error) (string, error) {
+	f, err := os.CreateTemp(m.dir, "wetlog-run-*")
+	if err != nil {
+		return "", err
+	}
+	w := bufio.NewWriter(f)
+	err = write(gob.NewEncoder(w))
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// Close removes the runs.
+func (m *mergeSorter) Close() error {
+	var firstErr error
+	for _, path := range m.runs {
+		if err := os.Remove(path); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	m.runs = nil
+	return firstErr
+}
+
+// runReader reads the entries of a run one at a time.
+type runReader struct {
+	index int          // index orders the runs so that ties keep the order the entries were added in.
+	dec   *gob.Decoder // dec decodes the entries of a spilled run, nil for a buffer still in memory.
+	buf   LogEntries   // buf holds the remaining entries of a buffer still in memory.
+	head  *LogEntry    // head is the next entry of the run.
+}
+
+// next advances the run to its next entry, returning false at its end.
+func (r *runReader) next() (bool, error) {
+	if r.dec == nil {
+		if len(r.buf) == 0 {
+			return false, nil
+		}
+		r.head, r.buf = r.buf[0], r.buf[1:]
+		return true, nil
+	}
+	entry := &LogEntry{}
+	if err := r.dec.Decode(entry); err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	r.head = entry
+	return true, nil
+}
+
+// runHeap orders runs by their next entry like ByDate, then by index.
+type runHeap []*runReader
+
+func (h runHeap) Len() int { return len(h) }
+func (h runHeap) Less(i, j int) bool {
+	switch {
+	case wetlog.DateLess(h[i].head, h[j].head):
+		return true
+	case wetlog.DateLess(h[j].head, h[i].head):
+		return false
+	}
+	return h[i].index < h[j].index
+}
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*runReader)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// Merge passes every added entry to consume in date order, merging the runs with the entries still buffered, which
+// are not spilled. Runs beyond maxMergeFanIn are first merged into fewer, longer runs. It stops at the first error
+// returned by consume.
+func (m *mergeSorter) Merge(consume func(*LogEntry) error) error {
+	for len(m.runs) > maxMergeFanIn {
+		// the earliest runs are merged into the first run, so the runs stay in the order their entries were added
+		merged, err := m.writeRun(func(enc *gob.Encoder) error {
+			return mergeRuns(m.runs[:maxMergeFanIn], nil, func(e *LogEntry) error { return enc.Encode(e) })
+		})
+		if err != nil {
+			return err
+		}
+		for _, run := range m.runs[:maxMergeFanIn] {
+			if err := os.Remove(run); err != nil {
+				return err
+			}
+		}
+		m.runs = append([]string{merged}, m.runs[maxMergeFanIn:]...)
+	}
+	sort.Stable(ByDate{LogEntries: m.buf})
+	return mergeRuns(m.runs, m.buf, consume)
+}
+
+// mergeRuns passes the entries of the runs at paths and of the sorted buffer buf to consume in date order, with every
+// run open at once.
+func mergeRuns(paths []string, buf LogEntries, consume func(*LogEntry) error) error {
+	readers := make([]*runReader, 0, len(paths)+1)
+	for i, path := range paths {
+		f, err := os.Open(path) //nosec G304
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		readers = append(readers, &runReader{index: i, dec: gob.NewDecoder(bufio.NewReader(f))})
+	}
+	readers = append(readers, &runReader{index: len(readers), buf: buf})
+
+	h := make(runHeap, 0, len(readers))
+	for _, r := range readers {
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h = append(h, r)
+		}
+	}
+	heap.Init(&h)
+
+	for h.Len() > 0 {
+		r := h[0]
+		if err := consume(r.head); err != nil {
+			return err
+		}
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return nil
+}
+
+// externalSortEntries streams the matching entries of every node like pipeline.StreamEntries through filter, which is
+// given one entry at a time, and passes them to consume sorted by date. Only about budget bytes of entries are kept in
+// memory, the others are spilled to temporary files under dir. If timeout is positive and the scan takes longer, the
+// entries collected so far are passed to consume and context.DeadlineExceeded is returned.
+func externalSortEntries(ctx context.Context, nodes []Node, topLevelDir string, queries []string, opts pipeline.ScanOptions, timeout time.Duration, dir string, budget int, filter func(LogEntries) LogEntries, consume func(*LogEntry) error) error {
+	sorter := newMergeSorter(dir, budget)
+	defer sorter.Close()
+
+	scanCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		scanCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var addErr error
+	scanErr := pipeline.StreamEntries(scanCtx, nodes, topLevelDir, queries, opts, pipeline.EntryBufferSize, func(entry *LogEntry) {
+		for _, kept := range filter(LogEntries{entry}) {
+			if addErr == nil {
+				addErr = sorter.Add(kept)
+			}
+		}
+	})
+	if addErr != nil {
+		return addErr
+	}
+	if scanErr != nil && !errors.Is(scanErr, context.DeadlineExceeded) {
+		return scanErr
+	}
+
+	if err := sorter.Merge(consume); err != nil {
+		return err
+	}
+	if err := sorter.Close(); err != nil {
+		return err
+	}
+	return scanErr
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+func TestMergeSorter(t *testing.T) {
+	// dates repeat within a node, but never across nodes, so the stable in-memory sort gives the only expected order
+	zone := time.FixedZone("CEST", 2*60*60)
+	base := time.Date(2023, 7, 14, 16, 0, 0, 0, zone)
+	random := rand.New(rand.NewSource(1))
+	var entries LogEntries
+	for i := 0; i < 500; i++ {
+		node := i % 3
+		entries = append(entries, &LogEntry{
+			LogLevel:   INFO,
+			Date:       base.Add(time.Duration(random.Intn(50))*time.Second + time.Duration(node)),
+			LineNumber: i/3 + 1,
+			NodeIP:     fmt.Sprintf("10.0.0.%d", node+1),
+			Datacenter: "DC1",
+			FilePath:   "system.log",
+			Message:    fmt.Sprintf("Message %d", i),
+			LineCount:  1,
+			Fields:     map[string]string{"i": fmt.Sprint(i)},
+		})
+	}
+	describe := func(e *LogEntry) string {
+		return fmt.Sprintf("%s %s:%d %s %v", e.Date.Format(time.RFC3339Nano), e.NodeIP, e.LineNumber, e.Message, e.Fields)
+	}
+
+	dir := t.TempDir()
+	sorter := newMergeSorter(dir, 20*1024)
+	for _, entry := range entries {
+		if err := sorter.Add(entry); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if len(sorter.runs) < 6 {
+		t.Fatalf("Expected the entries to be spilled to several runs, got %d runs", len(sorter.runs))
+	}
+
+	var got []string
+	if err := sorter.Merge(func(e *LogEntry) error {
+		got = append(got, describe(e))
+		return nil
+	}); err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+
+	want := append(LogEntries(nil), entries...)
+	sort.Stable(ByDate{LogEntries: want})
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d entries, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != describe(want[i]) {
+			t.Fatalf("Entry %d: expected %s, got %s", i, describe(want[i]), got[i])
+		}
+	}
+
+	if err := sorter.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Expected the runs to be removed, %d files are left", len(files))
+	}
+}
+
+func TestMergeSorterManySpills(t *testing.T) {
+	base := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	random := rand.New(rand.NewSource(1))
+	var entries LogEntries
+	for i := 0; i < 5*maxMergeFanIn; i++ {
+		entries = append(entries, &LogEntry{
+			LogLevel:   INFO,
+			Date:       base.Add(time.Duration(random.Intn(1000)) * time.Second),
+			LineNumber: i + 1,
+			NodeIP:     fmt.Sprintf("10.0.0.%d", i%7+1),
+			FilePath:   "system.log",
+			Message:    fmt.Sprintf("Message %d", i),
+		})
+	}
+	// openFiles returns the number of file descriptors of the process, or -1 where they can't be listed
+	openFiles := func() int {
+		fds, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			return -1
+		}
+		return len(fds)
+	}
+
+	dir := t.TempDir()
+	// a budget of a byte spills a run per entry
+	sorter := newMergeSorter(dir, 1)
+	defer sorter.Close()
+	before := openFiles()
+	for _, entry := range entries {
+		if err := sorter.Add(entry); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if len(sorter.runs) != len(entries) {
+		t.Fatalf("Expected a run per entry, got %d runs", len(sorter.runs))
+	}
+	if after := openFiles(); after > before {
+		t.Errorf("Expected the spilled runs to be closed, %d more files are open", after-before)
+	}
+
+	var got LogEntries
+	maxRuns, maxOpen := 0, 0
+	if err := sorter.Merge(func(e *LogEntry) error {
+		got = append(got, e)
+		if files, _ := os.ReadDir(dir); len(files) > maxRuns {
+			maxRuns = len(files)
+		}
+		if open := openFiles() - before; open > maxOpen {
+			maxOpen = open
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if maxRuns > maxMergeFanIn {
+		t.Errorf("Expected at most %d runs left for the final merge, got %d", maxMergeFanIn, maxRuns)
+	}
+	if maxOpen > maxMergeFanIn {
+		t.Errorf("Expected at most %d runs open at once, got %d", maxMergeFanIn, maxOpen)
+	}
+
+	want := append(LogEntries(nil), entries...)
+	sort.Stable(ByDate{LogEntries: want})
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d entries, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].Message != want[i].Message {
+			t.Fatalf("Entry %d: expected %s, got %s", i, want[i].Message, got[i].Message)
+		}
+	}
+}
+
+// TestMergeSorterBounded tests that sorting the entries streamed from the nodes keeps fewer of them buffered than the
+// budget allows, however many entries each node has.
+func TestMergeSorterBounded(t *testing.T) {
+	const (
+		nodeCount      = 4
+		entriesPerNode = 5000
+		budget         = 64 * 1024
+	)
+
+	topLevelDir := t.TempDir()
+	var nodes []Node
+	for n := 0; n < nodeCount; n++ {
+		node := Node{Address: fmt.Sprintf("10.0.0.%d", n+1)}
+		var content strings.Builder
+		for i := 0; i < entriesPerNode; i++ {
+			fmt.Fprintf(&content, "INFO  [main] 2023-07-14 16:%02d:%02d,%03d Server.java:10 - Entry %d\n", i/60000%60, i/1000%60, i%1000, i)
+		}
+		writeSystemLog(t, topLevelDir, node.Address, content.String())
+		nodes = append(nodes, node)
+	}
+
+	sorter := newMergeSorter(t.TempDir(), budget)
+	defer sorter.Close()
+	peak := 0
+	var addErr error
+	err := pipeline.StreamEntries(context.Background(), nodes, topLevelDir, nil, pipeline.ScanOptions{}, pipeline.EntryBufferSize, func(entry *LogEntry) {
+		if addErr == nil {
+			addErr = sorter.Add(entry)
+		}
+		if len(sorter.buf) > peak {
+			peak = len(sorter.buf)
+		}
+	})
+	if err != nil || addErr != nil {
+		t.Fatalf("Expected the entries to be streamed and added, got %v and %v", err, addErr)
+	}
+	// every entry takes at least entryOverhead bytes of the budget
+	if bound := budget / entryOverhead; peak > bound {
+		t.Errorf("Expected at most %d entries buffered, got %d", bound, peak)
+	}
+
+	merged := 0
+	var last *LogEntry
+	if err := sorter.Merge(func(e *LogEntry) error {
+		if last != nil && e.Date.Before(last.Date) {
+			t.Fatalf("Entry %d is dated before the previous one", merged)
+		}
+		last = e
+		merged++
+		return nil
+	}); err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if merged != nodeCount*entriesPerNode {
+		t.Errorf("Expected %d entries, got %d", nodeCount*entriesPerNode, merged)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// failLevelExitCode is the exit code of wetlog when -fail-level finds entries at or above its level.
+const failLevelExitCode = 1
+
+// checkFailLevel counts the entries with a log level of at least level. If there are any, it writes their count to w
+// and returns failLevelExitCode, otherwise it returns 0.
+func checkFailLevel(w io.Writer, entries LogEntries, level LogLevel) int {
+	count := 0
+	for _, entry := range entries {
+		if entry.LogLevel >= level {
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	_, _ = fmt.Fprintf(w, "Found %d entries at or above %s (-fail-level)\n", count, level)
+	return failLevelExitCode
+}
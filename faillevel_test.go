@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCheckFailLevel(t *testing.T) {
+	entries := LogEntries{
+		{LogLevel: INFO},
+		{LogLevel: WARN},
+		{LogLevel: DEBUG},
+		{LogLevel: ERROR},
+		{LogLevel: WARN},
+	}
+
+	tests := []struct {
+		name     string
+		entries  LogEntries
+		level    LogLevel
+		wantCode int
+		wantOut  string
+	}{
+		{name: "WARN present", entries: entries, level: WARN, wantCode: failLevelExitCode, wantOut: "Found 3 entries at or above WARN (-fail-level)\n"},
+		{name: "ERROR present", entries: entries, level: ERROR, wantCode: failLevelExitCode, wantOut: "Found 1 entries at or above ERROR (-fail-level)\n"},
+		{name: "below level", entries: entries[:1], level: WARN, wantCode: 0, wantOut: ""},
+		{name: "no entries", entries: nil, level: DEBUG, wantCode: 0, wantOut: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if code := checkFailLevel(&buf, tt.entries, tt.level); code != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantCode, code)
+			}
+			if buf.String() != tt.wantOut {
+				t.Errorf("Expected output %q, got %q", tt.wantOut, buf.String())
+			}
+		})
+	}
+}
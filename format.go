@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github/kenjords/wetlog/pkg/wetlog"
+)
+
+const (
+	// FormatText renders an entry as a single "node:file:line: level [date] message" line.
+	FormatText = "text"
+	// FormatJSON renders an entry as a JSON object on its own line.
+	FormatJSON = "json"
+	// FormatCSV renders an entry as a CSV record. Printed entries are preceded by a csvHeader row, see writeHeader.
+	FormatCSV = "csv"
+	// FormatProto renders an entry as a length-delimited protobuf message, see proto/wetlog.proto.
+	FormatProto = "proto"
+	// FormatRaw renders an entry as the lines it was parsed from, exactly as they appear in the log file.
+	FormatRaw = "raw"
+)
+
+// Values of the -output flag.
+const (
+	OutputText = "text" // OutputText prints the entries one at a time in the -format.
+	OutputJSON = "json" // OutputJSON prints all entries as a single JSON array, see writeJSONArray.
+)
+
+// FormatOptions controls how FormatEntry renders an entry.
+type FormatOptions struct {
+	Format             string // Format is one of FormatText, FormatJSON, FormatCSV, FormatProto or FormatRaw.
+	CollapseWhitespace bool   // CollapseWhitespace renders every run of whitespace in the message, newlines included, as one space.
+	ShowDatacenter     bool   // ShowDatacenter prefixes text output with the datacenter of the entry, e.g. "[DC1] ".
+	JSONMaxMessage     int    // JSONMaxMessage truncates the message of JSON output to this many runes, 0 means no limit.
+	StripPrefix        string // StripPrefix is a leading directory removed from the file path of every entry.
+	// Color enables ANSI escape codes in text output. It should be set from useColor, and Highlight is ignored without it.
+	Color bool
+	// Highlight matches the query hits highlighted with ANSI codes in text output, see highlightRegex. Nil for none.
+	Highlight *regexp.Regexp
+	// FieldSeparator separates the node, file path and line number in text output. When empty, ":" is used, or "|"
+	// for IPv6 node addresses which contain colons.
+	FieldSeparator string
+	// RawNodePrefix prefixes every line of raw output with the node of the entry and the field separator.
+	RawNodePrefix bool
+}
+
+// fieldSeparator returns the separator between the fields of the entry in text output.
+func (opts FormatOptions) fieldSeparator(e *LogEntry) string {
+	if opts.FieldSeparator != "" {
+		return opts.FieldSeparator
+	}
+	if strings.Contains(e.NodeIP, ":") {
+		return "|"
+	}
+	return ":"
+}
+
+// truncationMarker is appended to messages truncated by FormatOptions.JSONMaxMessage.
+const truncationMarker = "...[truncated]"
+
+// truncateRunes returns s cut to at most n runes followed by truncationMarker, or s itself if it is short enough.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := 0
+	for i := range s {
+		if runes == n {
+			return s[:i] + truncationMarker
+		}
+		runes++
+	}
+	return s
+}
+
+// displayEntry returns the entry as it should be rendered. Entries needing changes are copied so the parsed entry is
+// left untouched.
+func (opts FormatOptions) displayEntry(e *LogEntry) *LogEntry {
+	filePath, stripped := stripPathPrefix(e.FilePath, opts.StripPrefix)
+	if !opts.CollapseWhitespace && !stripped {
+		return e
+	}
+	display := *e
+	display.FilePath = filePath
+	if opts.CollapseWhitespace {
+		display.Message = strings.Join(strings.Fields(display.Message), " ")
+	}
+	return &display
+}
+
+// jsonEntry returns the display entry e as it should be encoded in JSON output, with its message truncated to
+// JSONMaxMessage runes.
+func (opts FormatOptions) jsonEntry(e *LogEntry) *LogEntry {
+	if opts.JSONMaxMessage <= 0 {
+		return e
+	}
+	truncated := *e
+	truncated.Message = truncateRunes(e.Message, opts.JSONMaxMessage)
+	return &truncated
+}
+
+// stripPathPrefix removes the leading directory prefix from path. Paths outside prefix, including those merely sharing
+// its first characters like "/data2" for "/data", are returned unchanged along with false.
+func stripPathPrefix(path, prefix string) (string, bool) {
+	if prefix == "" {
+		return path, false
+	}
+	prefix = strings.TrimSuffix(prefix, string(filepath.Separator))
+	rest, ok := strings.CutPrefix(path, prefix+string(filepath.Separator))
+	if !ok || rest == "" {
+		return path, false
+	}
+	return rest, true
+}
+
+// csvHeader names the fields of the CSV records of entries, see csvRecord.
+var csvHeader = []string{"level", "date", "node_ip", "file_path", "line_number", "message"}
+
+// csvRecord returns the fields of the entry as a CSV record, its message whole as a single field.
+func csvRecord(e *LogEntry) []string {
+	return []string{
+		e.LogLevel.String(),
+		e.Date.Format(time.RFC3339Nano),
+		e.NodeIP,
+		e.FilePath,
+		strconv.Itoa(e.LineNumber),
+		e.Message,
+	}
+}
+
+// FormatEntry renders a single entry to w in the format selected by opts.
+func FormatEntry(w io.Writer, e *LogEntry, opts FormatOptions) error {
+	e = opts.displayEntry(e)
+	switch opts.Format {
+	case FormatText, "":
+		var repeats string
+		if e.Count > 1 {
+			repeats = fmt.Sprintf(" (x%d)", e.Count)
+		}
+		var dc string
+		if opts.ShowDatacenter {
+			dc = fmt.Sprintf("[%s] ", e.Datacenter)
+		}
+		message := e.Message
+		if opts.Color && opts.Highlight != nil {
+			message = highlightMatches(message, opts.Highlight)
+		}
+		sep := opts.fieldSeparator(e)
+		_, err := fmt.Fprintf(w, "%s%s%s%s%s%d%s %v [%s] %s%s\n", dc, e.NodeIP, sep, e.FilePath, sep, e.LineNumber, sep, e.LogLevel, e.Date, message, repeats)
+		return err
+	case FormatJSON:
+		data, err := json.Marshal(opts.jsonEntry(e))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvRecord(e)); err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatProto:
+		return wetlog.WriteProtoEntry(w, e)
+	case FormatRaw:
+		var prefix string
+		if opts.RawNodePrefix {
+			prefix = e.NodeIP + opts.fieldSeparator(e) + " "
+		}
+		for _, line := range strings.Split(e.Raw(), "\n") {
+			if _, err := fmt.Fprintf(w, "%s%s\n", prefix, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("Invalid output format: %s", opts.Format)
+	}
+}
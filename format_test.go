@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+func TestFormatEntry(t *testing.T) {
+	entry := &LogEntry{
+		LogLevel:   WARN,
+		Date:       time.Date(2023, 7, 14, 16, 0, 0, 658000000, time.UTC),
+		LineNumber: 42,
+		NodeIP:     "192.168.1.1",
+		FilePath:   "/var/log/cassandra/system.log",
+		Message:    "WARN  [main] 2023-07-14 16:00:00,658 Gossiper.java:1200 - Node /10.0.0.1, is down\nCaused by: timeout",
+	}
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := FormatEntry(&buf, entry, FormatOptions{Format: FormatText}); err != nil {
+			t.Fatalf("FormatEntry() error = %v", err)
+		}
+		want := "192.168.1.1:/var/log/cassandra/system.log:42: WARN [2023-07-14 16:00:00.658 +0000 UTC] " + entry.Message + "\n"
+		if buf.String() != want {
+			t.Errorf("FormatEntry() = %q, want %q", buf.String(), want)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := FormatEntry(&buf, entry, FormatOptions{Format: FormatJSON}); err != nil {
+			t.Fatalf("FormatEntry() error = %v", err)
+		}
+		var got map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("Couldn't unmarshal %q: %v", buf.String(), err)
+		}
+		want := map[string]interface{}{
+			"level":       "WARN",
+			"date":        "2023-07-14T16:00:00.658Z",
+			"line_number": float64(42),
+			"node_ip":     "192.168.1.1",
+			"file_path":   "/var/log/cassandra/system.log",
+			"message":     entry.Message,
+		}
+		for key, value := range want {
+			if got[key] != value {
+				t.Errorf("Expected %s = %v, got %v", key, value, got[key])
+			}
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := FormatEntry(&buf, entry, FormatOptions{Format: FormatCSV}); err != nil {
+			t.Fatalf("FormatEntry() error = %v", err)
+		}
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("Couldn't read csv output: %v", err)
+		}
+		want := []string{"WARN", "2023-07-14T16:00:00.658Z", "192.168.1.1", "/var/log/cassandra/system.log", "42", entry.Message}
+		if len(records) != 1 || len(records[0]) != len(want) {
+			t.Fatalf("Expected 1 record with %d fields, got %v", len(want), records)
+		}
+		for i := range want {
+			if records[0][i] != want[i] {
+				t.Errorf("Expected field %d = %q, got %q", i, want[i], records[0][i])
+			}
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := FormatEntry(&buf, entry, FormatOptions{Format: "xml"}); err == nil {
+			t.Errorf("Expected an error for an invalid format")
+		}
+	})
+}
+
+func TestFormatEntryCollapseWhitespace(t *testing.T) {
+	message := "ERROR [main] 2023-07-14 16:00:00,658 Server.java:10 - Exception   thrown\njava.lang.RuntimeException: boom\n\tat org.apache.cassandra.Server.run(Server.java:10)"
+	entry := &LogEntry{LogLevel: ERROR, NodeIP: "192.168.1.1", FilePath: "system.log", LineNumber: 7, Message: message}
+
+	var buf bytes.Buffer
+	if err := FormatEntry(&buf, entry, FormatOptions{Format: FormatText, CollapseWhitespace: true}); err != nil {
+		t.Fatalf("FormatEntry() error = %v", err)
+	}
+
+	want := "ERROR [main] 2023-07-14 16:00:00,658 Server.java:10 - Exception thrown java.lang.RuntimeException: boom at org.apache.cassandra.Server.run(Server.java:10)\n"
+	if !strings.HasSuffix(buf.String(), want) || strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("Expected a single line ending in %q, got %q", want, buf.String())
+	}
+	if entry.Message != message {
+		t.Errorf("Expected the raw message to be preserved, got %q", entry.Message)
+	}
+}
+
+func TestFormatEntryShowDatacenter(t *testing.T) {
+	entry := &LogEntry{LogLevel: INFO, NodeIP: "192.168.1.1", Datacenter: "DC2", FilePath: "system.log", LineNumber: 3, Message: "Starting"}
+
+	var plain, withDC bytes.Buffer
+	if err := FormatEntry(&plain, entry, FormatOptions{Format: FormatText}); err != nil {
+		t.Fatalf("FormatEntry() error = %v", err)
+	}
+	if err := FormatEntry(&withDC, entry, FormatOptions{Format: FormatText, ShowDatacenter: true}); err != nil {
+		t.Fatalf("FormatEntry() error = %v", err)
+	}
+
+	if strings.Contains(plain.String(), "DC2") {
+		t.Errorf("Expected no datacenter without ShowDatacenter, got %q", plain.String())
+	}
+	if withDC.String() != "[DC2] "+plain.String() {
+		t.Errorf("Expected %q, got %q", "[DC2] "+plain.String(), withDC.String())
+	}
+}
+
+func TestFormatEntryCount(t *testing.T) {
+	newEntry := func() *LogEntry {
+		return &LogEntry{LogLevel: WARN, NodeIP: "10.0.0.1", Message: "WARN  [MutationStage-1] 2023-07-14 16:00:00,000 Server.java:10 - Dropped mutations"}
+	}
+	deduped := pipeline.EntryFilters{Dedup: true}.Apply(LogEntries{newEntry(), newEntry(), newEntry()})
+	if len(deduped) != 1 {
+		t.Fatalf("Expected a single entry, got %d", len(deduped))
+	}
+
+	var buf bytes.Buffer
+	if err := FormatEntry(&buf, deduped[0], FormatOptions{Format: FormatText}); err != nil {
+		t.Fatalf("FormatEntry() error = %v", err)
+	}
+	if !strings.HasSuffix(buf.String(), "Dropped mutations (x3)\n") {
+		t.Errorf("Expected the occurrence count in the text output, got %q", buf.String())
+	}
+}
+
+func TestFormatEntryFields(t *testing.T) {
+	entry := &LogEntry{LogLevel: INFO, Message: "INFO  [main] 2023-07-14 16:00:00,000 StatusLogger.java:65 - pool=CompactionExecutor active=2 pending=5"}
+	pipeline.EntryFilters{KV: true}.Apply(LogEntries{entry})
+
+	var buf bytes.Buffer
+	if err := FormatEntry(&buf, entry, FormatOptions{Format: FormatJSON}); err != nil {
+		t.Fatalf("FormatEntry() error = %v", err)
+	}
+	var decoded LogEntry
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", buf.String(), err)
+	}
+	want := map[string]string{"pool": "CompactionExecutor", "active": "2", "pending": "5"}
+	if !reflect.DeepEqual(decoded.Fields, want) {
+		t.Errorf("Expected JSON fields %v, got %v", want, decoded.Fields)
+	}
+}
+
+func TestFormatEntryJSONMaxMessage(t *testing.T) {
+	message := "Compaction of système.peers terminée"
+	entry := &LogEntry{LogLevel: INFO, NodeIP: "192.168.1.1", FilePath: "system.log", LineNumber: 3, Message: message}
+	opts := FormatOptions{Format: FormatJSON, JSONMaxMessage: 20}
+
+	var buf bytes.Buffer
+	if err := FormatEntry(&buf, entry, opts); err != nil {
+		t.Fatalf("FormatEntry() error = %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", buf.String(), err)
+	}
+	// counting runes rather than bytes keeps the two-byte "è" whole and the cut after the 20th rune
+	want := "Compaction of systèm" + truncationMarker
+	if decoded["message"] != want {
+		t.Errorf("Expected message %q, got %q", want, decoded["message"])
+	}
+	if decoded["node_ip"] != "192.168.1.1" || decoded["file_path"] != "system.log" {
+		t.Errorf("Expected other fields to be unaffected, got %v", decoded)
+	}
+	if entry.Message != message {
+		t.Errorf("Expected the raw message to be preserved, got %q", entry.Message)
+	}
+
+	buf.Reset()
+	if err := FormatEntry(&buf, entry, FormatOptions{Format: FormatJSON, JSONMaxMessage: 100}); err != nil {
+		t.Fatalf("FormatEntry() error = %v", err)
+	}
+	if strings.Contains(buf.String(), truncationMarker) {
+		t.Errorf("Expected short messages to be left whole, got %q", buf.String())
+	}
+
+	for _, format := range []string{FormatText, FormatCSV} {
+		buf.Reset()
+		if err := FormatEntry(&buf, entry, FormatOptions{Format: format, JSONMaxMessage: 20}); err != nil {
+			t.Fatalf("FormatEntry() error = %v", err)
+		}
+		if !strings.Contains(buf.String(), message) {
+			t.Errorf("Expected %s output to keep the whole message, got %q", format, buf.String())
+		}
+	}
+}
+
+func TestFormatEntryStripPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		filePath string
+		want     string
+	}{
+		{name: "prefix", prefix: "/data/bundle", filePath: "/data/bundle/nodes/10.0.0.1/logs/cassandra/system.log", want: "nodes/10.0.0.1/logs/cassandra/system.log"},
+		{name: "trailing slash", prefix: "/data/bundle/", filePath: "/data/bundle/nodes/10.0.0.1/logs/cassandra/system.log", want: "nodes/10.0.0.1/logs/cassandra/system.log"},
+		{name: "other directory", prefix: "/data/bundle", filePath: "/var/log/cassandra/system.log", want: "/var/log/cassandra/system.log"},
+		{name: "sibling directory", prefix: "/data/bundle", filePath: "/data/bundle2/nodes/10.0.0.1/logs/cassandra/system.log", want: "/data/bundle2/nodes/10.0.0.1/logs/cassandra/system.log"},
+		{name: "no prefix", prefix: "", filePath: "/data/bundle/system.log", want: "/data/bundle/system.log"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &LogEntry{LogLevel: INFO, FilePath: tt.filePath, Message: "Starting"}
+			var buf bytes.Buffer
+			if err := FormatEntry(&buf, entry, FormatOptions{Format: FormatJSON, StripPrefix: tt.prefix}); err != nil {
+				t.Fatalf("FormatEntry() error = %v", err)
+			}
+			var decoded LogEntry
+			if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+				t.Fatalf("Invalid JSON %q: %v", buf.String(), err)
+			}
+			if decoded.FilePath != tt.want {
+				t.Errorf("Expected file path %q, got %q", tt.want, decoded.FilePath)
+			}
+			if entry.FilePath != tt.filePath {
+				t.Errorf("Expected the parsed entry to be left untouched, got %q", entry.FilePath)
+			}
+		})
+	}
+}
+
+func TestFormatEntryFieldSeparator(t *testing.T) {
+	date := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		nodeIP string
+		sep    string
+		want   string
+	}{
+		{name: "default", nodeIP: "10.0.0.1", sep: "", want: "10.0.0.1:system.log:3: "},
+		{name: "custom", nodeIP: "10.0.0.1", sep: " | ", want: "10.0.0.1 | system.log | 3 | "},
+		{name: "IPv6 default", nodeIP: "2001:db8::1", sep: "", want: "2001:db8::1|system.log|3| "},
+		{name: "IPv6 custom", nodeIP: "2001:db8::1", sep: ";", want: "2001:db8::1;system.log;3; "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &LogEntry{LogLevel: INFO, Date: date, NodeIP: tt.nodeIP, FilePath: "system.log", LineNumber: 3, Message: "Starting"}
+			var buf bytes.Buffer
+			if err := FormatEntry(&buf, entry, FormatOptions{Format: FormatText, FieldSeparator: tt.sep}); err != nil {
+				t.Fatalf("FormatEntry() error = %v", err)
+			}
+			if !strings.HasPrefix(buf.String(), tt.want) {
+				t.Errorf("Expected a line starting with %q, got %q", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestFormatEntryRaw(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "10.0.0.1"}
+	matched := "ERROR [ReadStage-1] 2023-07-14 16:00:01,000 Server.java:20 - Read   timed out\n" +
+		"java.lang.RuntimeException: timeout\n" +
+		"\tat org.apache.cassandra.Server.read(Server.java:20)\n"
+	writeSystemLog(t, topLevelDir, node.Address,
+		"INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n"+
+			matched+
+			"INFO  [main] 2023-07-14 16:00:02,000 Server.java:30 - Started\n")
+
+	entries := pipeline.CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, []string{"timed out"}, pipeline.ScanOptions{})[node.Address]
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+
+	tests := []struct {
+		name string
+		opts FormatOptions
+		want string
+	}{
+		// collapsing whitespace only changes the message, the raw lines are printed exactly
+		{name: "raw", opts: FormatOptions{Format: FormatRaw, CollapseWhitespace: true}, want: matched},
+		{name: "node prefix", opts: FormatOptions{Format: FormatRaw, RawNodePrefix: true}, want: "10.0.0.1: " + strings.ReplaceAll(strings.TrimSuffix(matched, "\n"), "\n", "\n10.0.0.1: ") + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := FormatEntry(&buf, entries[0], tt.opts); err != nil {
+				t.Fatalf("FormatEntry() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("FormatEntry() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+
+	// entries without raw lines, e.g. loaded from JSON, print their message
+	var buf bytes.Buffer
+	if err := FormatEntry(&buf, &LogEntry{Message: "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting"}, FormatOptions{Format: FormatRaw}); err != nil {
+		t.Fatalf("FormatEntry() error = %v", err)
+	}
+	if want := "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n"; buf.String() != want {
+		t.Errorf("FormatEntry() = %q, want %q", buf.String(), want)
+	}
+}
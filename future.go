@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// futureDateTolerance is how far past the current time an entry may be dated before it is reported, to allow for small
+// clock differences between the nodes and the machine running wetlog.
+const futureDateTolerance = 5 * time.Minute
+
+// checkFutureDates writes a warning to w for every entry dated more than futureDateTolerance after now, which usually
+// means clock skew or a misparsed date. If drop is true, the returned entries leave those entries out.
+func checkFutureDates(w io.Writer, entries LogEntries, now time.Time, drop bool) LogEntries {
+	limit := now.Add(futureDateTolerance)
+	var kept LogEntries
+	for _, entry := range entries {
+		if entry.Date.After(limit) {
+			_, _ = fmt.Fprintf(w, "%s:%d: entry of node %s dated in the future: %s\n",
+				entry.FilePath, entry.LineNumber, entry.NodeIP, entry.Date.Format(time.RFC3339Nano))
+			if drop {
+				continue
+			}
+		}
+		kept = append(kept, entry)
+	}
+	return kept
+}
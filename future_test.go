@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCheckFutureDates(t *testing.T) {
+	now := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	future := &LogEntry{LogLevel: WARN, Date: now.Add(24 * time.Hour), NodeIP: "10.0.0.2", FilePath: "system.log", LineNumber: 7}
+	entries := LogEntries{
+		{LogLevel: INFO, Date: now.Add(-time.Hour), NodeIP: "10.0.0.1", FilePath: "system.log", LineNumber: 1},
+		{LogLevel: INFO, Date: now.Add(time.Minute), NodeIP: "10.0.0.1", FilePath: "system.log", LineNumber: 2},
+		future,
+	}
+	wantWarning := "system.log:7: entry of node 10.0.0.2 dated in the future: 2023-07-15T16:00:00Z\n"
+
+	tests := []struct {
+		name     string
+		drop     bool
+		wantKept int
+	}{
+		{name: "warn", drop: false, wantKept: 3},
+		{name: "drop", drop: true, wantKept: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warnings bytes.Buffer
+			kept := checkFutureDates(&warnings, entries, now, tt.drop)
+			if warnings.String() != wantWarning {
+				t.Errorf("Expected warning %q, got %q", wantWarning, warnings.String())
+			}
+			if len(kept) != tt.wantKept {
+				t.Fatalf("Expected %d entries kept, got %d", tt.wantKept, len(kept))
+			}
+			for _, entry := range kept {
+				if tt.drop && entry == future {
+					t.Errorf("Expected the future entry to be dropped")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Gap is a period in which a node logged nothing.
+type Gap struct {
+	Start time.Time // Start is the date of the last entry before the gap.
+	End   time.Time // End is the date of the first entry after the gap.
+}
+
+// Duration returns the length of the gap.
+func (g Gap) Duration() time.Duration { return g.End.Sub(g.Start) }
+
+// findGaps returns, in date order, every period longer than threshold between consecutive entries of a node.
+func findGaps(entries LogEntries, threshold time.Duration) []Gap {
+	sorted := append(LogEntries(nil), entries...)
+	sort.Stable(ByDate{LogEntries: sorted})
+
+	var gaps []Gap
+	for i := 1; i < len(sorted); i++ {
+		gap := Gap{Start: sorted[i-1].Date, End: sorted[i].Date}
+		if gap.Duration() > threshold {
+			gaps = append(gaps, gap)
+		}
+	}
+	return gaps
+}
+
+// PrintGaps writes the logging gaps longer than threshold of each node, sorted by node address, to w.
+func PrintGaps(w io.Writer, nodeEntries map[string]LogEntries, threshold time.Duration) error {
+	addrs := make([]string, 0, len(nodeEntries))
+	for addr := range nodeEntries {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	for _, addr := range addrs {
+		for _, gap := range findGaps(nodeEntries[addr], threshold) {
+			if _, err := fmt.Fprintf(w, "%s: %s -> %s (%s)\n", addr, gap.Start.Format(time.RFC3339Nano), gap.End.Format(time.RFC3339Nano), gap.Duration()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
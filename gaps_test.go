@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+func TestPrintGaps(t *testing.T) {
+	topLevelDir := t.TempDir()
+	nodes := []Node{{Address: "10.0.0.1"}, {Address: "10.0.0.2"}}
+	writeSystemLog(t, topLevelDir, "10.0.0.1",
+		"INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n"+
+			"INFO  [main] 2023-07-14 16:01:00,000 Server.java:10 - Ready\n"+
+			"WARN  [main] 2023-07-14 16:31:00,500 Server.java:10 - Back after a hang\n"+
+			"INFO  [main] 2023-07-14 16:32:00,000 Server.java:10 - Still going\n")
+	writeSystemLog(t, topLevelDir, "10.0.0.2",
+		"INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n"+
+			"INFO  [main] 2023-07-14 16:04:00,000 Server.java:10 - Ready\n")
+
+	nodeEntries := pipeline.CollectNodeEntries(context.Background(), nodes, topLevelDir, nil, pipeline.ScanOptions{})
+
+	gaps := findGaps(nodeEntries["10.0.0.1"], 5*time.Minute)
+	if len(gaps) != 1 {
+		t.Fatalf("Expected 1 gap, got %d", len(gaps))
+	}
+	wantStart := time.Date(2023, 7, 14, 16, 1, 0, 0, time.UTC)
+	wantEnd := time.Date(2023, 7, 14, 16, 31, 0, 500000000, time.UTC)
+	if !gaps[0].Start.Equal(wantStart) || !gaps[0].End.Equal(wantEnd) {
+		t.Errorf("Expected gap %v -> %v, got %v -> %v", wantStart, wantEnd, gaps[0].Start, gaps[0].End)
+	}
+	if gaps[0].Duration() != 30*time.Minute+500*time.Millisecond {
+		t.Errorf("Expected a gap of 30m0.5s, got %v", gaps[0].Duration())
+	}
+
+	var buf bytes.Buffer
+	if err := PrintGaps(&buf, nodeEntries, 5*time.Minute); err != nil {
+		t.Fatalf("PrintGaps() error = %v", err)
+	}
+	want := "10.0.0.1: 2023-07-14T16:01:00Z -> 2023-07-14T16:31:00.5Z (30m0.5s)\n"
+	if buf.String() != want {
+		t.Errorf("PrintGaps() = %q, want %q", buf.String(), want)
+	}
+}
@@ -3,6 +3,8 @@ module github/kenjords/wetlog
 go 1.20
 
 require (
+	golang.org/x/crypto v0.12.0
 	golang.org/x/lint v0.0.0-20210508222113-6edffad5e616 // indirect
+	golang.org/x/sys v0.11.0 // indirect
 	golang.org/x/tools v0.11.0 // indirect
 )
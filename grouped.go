@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+// collapseConsecutive collapses runs of consecutive entries with the same level and message, see pipeline.DedupKeyOf,
+// into the first entry of the run, which records the length of the run in Count. Entries are expected to be sorted.
+func collapseConsecutive(entries LogEntries) LogEntries {
+	var collapsed LogEntries
+	var previous pipeline.DedupKey
+	for _, entry := range entries {
+		key := pipeline.DedupKeyOf(entry)
+		if len(collapsed) > 0 && key == previous {
+			collapsed[len(collapsed)-1].Count++
+			continue
+		}
+
+		entry.Count = 1
+		collapsed = append(collapsed, entry)
+		previous = key
+	}
+	return collapsed
+}
+
+// PrintNodeGroups writes the entries of each node to w under a header naming the node, nodes sorted by address and
+// entries by date. Consecutive repeats of the same message within a node are collapsed into one entry.
+func PrintNodeGroups(w io.Writer, nodeEntries map[string]LogEntries, opts FormatOptions) error {
+	addrs := make([]string, 0, len(nodeEntries))
+	for addr := range nodeEntries {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	for _, addr := range addrs {
+		entries := append(LogEntries(nil), nodeEntries[addr]...)
+		if len(entries) == 0 {
+			continue
+		}
+		sort.Stable(ByDate{LogEntries: entries})
+
+		if _, err := fmt.Fprintf(w, "== %s ==\n", addr); err != nil {
+			return err
+		}
+		for _, entry := range collapseConsecutive(entries) {
+			if err := FormatEntry(w, entry, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
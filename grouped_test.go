@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintNodeGroups(t *testing.T) {
+	start := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	entry := func(offset int, message string) *LogEntry {
+		return &LogEntry{LogLevel: WARN, Date: start.Add(time.Duration(offset) * time.Second), FilePath: "system.log", Message: message}
+	}
+	nodeEntries := map[string]LogEntries{
+		"10.0.0.2": {
+			entry(0, "Timed out after 1000 ms"),
+		},
+		"10.0.0.1": {
+			entry(2, "Timed out after 1000 ms"),
+			entry(0, "Timed out after 1000 ms"),
+			entry(1, "Timed out after 1000 ms"),
+			entry(3, "Timed out after 1500 ms"),
+			entry(4, "Compacted 4 sstables"),
+			entry(5, "Timed out after 1000 ms"),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := PrintNodeGroups(&buf, nodeEntries, FormatOptions{Format: FormatText}); err != nil {
+		t.Fatalf("PrintNodeGroups() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []struct{ prefix, suffix string }{
+		{"== 10.0.0.1 ==", ""},
+		{"", "Timed out after 1000 ms (x3)"},
+		{"", "Timed out after 1500 ms"},
+		{"", "Compacted 4 sstables"},
+		{"", "Timed out after 1000 ms"},
+		{"== 10.0.0.2 ==", ""},
+		{"", "Timed out after 1000 ms"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d lines, got:\n%s", len(want), buf.String())
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, want[i].prefix) || !strings.HasSuffix(line, want[i].suffix) {
+			t.Errorf("Expected line %d to look like %q...%q, got %q", i, want[i].prefix, want[i].suffix, line)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ANSI escape sequences wrapped around highlighted query hits.
+const (
+	highlightStart = "\x1b[1;31m"
+	highlightEnd   = "\x1b[0m"
+)
+
+// highlightRegex compiles the query terms into a single regex matching every hit of each of them, ignoring case if
+// ignoreCase is true, or returns nil without any term. The terms are matched independently of each other, so an entry
+// kept by -match-any for some of them still has those highlighted. Longer terms come first so that a term containing
+// another one is highlighted whole.
+func highlightRegex(queries []string, ignoreCase bool) *regexp.Regexp {
+	var terms []string
+	for _, query := range queries {
+		if query != "" {
+			terms = append(terms, regexp.QuoteMeta(query))
+		}
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+
+	pattern := strings.Join(terms, "|")
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.MustCompile(pattern)
+}
+
+// highlightRegexes joins the regexes of -regex into a single regex matching every hit of each of them, or returns nil
+// without any. Each keeps its own flags, such as the (?i) of -ignore-case.
+func highlightRegexes(regexes []*regexp.Regexp) *regexp.Regexp {
+	if len(regexes) == 0 {
+		return nil
+	}
+	patterns := make([]string, 0, len(regexes))
+	for _, re := range regexes {
+		patterns = append(patterns, "(?:"+re.String()+")")
+	}
+	return regexp.MustCompile(strings.Join(patterns, "|"))
+}
+
+// highlightMatches wraps every hit of re in message with ANSI highlight codes.
+func highlightMatches(message string, re *regexp.Regexp) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range re.FindAllStringIndex(message, -1) {
+		// a regex matching the empty string has nothing to highlight there
+		if span[0] == span[1] {
+			continue
+		}
+		b.WriteString(message[last:span[0]])
+		b.WriteString(highlightStart)
+		b.WriteString(message[span[0]:span[1]])
+		b.WriteString(highlightEnd)
+		last = span[1]
+	}
+	if last == 0 {
+		return message
+	}
+	b.WriteString(message[last:])
+	return b.String()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+func TestFormatEntryHighlight(t *testing.T) {
+	entry := &LogEntry{LogLevel: WARN, NodeIP: "10.0.0.1", FilePath: "system.log", LineNumber: 3,
+		Message: "Read timeout: 2 replicas timed out, timeout again"}
+	highlight := highlightRegex([]string{"timeout", "replicas"}, false)
+
+	var buf bytes.Buffer
+	if err := FormatEntry(&buf, entry, FormatOptions{Format: FormatText, Color: true, Highlight: highlight}); err != nil {
+		t.Fatalf("FormatEntry() error = %v", err)
+	}
+	want := "Read " + highlightStart + "timeout" + highlightEnd + ": 2 " + highlightStart + "replicas" + highlightEnd + " timed out, " +
+		highlightStart + "timeout" + highlightEnd + " again\n"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("Expected a line ending in %q, got %q", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := FormatEntry(&buf, entry, FormatOptions{Format: FormatJSON, Color: true, Highlight: highlight}); err != nil {
+		t.Fatalf("FormatEntry() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "\\u001b") {
+		t.Errorf("Expected no highlight codes in JSON output, got %q", buf.String())
+	}
+}
+
+func TestHighlightMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		queries []string
+		want    string
+	}{
+		{
+			name:    "only some terms match",
+			message: "a timeout b",
+			queries: []string{"timeout", "missing"},
+			want:    "a " + highlightStart + "timeout" + highlightEnd + " b",
+		},
+		{
+			name:    "terms out of order",
+			message: "b then a",
+			queries: []string{"a", "b"},
+			want:    highlightStart + "b" + highlightEnd + " then " + highlightStart + "a" + highlightEnd,
+		},
+		{
+			name:    "longer term containing another",
+			message: "Read timeout",
+			queries: []string{"time", "timeout"},
+			want:    "Read " + highlightStart + "timeout" + highlightEnd,
+		},
+		{
+			name:    "no match",
+			message: "Starting",
+			queries: []string{"timeout"},
+			want:    "Starting",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightMatches(tt.message, highlightRegex(tt.queries, false)); got != tt.want {
+				t.Errorf("highlightMatches() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if re := highlightRegex([]string{""}, false); re != nil {
+		t.Errorf("Expected no regex without terms, got %v", re)
+	}
+}
+
+func TestHighlightMatchesIgnoreCase(t *testing.T) {
+	// "İ" lowercases to a shorter string, so offsets taken from a lowercased copy would be off by one
+	message := "İ Read TIMEOUT, timeout again"
+	got := highlightMatches(message, highlightRegex([]string{"read", "Timeout"}, true))
+	want := "İ " + highlightStart + "Read" + highlightEnd + " " + highlightStart + "TIMEOUT" + highlightEnd + ", " +
+		highlightStart + "timeout" + highlightEnd + " again"
+	if got != want {
+		t.Errorf("highlightMatches() = %q, want %q", got, want)
+	}
+
+	if got := highlightMatches(message, highlightRegex([]string{"read"}, false)); got != message {
+		t.Errorf("Expected no highlight for a case-sensitive miss, got %q", got)
+	}
+}
+
+func TestHighlightRegexes(t *testing.T) {
+	regexes, err := pipeline.CompileQueryRegexes([]string{`GC in \d+ms`, `^$`, "slow"}, true)
+	if err != nil {
+		t.Fatalf("pipeline.CompileQueryRegexes() error = %v", err)
+	}
+	got := highlightMatches("Slow: G1 GC in 523ms", highlightRegexes(regexes))
+	want := highlightStart + "Slow" + highlightEnd + ": G1 " + highlightStart + "GC in 523ms" + highlightEnd
+	if got != want {
+		t.Errorf("highlightMatches() = %q, want %q", got, want)
+	}
+}
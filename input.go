@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReadJSONEntries decodes entries previously emitted with -format json, either one object per line or a JSON array.
+// Entries without a line count, written before it was recorded, get the number of lines of their message.
+func ReadJSONEntries(r io.Reader) (LogEntries, error) {
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+
+	var entries LogEntries
+	if first, err := peekNonSpace(br); err == nil && first == '[' {
+		if err := dec.Decode(&entries); err != nil {
+			return nil, err
+		}
+		fillLineCounts(entries)
+		return entries, nil
+	}
+
+	for {
+		entry := &LogEntry{}
+		err := dec.Decode(entry)
+		if err == io.EOF {
+			fillLineCounts(entries)
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// fillLineCounts sets the LineCount of the entries without one to the number of lines of their message, so that
+// -min-lines doesn't drop them all.
+func fillLineCounts(entries LogEntries) {
+	for _, entry := range entries {
+		if entry.LineCount == 0 {
+			entry.LineCount = strings.Count(entry.Message, "\n") + 1
+		}
+	}
+}
+
+// peekNonSpace returns the first non-whitespace byte of br without consuming it.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			if _, err := br.ReadByte(); err != nil {
+				return 0, err
+			}
+		default:
+			return b[0], nil
+		}
+	}
+}
+
+// loadJSONEntries reads the entries of a file previously written with -format json.
+func loadJSONEntries(path string) (LogEntries, error) {
+	file, err := os.Open(path) //nosec G304
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = file.Close()
+	}()
+
+	return ReadJSONEntries(file)
+}
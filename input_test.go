@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+// TestReadJSONEntriesRoundTrip tests that entries written as JSON can be read back and re-sorted.
+func TestReadJSONEntriesRoundTrip(t *testing.T) {
+	entries := LogEntries{
+		{LogLevel: INFO, Date: time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC), LineNumber: 1, NodeIP: "10.0.0.1", FilePath: "system.log", Message: "Starting", LineCount: 1},
+		{LogLevel: ERROR, Date: time.Date(2023, 7, 14, 16, 0, 2, 0, time.UTC), LineNumber: 2, NodeIP: "10.0.0.2", FilePath: "system.log", Message: "Failed\n\tat Server.java:10", LineCount: 2},
+		{LogLevel: WARN, Date: time.Date(2023, 7, 14, 16, 0, 1, 0, time.UTC), LineNumber: 3, NodeIP: "10.0.0.1", FilePath: "system.log", Message: "Slow", Metrics: map[string]float64{"gc_pause_ms": 523}, LineCount: 1},
+	}
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		if err := FormatEntry(&buf, entry, FormatOptions{Format: FormatJSON}); err != nil {
+			t.Fatalf("FormatEntry() error = %v", err)
+		}
+	}
+
+	got, err := ReadJSONEntries(&buf)
+	if err != nil {
+		t.Fatalf("ReadJSONEntries() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Fatalf("ReadJSONEntries() = %v, want %v", got, entries)
+	}
+
+	sort.Sort(ByLogLevel{LogEntries: got})
+	wantLevels := []LogLevel{INFO, WARN, ERROR}
+	for i, entry := range got {
+		if entry.LogLevel != wantLevels[i] {
+			t.Errorf("Expected log level %v at index %d, got %v", wantLevels[i], i, entry.LogLevel)
+		}
+	}
+}
+
+func TestReadJSONEntriesArray(t *testing.T) {
+	input := `[
+  {"level": "WARN", "date": "2023-07-14T16:00:00Z", "line_number": 1, "node_ip": "10.0.0.1", "file_path": "system.log", "message": "Slow"},
+  {"level": "DEBUG", "date": "2023-07-14T16:00:01Z", "line_number": 2, "node_ip": "10.0.0.1", "file_path": "system.log", "message": "Detail"}
+]`
+	got, err := ReadJSONEntries(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadJSONEntries() error = %v", err)
+	}
+	if len(got) != 2 || got[0].LogLevel != WARN || got[1].LogLevel != DEBUG {
+		t.Errorf("ReadJSONEntries() = %v, want a WARN and a DEBUG entry", got)
+	}
+}
+
+func TestReadJSONEntriesWithoutLineCount(t *testing.T) {
+	// written before line_count was recorded
+	input := `{"level":"ERROR","message":"Failed\n\tat Server.java:10\n\tat Main.java:5"}
+{"level":"INFO","message":"Starting"}
+{"level":"WARN","message":"Slow","line_count":4}
+`
+	entries, err := ReadJSONEntries(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadJSONEntries() error = %v", err)
+	}
+
+	want := []int{3, 1, 4}
+	for i, entry := range entries {
+		if entry.LineCount != want[i] {
+			t.Errorf("Expected a line count of %d for entry %d, got %d", want[i], i, entry.LineCount)
+		}
+	}
+	if kept := (pipeline.EntryFilters{MinLines: 2}).Apply(entries); len(kept) != 2 {
+		t.Errorf("Expected -min-lines 2 to keep 2 entries, got %d", len(kept))
+	}
+}
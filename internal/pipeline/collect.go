@@ -0,0 +1,231 @@
+package pipeline
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EntryBufferSize bounds the number of entries in flight between the node goroutines and the consumer of their entries.
+const EntryBufferSize = 1024
+
+// StreamEntries processes the logs of every node through forEachNode and passes each matching entry to consume from a
+// single goroutine. At most bufferSize entries are queued between them, so the node goroutines block instead of
+// accumulating entries when consume falls behind. It returns ctx.Err() if ctx is cancelled before every entry was
+// consumed.
+func StreamEntries(ctx context.Context, nodes []Node, topLevelDir string, queries []string, opts ScanOptions, bufferSize int, consume func(*LogEntry)) error {
+	logEntryChan := make(chan *LogEntry, bufferSize)
+	go func() {
+		forEachNode(nodes, opts, func(node Node) {
+			scanNode(ctx, node, topLevelDir, queries, logEntryChan, opts)
+		})
+		close(logEntryChan)
+	}()
+
+	for {
+		select {
+		case entry, ok := <-logEntryChan:
+			if !ok {
+				return nil
+			}
+			if ctx.Err() == nil {
+				consume(entry)
+				continue
+			}
+		case <-ctx.Done():
+		}
+
+		// unblock the node goroutines so they can finish
+		go func() {
+			for range logEntryChan {
+			}
+		}()
+		return ctx.Err()
+	}
+}
+
+// forEachNode calls process for every node concurrently, opts.Concurrency nodes at a time and at most
+// opts.PerDCConcurrency of them per datacenter, and returns once every call returned. With opts.Deterministic, the
+// nodes are processed one at a time in address order instead.
+func forEachNode(nodes []Node, opts ScanOptions, process func(Node)) {
+	dcSlots := datacenterSemaphores(nodes, opts.PerDCConcurrency)
+	processNode := func(node Node) {
+		if slots := dcSlots[node.Datacenter]; slots != nil {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+		}
+		process(node)
+	}
+
+	if opts.Deterministic {
+		sorted := append([]Node(nil), nodes...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].Address < sorted[j].Address
+		})
+		for _, node := range sorted {
+			processNode(node)
+		}
+		return
+	}
+
+	// a bounded pool of workers keeps the number of log files open at once in check on large clusters
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(nodes) {
+		workers = len(nodes)
+	}
+	// with a limit per datacenter, the nodes are queued alternating between datacenters so the workers don't all wait
+	// on the nodes of the same datacenter
+	queued := nodes
+	if opts.PerDCConcurrency > 0 {
+		queued = interleaveDatacenters(nodes)
+	}
+	nodeChan := make(chan Node, len(nodes))
+	for _, node := range queued {
+		nodeChan <- node
+	}
+	close(nodeChan)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for node := range nodeChan {
+				processNode(node)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// scanNode sends the matching entries of node to logEntryChan, reports the error that stopped it unless ctx was
+// cancelled, and calls opts.NodeDone.
+func scanNode(ctx context.Context, node Node, topLevelDir string, queries []string, logEntryChan chan *LogEntry, opts ScanOptions) {
+	err := ProcessFile(ctx, node, topLevelDir, queries, logEntryChan, opts)
+	if err != nil && ctx.Err() == nil {
+		reportNodeError(node, err, opts)
+	}
+	if opts.NodeDone != nil {
+		opts.NodeDone(node)
+	}
+}
+
+// reportNodeError records the error that stopped the processing of the logs of node in opts.NodeErrors, or logs it
+// right away without one.
+func reportNodeError(node Node, err error, opts ScanOptions) {
+	if opts.NodeErrors != nil {
+		opts.NodeErrors.Add(node.Address, err)
+		return
+	}
+	log.Printf("Error while processing logs for node %s: %v\n", node.Address, err)
+}
+
+// datacenterSemaphores returns a semaphore for each datacenter of the nodes letting limit of its nodes be processed at
+// once, or nil if limit isn't positive.
+func datacenterSemaphores(nodes []Node, limit int) map[string]chan struct{} {
+	if limit <= 0 {
+		return nil
+	}
+	semaphores := make(map[string]chan struct{})
+	for _, node := range nodes {
+		if semaphores[node.Datacenter] == nil {
+			semaphores[node.Datacenter] = make(chan struct{}, limit)
+		}
+	}
+	return semaphores
+}
+
+// interleaveDatacenters returns the nodes ordered by taking one node of each datacenter in turn, keeping the order of
+// the nodes within a datacenter.
+func interleaveDatacenters(nodes []Node) []Node {
+	var dcs []string
+	byDC := make(map[string][]Node)
+	for _, node := range nodes {
+		if _, ok := byDC[node.Datacenter]; !ok {
+			dcs = append(dcs, node.Datacenter)
+		}
+		byDC[node.Datacenter] = append(byDC[node.Datacenter], node)
+	}
+
+	interleaved := make([]Node, 0, len(nodes))
+	for len(interleaved) < len(nodes) {
+		for _, dc := range dcs {
+			if len(byDC[dc]) > 0 {
+				interleaved = append(interleaved, byDC[dc][0])
+				byDC[dc] = byDC[dc][1:]
+			}
+		}
+	}
+	return interleaved
+}
+
+// ScanEntries collects the matching entries of every node like StreamEntries, keeping them all in memory for the
+// callers that need them at once. If timeout is positive and the scan takes longer, it stops and returns the entries
+// collected so far along with context.DeadlineExceeded.
+func ScanEntries(ctx context.Context, nodes []Node, topLevelDir string, queries []string, opts ScanOptions, timeout time.Duration) (LogEntries, error) {
+	ctx, cancel := WithScanTimeout(ctx, timeout)
+	defer cancel()
+
+	var entries LogEntries
+	err := StreamEntries(ctx, nodes, topLevelDir, queries, opts, EntryBufferSize, func(entry *LogEntry) {
+		entries = append(entries, entry)
+	})
+	return entries, err
+}
+
+// WithScanTimeout returns a copy of ctx cancelled once timeout elapses, the -timeout of a scan. A timeout that isn't
+// positive only cancels it along with ctx.
+func WithScanTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// CountNodeEntries returns the total number of entries of the nodes.
+func CountNodeEntries(nodeEntries map[string]LogEntries) int {
+	count := 0
+	for _, entries := range nodeEntries {
+		count += len(entries)
+	}
+	return count
+}
+
+// CollectNodeEntries processes the logs of each node under topLevelDir through forEachNode and groups the entries by
+// node address. Once ctx is cancelled, the nodes stop being processed and hold the entries collected so far.
+func CollectNodeEntries(ctx context.Context, nodes []Node, topLevelDir string, queries []string, opts ScanOptions) map[string]LogEntries {
+	var mu sync.Mutex
+	nodeEntries := make(map[string]LogEntries, len(nodes))
+
+	forEachNode(nodes, opts, func(node Node) {
+		logEntryChan := make(chan *LogEntry)
+		go func() {
+			scanNode(ctx, node, topLevelDir, queries, logEntryChan, opts)
+			close(logEntryChan)
+		}()
+
+		var entries LogEntries
+		for entry := range logEntryChan {
+			entries = append(entries, entry)
+		}
+
+		mu.Lock()
+		nodeEntries[node.Address] = entries
+		mu.Unlock()
+	})
+
+	return nodeEntries
+}
+
+// FilterNodeEntries replaces the entries of each node with the entries filter keeps, e.g. EntryFilters.Apply.
+func FilterNodeEntries(nodeEntries map[string]LogEntries, filter func(LogEntries) LogEntries) map[string]LogEntries {
+	for address, entries := range nodeEntries {
+		nodeEntries[address] = filter(entries)
+	}
+	return nodeEntries
+}
@@ -0,0 +1,297 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestStreamEntriesBounded tests that the node goroutines stop producing entries once the buffer is full, so memory is
+// bounded by the buffer rather than the number of entries per node.
+func TestStreamEntriesBounded(t *testing.T) {
+	const (
+		nodeCount      = 4
+		entriesPerNode = 5000
+		bufferSize     = 16
+	)
+
+	topLevelDir := t.TempDir()
+	var nodes []Node
+	for n := 0; n < nodeCount; n++ {
+		node := Node{Address: fmt.Sprintf("10.0.0.%d", n+1)}
+		var content strings.Builder
+		for i := 0; i < entriesPerNode; i++ {
+			fmt.Fprintf(&content, "INFO  [main] 2023-07-14 16:00:00,%03d Server.java:10 - Entry %d\n", i%1000, i)
+		}
+		writeSystemLog(t, topLevelDir, node.Address, content.String())
+		nodes = append(nodes, node)
+	}
+
+	// produced counts the entries the node goroutines have matched and are about to send.
+	var produced int64
+	opts := ScanOptions{Matchers: []Matcher{{
+		Name: "count",
+		Match: func(*LogEntry) bool {
+			atomic.AddInt64(&produced, 1)
+			return true
+		},
+	}}}
+
+	var consumed int
+	err := StreamEntries(context.Background(), nodes, topLevelDir, nil, opts, bufferSize, func(*LogEntry) {
+		if consumed == 0 {
+			// Stall the consumer to let the producers fill the buffer.
+			time.Sleep(100 * time.Millisecond)
+			// Every producer can hold one entry it is blocked sending and one it has matched but not yet sent.
+			if inFlight := atomic.LoadInt64(&produced); inFlight > bufferSize+2*nodeCount {
+				t.Errorf("Expected at most %d entries in flight while the consumer is stalled, got %d", bufferSize+2*nodeCount, inFlight)
+			}
+		}
+		consumed++
+	})
+	if err != nil {
+		t.Fatalf("StreamEntries() error = %v", err)
+	}
+
+	if consumed != nodeCount*entriesPerNode {
+		t.Errorf("Expected %d entries to be consumed, got %d", nodeCount*entriesPerNode, consumed)
+	}
+}
+
+func TestStreamEntriesConcurrency(t *testing.T) {
+	topLevelDir := t.TempDir()
+	var nodes []Node
+	for i := 0; i < 50; i++ {
+		node := Node{Address: fmt.Sprintf("10.0.0.%d", i), Datacenter: "DC1"}
+		nodes = append(nodes, node)
+		writeSystemLog(t, topLevelDir, node.Address,
+			"INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n"+
+				"WARN  [main] 2023-07-14 16:00:01,000 Server.java:20 - Slow\n")
+	}
+
+	for _, concurrency := range []int{0, 1, 4, 100} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			counts := make(map[string]int)
+			err := StreamEntries(context.Background(), nodes, topLevelDir, nil, ScanOptions{Concurrency: concurrency}, EntryBufferSize, func(entry *LogEntry) {
+				counts[entry.NodeIP]++
+			})
+			if err != nil {
+				t.Fatalf("StreamEntries() error = %v", err)
+			}
+			if len(counts) != len(nodes) {
+				t.Fatalf("Expected entries from %d nodes, got %d", len(nodes), len(counts))
+			}
+			for address, count := range counts {
+				if count != 2 {
+					t.Errorf("Expected 2 entries from node %s, got %d", address, count)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamEntriesPerDCConcurrency(t *testing.T) {
+	topLevelDir := t.TempDir()
+	var nodes []Node
+	for i := 0; i < 24; i++ {
+		node := Node{Address: fmt.Sprintf("10.0.%d.%d", i%3, i), Datacenter: fmt.Sprintf("DC%d", i%3+1)}
+		nodes = append(nodes, node)
+		writeSystemLog(t, topLevelDir, node.Address,
+			"INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n"+
+				"WARN  [main] 2023-07-14 16:00:01,000 Server.java:20 - Slow\n")
+	}
+
+	const perDC = 2
+	var mu sync.Mutex
+	active := make(map[string]int)
+	maxActive := make(map[string]int)
+	total, maxTotal := 0, 0
+	opts := ScanOptions{
+		Concurrency:      12,
+		PerDCConcurrency: perDC,
+		// a node starts being processed at its first entry and is done once NodeDone is called
+		Matchers: []Matcher{{
+			Name: "track",
+			Match: func(entry *LogEntry) bool {
+				if entry.LineNumber == 1 {
+					mu.Lock()
+					active[entry.Datacenter]++
+					total++
+					if active[entry.Datacenter] > maxActive[entry.Datacenter] {
+						maxActive[entry.Datacenter] = active[entry.Datacenter]
+					}
+					if total > maxTotal {
+						maxTotal = total
+					}
+					mu.Unlock()
+					time.Sleep(20 * time.Millisecond)
+				}
+				return true
+			},
+		}},
+		NodeDone: func(node Node) {
+			mu.Lock()
+			active[node.Datacenter]--
+			total--
+			mu.Unlock()
+		},
+	}
+
+	count := 0
+	err := StreamEntries(context.Background(), nodes, topLevelDir, nil, opts, EntryBufferSize, func(*LogEntry) { count++ })
+	if err != nil {
+		t.Fatalf("StreamEntries() error = %v", err)
+	}
+	if count != 2*len(nodes) {
+		t.Errorf("Expected %d entries, got %d", 2*len(nodes), count)
+	}
+	for dc, max := range maxActive {
+		if max > perDC {
+			t.Errorf("Expected at most %d nodes of %s processed at once, got %d", perDC, dc, max)
+		}
+	}
+	// the datacenters are processed in parallel with each other
+	if maxTotal <= perDC {
+		t.Errorf("Expected nodes of several datacenters to be processed at once, got at most %d nodes", maxTotal)
+	}
+}
+
+func TestInterleaveDatacenters(t *testing.T) {
+	nodes := []Node{
+		{Address: "10.0.0.1", Datacenter: "DC1"}, {Address: "10.0.0.2", Datacenter: "DC1"}, {Address: "10.0.0.3", Datacenter: "DC1"},
+		{Address: "10.0.1.1", Datacenter: "DC2"},
+		{Address: "10.0.2.1", Datacenter: "DC3"}, {Address: "10.0.2.2", Datacenter: "DC3"},
+	}
+	var got []string
+	for _, node := range interleaveDatacenters(nodes) {
+		got = append(got, node.Address)
+	}
+	want := []string{"10.0.0.1", "10.0.1.1", "10.0.2.1", "10.0.0.2", "10.0.2.2", "10.0.0.3"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("interleaveDatacenters() = %v, want %v", got, want)
+	}
+}
+
+func TestStreamEntriesCancelled(t *testing.T) {
+	topLevelDir := t.TempDir()
+	writeSystemLog(t, topLevelDir, "10.0.0.1", "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Entry\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := StreamEntries(ctx, []Node{{Address: "10.0.0.1"}}, topLevelDir, nil, ScanOptions{}, 0, func(*LogEntry) {})
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// TestStreamEntriesDeterministic tests that two deterministic runs over the same logs produce identical output, even
+// when entries of different nodes share a date.
+func TestStreamEntriesDeterministic(t *testing.T) {
+	topLevelDir := t.TempDir()
+	var nodes []Node
+	for n := 8; n > 0; n-- {
+		node := Node{Address: fmt.Sprintf("10.0.0.%d", n)}
+		writeSystemLog(t, topLevelDir, node.Address,
+			"INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n"+
+				"WARN  [main] 2023-07-14 16:00:01,000 Server.java:10 - Slow\n")
+		nodes = append(nodes, node)
+	}
+
+	run := func() string {
+		var entries LogEntries
+		err := StreamEntries(context.Background(), nodes, topLevelDir, nil, ScanOptions{Deterministic: true}, 1, func(entry *LogEntry) {
+			entries = append(entries, entry)
+		})
+		if err != nil {
+			t.Fatalf("StreamEntries() error = %v", err)
+		}
+		for i := 1; i < len(entries); i++ {
+			if entries[i].FilePath < entries[i-1].FilePath {
+				t.Fatalf("Expected entries in node address order, got %s after %s", entries[i].FilePath, entries[i-1].FilePath)
+			}
+		}
+
+		sort.Sort(ByDate{LogEntries: entries})
+		out, err := json.Marshal(entries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(out)
+	}
+
+	first, second := run(), run()
+	if first != second {
+		t.Errorf("Expected identical output across runs, got:\n%s\nand:\n%s", first, second)
+	}
+}
+
+// TestScanEntriesTimeout tests that a scan exceeding its timeout stops early and reports partial results.
+func TestScanEntriesTimeout(t *testing.T) {
+	topLevelDir := t.TempDir()
+	var nodes []Node
+	for n := 0; n < 4; n++ {
+		node := Node{Address: fmt.Sprintf("10.0.0.%d", n+1)}
+		var content strings.Builder
+		for i := 0; i < 1000; i++ {
+			fmt.Fprintf(&content, "INFO  [main] 2023-07-14 16:00:00,%03d Server.java:10 - Entry %d\n", i%1000, i)
+		}
+		writeSystemLog(t, topLevelDir, node.Address, content.String())
+		nodes = append(nodes, node)
+	}
+
+	entries, err := ScanEntries(context.Background(), nodes, topLevelDir, nil, ScanOptions{}, time.Nanosecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if len(entries) >= 4*1000 {
+		t.Errorf("Expected partial results, got all %d entries", len(entries))
+	}
+
+	entries, err = ScanEntries(context.Background(), nodes, topLevelDir, nil, ScanOptions{}, 0)
+	if err != nil || len(entries) != 4*1000 {
+		t.Errorf("Expected all %d entries without a timeout, got %d (error: %v)", 4*1000, len(entries), err)
+	}
+}
+
+func TestFilterNodeEntries(t *testing.T) {
+	nodeEntries := map[string]LogEntries{
+		"10.0.0.1": {{LogLevel: INFO, LineNumber: 1}, {LogLevel: ERROR, LineNumber: 2}},
+		"10.0.0.2": {{LogLevel: DEBUG, LineNumber: 1}},
+	}
+	filtered := FilterNodeEntries(nodeEntries, func(entries LogEntries) LogEntries { return filterByMinLevel(entries, WARN) })
+	if len(filtered) != 2 {
+		t.Fatalf("Expected every node to be kept, got %v", filtered)
+	}
+	if got := filtered["10.0.0.1"]; len(got) != 1 || got[0].LineNumber != 2 {
+		t.Errorf("Expected the ERROR entry of 10.0.0.1, got %v", got)
+	}
+	if got := filtered["10.0.0.2"]; len(got) != 0 {
+		t.Errorf("Expected no entries for 10.0.0.2, got %v", got)
+	}
+}
+
+func TestWithScanTimeout(t *testing.T) {
+	ctx, cancel := WithScanTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to be exceeded, got %v", ctx.Err())
+	}
+
+	ctx, cancel = WithScanTimeout(context.Background(), 0)
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("Expected no deadline without a timeout")
+	}
+	cancel()
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("Expected the context to be cancelled, got %v", ctx.Err())
+	}
+}
@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	"sort"
+	"time"
+)
+
+// DedupKey identifies the repeats of a message collapsed by dedup: the same level and an identical message body,
+// numbers included. Only the prefix with the thread, date and source may differ.
+type DedupKey struct {
+	level LogLevel
+	body  string
+}
+
+// DedupKeyOf returns the DedupKey of the entry.
+func DedupKeyOf(entry *LogEntry) DedupKey {
+	return DedupKey{level: entry.LogLevel, body: entry.Body()}
+}
+
+// dedupWithinWindow collapses the repeats of a signature on a node, see LogEntry.Hash, each within window of the
+// previous one, into the first of them, which records their number in Count. The result is sorted by date.
+func dedupWithinWindow(entries LogEntries, window time.Duration) LogEntries {
+	sorted := append(LogEntries(nil), entries...)
+	sort.Stable(ByDate{LogEntries: sorted})
+
+	type run struct {
+		first    *LogEntry
+		lastSeen time.Time
+	}
+	runs := make(map[string]*run)
+
+	var deduped LogEntries
+	for _, entry := range sorted {
+		key := entry.NodeIP + "|" + entry.Hash()
+		if r, ok := runs[key]; ok && entry.Date.Sub(r.lastSeen) <= window {
+			r.first.Count++
+			r.lastSeen = entry.Date
+			continue
+		}
+
+		entry.Count = 1
+		runs[key] = &run{first: entry, lastSeen: entry.Date}
+		deduped = append(deduped, entry)
+	}
+	return deduped
+}
+
+// dedup collapses the repeats of a message across all nodes, see DedupKey, into the earliest of them, which records
+// the number of occurrences in Count. Entries already collapsed, e.g. by dedupWithinWindow, count for their Count. The
+// result is sorted by date.
+func dedup(entries LogEntries) LogEntries {
+	sorted := append(LogEntries(nil), entries...)
+	sort.Stable(ByDate{LogEntries: sorted})
+
+	firsts := make(map[DedupKey]*LogEntry)
+
+	var deduped LogEntries
+	for _, entry := range sorted {
+		occurrences := entry.Count
+		if occurrences < 1 {
+			occurrences = 1
+		}
+		key := DedupKeyOf(entry)
+		if first, ok := firsts[key]; ok {
+			first.Count += occurrences
+			continue
+		}
+
+		entry.Count = occurrences
+		firsts[key] = entry
+		deduped = append(deduped, entry)
+	}
+	return deduped
+}
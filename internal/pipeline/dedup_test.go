@@ -0,0 +1,106 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDedupWithinWindow(t *testing.T) {
+	start := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	newEntry := func(offset time.Duration, message string) *LogEntry {
+		return &LogEntry{LogLevel: WARN, Date: start.Add(offset), NodeIP: "10.0.0.1", Message: message}
+	}
+	entries := LogEntries{
+		newEntry(0, "Dropped 12 mutations"),
+		// messages differing only in a number share their signature
+		newEntry(4*time.Second, "Dropped 7 mutations"),
+		newEntry(2*time.Second, "Node /10.0.0.2 is down"),
+		newEntry(12*time.Second, "Dropped 30 mutations"),
+		// more than 10s after the previous occurrence, so it starts a new run
+		newEntry(25*time.Second, "Dropped 3 mutations"),
+	}
+
+	deduped := dedupWithinWindow(entries, 10*time.Second)
+
+	want := []struct {
+		message string
+		count   int
+	}{
+		{"Dropped 12 mutations", 3},
+		{"Node /10.0.0.2 is down", 1},
+		{"Dropped 3 mutations", 1},
+	}
+	if len(deduped) != len(want) {
+		t.Fatalf("Expected %d entries, got %d", len(want), len(deduped))
+	}
+	for i, entry := range deduped {
+		if entry.Message != want[i].message || entry.Count != want[i].count {
+			t.Errorf("Expected %q (x%d) at index %d, got %q (x%d)", want[i].message, want[i].count, i, entry.Message, entry.Count)
+		}
+	}
+}
+
+func TestDedup(t *testing.T) {
+	start := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	newEntry := func(offset time.Duration, node string, level LogLevel, message string) *LogEntry {
+		date := start.Add(offset)
+		return &LogEntry{LogLevel: level, Date: date, NodeIP: node, Message: level.String() + "  [MutationStage-1] " + date.Format("2006-01-02 15:04:05,000") + " Server.java:10 - " + message}
+	}
+
+	t.Run("identical", func(t *testing.T) {
+		entry := newEntry(0, "10.0.0.1", WARN, "Dropped mutations")
+		entries := LogEntries{entry, newEntry(0, "10.0.0.1", WARN, "Dropped mutations"), newEntry(0, "10.0.0.1", WARN, "Dropped mutations")}
+
+		deduped := dedup(entries)
+		if len(deduped) != 1 || deduped[0] != entry || deduped[0].Count != 3 {
+			t.Fatalf("Expected the first entry with a count of 3, got %v", deduped)
+		}
+	})
+
+	t.Run("different timestamps", func(t *testing.T) {
+		entries := LogEntries{
+			newEntry(5*time.Second, "10.0.0.2", WARN, "Dropped mutations"),
+			newEntry(0, "10.0.0.1", WARN, "Dropped mutations"),
+			newEntry(time.Second, "10.0.0.1", INFO, "Dropped mutations"),
+			newEntry(2*time.Second, "10.0.0.1", WARN, "Dropped 3 mutations"),
+			newEntry(time.Hour, "10.0.0.3", WARN, "Dropped mutations"),
+		}
+
+		deduped := dedup(entries)
+
+		want := []struct {
+			node    string
+			level   LogLevel
+			message string
+			count   int
+		}{
+			// repeats on other nodes and much later are collapsed into the earliest entry
+			{"10.0.0.1", WARN, "Dropped mutations", 3},
+			// a different level or text is a different message
+			{"10.0.0.1", INFO, "Dropped mutations", 1},
+			{"10.0.0.1", WARN, "Dropped 3 mutations", 1},
+		}
+		if len(deduped) != len(want) {
+			t.Fatalf("Expected %d entries, got %d", len(want), len(deduped))
+		}
+		for i, entry := range deduped {
+			if entry.NodeIP != want[i].node || entry.LogLevel != want[i].level || entry.Body() != want[i].message || entry.Count != want[i].count {
+				t.Errorf("Expected %s %v %q (x%d) at index %d, got %s %v %q (x%d)", want[i].node, want[i].level, want[i].message, want[i].count, i,
+					entry.NodeIP, entry.LogLevel, entry.Body(), entry.Count)
+			}
+		}
+	})
+
+	t.Run("after dedup window", func(t *testing.T) {
+		entries := LogEntries{
+			newEntry(0, "10.0.0.1", WARN, "Dropped mutations"),
+			newEntry(time.Second, "10.0.0.1", WARN, "Dropped mutations"),
+			newEntry(time.Hour, "10.0.0.1", WARN, "Dropped mutations"),
+		}
+
+		deduped := dedup(dedupWithinWindow(entries, 10*time.Second))
+		if len(deduped) != 1 || deduped[0].Count != 3 {
+			t.Errorf("Expected a single entry with a count of 3, got %v", deduped)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// EventType is a kind of Cassandra event recognized in log messages.
+type EventType int
+
+const (
+	// EventNone is the type of the messages reporting none of the recognized events.
+	EventNone EventType = iota
+	// EventDroppedMutations is reported when a node drops writes it couldn't process in time, e.g. "MUTATION messages
+	// were dropped in last 5000 ms: 12 internal and 3 cross node".
+	EventDroppedMutations
+)
+
+// eventDefinition describes how to recognize an event and extract its values.
+type eventDefinition struct {
+	Type  EventType
+	Name  string         // Name is the name of the event as -event accepts it.
+	Regex *regexp.Regexp // Regex matches the messages reporting the event. Its named groups are the metrics of the event.
+}
+
+// eventDefinitions are the recognized events, checked in order by classifyEvent.
+var eventDefinitions = []eventDefinition{
+	{
+		Type: EventDroppedMutations,
+		Name: "dropped-mutations",
+		// "N internal and M cross node" since Cassandra 3.0, "N for internal timeout and M for cross node timeout" before,
+		// and MUTATION_REQ since 4.0
+		Regex: regexp.MustCompile(`\bMUTATION(?:_REQ)? messages were dropped in (?:the )?last (?P<dropped_interval_ms>\d+) ms: (?P<dropped_internal>\d+) (?:for )?internal(?: timeout)? and (?P<dropped_cross_node>\d+) (?:for )?cross node`),
+	},
+}
+
+// ParseEventType returns the event type named name, e.g. "dropped-mutations".
+func ParseEventType(name string) (EventType, error) {
+	for _, definition := range eventDefinitions {
+		if definition.Name == name {
+			return definition.Type, nil
+		}
+	}
+	return EventNone, fmt.Errorf("Invalid event: %s", name)
+}
+
+// classifyEvent returns the type of the event reported by msg, or EventNone if it reports none of them.
+func classifyEvent(msg string) EventType {
+	for _, definition := range eventDefinitions {
+		if definition.Regex.MatchString(msg) {
+			return definition.Type
+		}
+	}
+	return EventNone
+}
+
+// EventMatcher matches the entries reporting an event of the given type.
+func EventMatcher(eventType EventType) Matcher {
+	return Matcher{
+		Name:  "event",
+		Cost:  costRegex,
+		Match: func(entry *LogEntry) bool { return classifyEvent(entry.Message) == eventType },
+	}
+}
+
+// ExtractEventMetrics attaches to the entry the values of the event it reports, e.g. the dropped_internal and
+// dropped_cross_node counts of dropped mutations. Entries reporting no event are left unchanged.
+func ExtractEventMetrics(entry *LogEntry) {
+	for _, definition := range eventDefinitions {
+		match := definition.Regex.FindStringSubmatch(entry.Message)
+		if match == nil {
+			continue
+		}
+		for i, name := range definition.Regex.SubexpNames() {
+			if name == "" {
+				continue
+			}
+			value, err := strconv.ParseFloat(match[i], 64)
+			if err != nil {
+				continue
+			}
+			if entry.Metrics == nil {
+				entry.Metrics = make(map[string]float64)
+			}
+			entry.Metrics[name] = value
+		}
+		return
+	}
+}
@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestClassifyEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want EventType
+	}{
+		{
+			name: "Cassandra 3.x",
+			msg:  "INFO  [ScheduledTasks:1] 2023-07-14 16:00:00,000 MessagingService.java:1236 - MUTATION messages were dropped in last 5000 ms: 12 internal and 3 cross node. Mean internal dropped latency: 2311 ms and Mean cross-node dropped latency: 2108 ms",
+			want: EventDroppedMutations,
+		},
+		{
+			name: "Cassandra 2.x",
+			msg:  "INFO  [ScheduledTasks:1] 2023-07-14 16:00:00,000 MessagingService.java:888 - MUTATION messages were dropped in last 5000 ms: 7 for internal timeout and 0 for cross node timeout",
+			want: EventDroppedMutations,
+		},
+		{
+			name: "Cassandra 4.x",
+			msg:  "INFO  [ScheduledTasks:1] 2023-07-14 16:00:00,000 MessagingMetrics.java:206 - MUTATION_REQ messages were dropped in last 5000 ms: 0 internal and 41 cross node. Mean internal dropped latency: 0 ms and Mean cross-node dropped latency: 5034 ms",
+			want: EventDroppedMutations,
+		},
+		{
+			name: "other dropped messages",
+			msg:  "INFO  [ScheduledTasks:1] 2023-07-14 16:00:00,000 MessagingService.java:1236 - READ messages were dropped in last 5000 ms: 4 internal and 0 cross node",
+			want: EventNone,
+		},
+		{
+			name: "unrelated",
+			msg:  "INFO  [main] 2023-07-14 16:00:00,000 StorageService.java:200 - Cassandra version: 4.1.3",
+			want: EventNone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyEvent(tt.msg); got != tt.want {
+				t.Errorf("classifyEvent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEventType(t *testing.T) {
+	if got, err := ParseEventType("dropped-mutations"); err != nil || got != EventDroppedMutations {
+		t.Errorf("ParseEventType(dropped-mutations) = %v, %v, want %v", got, err, EventDroppedMutations)
+	}
+	if _, err := ParseEventType("compactions"); err == nil {
+		t.Errorf("Expected an error for an unknown event")
+	}
+}
+
+func TestDroppedMutations(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "10.0.0.1"}
+	writeSystemLog(t, topLevelDir, node.Address,
+		"INFO  [ScheduledTasks:1] 2023-07-14 16:00:00,000 MessagingService.java:1236 - MUTATION messages were dropped in last 5000 ms: 12 internal and 3 cross node. Mean internal dropped latency: 2311 ms and Mean cross-node dropped latency: 2108 ms\n"+
+			"INFO  [ScheduledTasks:1] 2023-07-14 16:00:00,000 MessagingService.java:1236 - READ messages were dropped in last 5000 ms: 4 internal and 0 cross node\n"+
+			"INFO  [ScheduledTasks:1] 2023-07-14 16:00:05,000 StatusLogger.java:47 - Pool Name                    Active   Pending\n"+
+			"INFO  [ScheduledTasks:1] 2023-07-14 16:00:10,000 MessagingService.java:1236 - MUTATION messages were dropped in last 5000 ms: 0 internal and 25 cross node. Mean internal dropped latency: 0 ms and Mean cross-node dropped latency: 4974 ms\n")
+
+	opts := ScanOptions{Matchers: []Matcher{EventMatcher(EventDroppedMutations)}}
+	entries := CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, nil, opts)[node.Address]
+	sort.Sort(ByLineNumber{LogEntries: entries})
+	for _, entry := range entries {
+		ExtractEventMetrics(entry)
+	}
+
+	want := []map[string]float64{
+		{"dropped_interval_ms": 5000, "dropped_internal": 12, "dropped_cross_node": 3},
+		{"dropped_interval_ms": 5000, "dropped_internal": 0, "dropped_cross_node": 25},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d dropped mutation entries, got %d", len(want), len(entries))
+	}
+	for i, entry := range entries {
+		if !reflect.DeepEqual(entry.Metrics, want[i]) {
+			t.Errorf("Entry %d: expected metrics %v, got %v", i, want[i], entry.Metrics)
+		}
+	}
+}
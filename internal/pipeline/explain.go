@@ -0,0 +1,34 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+)
+
+// explainThreadRegex matches the bracketed thread name following the log level.
+var explainThreadRegex = regexp.MustCompile(`^\w+\s+\[([^\]]*)\]`)
+
+// ExplainLine parses a single log line with opts, as if read from a file modified now, and writes the fields extracted
+// from it to w, one per line. If the line can't be parsed, it writes and returns the parse error instead.
+func ExplainLine(w io.Writer, line string, opts ScanOptions) error {
+	opts.modTime = time.Now()
+	entry, err := processLine(line, 1, "", opts)
+	if entry == nil && err == nil {
+		err = errNoLevelAndDate
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "error: %v\n", err)
+		return err
+	}
+
+	var thread string
+	if match := explainThreadRegex.FindStringSubmatch(entry.Message); match != nil {
+		thread = match[1]
+	}
+
+	_, err = fmt.Fprintf(w, "level: %s\ndate: %s\nthread: %s\nsource: %s\nmessage: %s\n",
+		entry.LogLevel.String(), entry.Date.Format(time.RFC3339Nano), thread, entry.Source(), entry.Body())
+	return err
+}
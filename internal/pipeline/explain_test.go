@@ -0,0 +1,53 @@
+package pipeline
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExplainLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "full line",
+			line: "WARN  [Service Thread] 2023-07-14 16:00:00,658 GCInspector.java:282 - G1 Young Generation GC in 523ms",
+			want: "level: WARN\ndate: 2023-07-14T16:00:00.658Z\nthread: Service Thread\nsource: GCInspector.java:282\n" +
+				"message: G1 Young Generation GC in 523ms\n",
+		},
+		{
+			name: "no thread or source",
+			line: "WARN  2023-04-24 12:12:32,430 org.apache.hadoop.hive.conf.HiveConf: HiveConf expects INT type value",
+			want: "level: WARN\ndate: 2023-04-24T12:12:32.43Z\nthread: \nsource: \n" +
+				"message: WARN  2023-04-24 12:12:32,430 org.apache.hadoop.hive.conf.HiveConf: HiveConf expects INT type value\n",
+		},
+		{
+			name:    "invalid level",
+			line:    "BOGUS [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting",
+			want:    "error: Invalid log level: BOGUS\n",
+			wantErr: true,
+		},
+		{
+			name:    "no date",
+			line:    "INFO replaying commit log segments",
+			want:    "error: No log level and date found\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := ExplainLine(&buf, tt.line, ScanOptions{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExplainLine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("ExplainLine() wrote:\n%s\nwant:\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// NodeError is the error that stopped the processing of the logs of a node, e.g. a missing log file.
+type NodeError struct {
+	Address string // Address is the address of the node.
+	Err     error  // Err is the error returned by ProcessFile for the node.
+}
+
+// NodeErrors collects the errors of the nodes processed concurrently, to report them together after the results. It is
+// safe for concurrent use.
+type NodeErrors struct {
+	mu     sync.Mutex
+	errors []NodeError
+}
+
+// Add records the error of the node at address.
+func (e *NodeErrors) Add(address string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errors = append(e.errors, NodeError{Address: address, Err: err})
+}
+
+// Errors returns the recorded errors sorted by node address, the errors of a node in the order they were recorded.
+func (e *NodeErrors) Errors() []NodeError {
+	e.mu.Lock()
+	errors := append([]NodeError(nil), e.errors...)
+	e.mu.Unlock()
+
+	sort.SliceStable(errors, func(i, j int) bool {
+		return errors[i].Address < errors[j].Address
+	})
+	return errors
+}
+
+// PrintFailedNodes writes a "Failed nodes" section to w listing each node error, headed by how many of the total nodes
+// failed. Nothing is written without errors.
+func PrintFailedNodes(w io.Writer, errors []NodeError, total int) error {
+	if len(errors) == 0 {
+		return nil
+	}
+	failed := make(map[string]bool)
+	for _, nodeErr := range errors {
+		failed[nodeErr.Address] = true
+	}
+	if _, err := fmt.Fprintf(w, "Failed nodes (%d of %d):\n", len(failed), total); err != nil {
+		return err
+	}
+	for _, nodeErr := range errors {
+		if _, err := fmt.Fprintf(w, "  %s: %v\n", nodeErr.Address, nodeErr.Err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
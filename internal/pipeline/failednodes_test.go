@@ -0,0 +1,84 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"testing"
+)
+
+func TestNodeErrorsConcurrentAdd(t *testing.T) {
+	const nodeCount = 100
+	var nodeErrors NodeErrors
+	var wg sync.WaitGroup
+	for i := 0; i < nodeCount; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			nodeErrors.Add(fmt.Sprintf("10.0.%d.%d", i/10, i%10), fmt.Errorf("error %d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	errs := nodeErrors.Errors()
+	if len(errs) != nodeCount {
+		t.Fatalf("Expected %d errors, got %d", nodeCount, len(errs))
+	}
+	seen := make(map[string]bool)
+	for i, nodeErr := range errs {
+		seen[nodeErr.Address] = true
+		if i > 0 && errs[i-1].Address > nodeErr.Address {
+			t.Errorf("Expected the errors sorted by address, got %s before %s", errs[i-1].Address, nodeErr.Address)
+		}
+	}
+	if len(seen) != nodeCount {
+		t.Errorf("Expected the errors of %d nodes, got %d", nodeCount, len(seen))
+	}
+}
+
+func TestPrintFailedNodes(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PrintFailedNodes(&buf, nil, 3); err != nil {
+		t.Fatalf("PrintFailedNodes() error = %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output without errors, got %q", buf.String())
+	}
+
+	errs := []NodeError{
+		{Address: "10.0.0.1", Err: errors.New("missing system.log")},
+		{Address: "10.0.0.2", Err: errors.New("invalid gzip file")},
+	}
+	if err := PrintFailedNodes(&buf, errs, 3); err != nil {
+		t.Fatalf("PrintFailedNodes() error = %v", err)
+	}
+	want := "Failed nodes (2 of 3):\n  10.0.0.1: missing system.log\n  10.0.0.2: invalid gzip file\n"
+	if buf.String() != want {
+		t.Errorf("PrintFailedNodes() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCollectNodeEntriesNodeErrors(t *testing.T) {
+	topLevelDir := t.TempDir()
+	writeSystemLog(t, topLevelDir, "10.0.0.1", "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n")
+	nodes := []Node{{Address: "10.0.0.1"}, {Address: "10.0.0.2"}, {Address: "10.0.0.3"}}
+
+	opts := ScanOptions{NodeErrors: &NodeErrors{}}
+	nodeEntries := CollectNodeEntries(context.Background(), nodes, topLevelDir, nil, opts)
+	if len(nodeEntries["10.0.0.1"]) != 1 {
+		t.Errorf("Expected 1 entry for 10.0.0.1, got %d", len(nodeEntries["10.0.0.1"]))
+	}
+
+	errs := opts.NodeErrors.Errors()
+	if len(errs) != 2 || errs[0].Address != "10.0.0.2" || errs[1].Address != "10.0.0.3" {
+		t.Fatalf("Expected errors for 10.0.0.2 and 10.0.0.3, got %v", errs)
+	}
+	for _, nodeErr := range errs {
+		if !errors.Is(nodeErr.Err, fs.ErrNotExist) {
+			t.Errorf("Expected a missing file error for %s, got %v", nodeErr.Address, nodeErr.Err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package pipeline
+
+import (
+	"sort"
+	"time"
+)
+
+// EntryFilters are the filters that work on collected entries, so they also apply to -input-json. The zero value keeps
+// every entry.
+type EntryFilters struct {
+	Extractors     []MetricExtractor // Extractors extract the -metrics-patterns metrics of every entry.
+	Event          EventType         // Event extracts the metrics of the -event events, EventNone for none.
+	MinLevel       LogLevel          // MinLevel is the lowest log level kept, DEBUG keeps them all.
+	Since          time.Time         // Since is the earliest date kept, the zero time for no limit.
+	Until          time.Time         // Until is the latest date kept, the zero time for no limit.
+	KV             bool              // KV parses the key=value pairs of every message body into the fields of the entry.
+	KVFilter       map[string]string // KVFilter keeps the entries with these key=value pairs, nil keeps them all.
+	GCMinMs        int               // GCMinMs keeps the GC pauses of at least this many milliseconds, 0 for every entry.
+	MetricMinName  string            // MetricMinName is the metric compared to MetricMinValue, empty for none.
+	MetricMinValue float64           // MetricMinValue is the lowest value of MetricMinName kept.
+	MinLines       int               // MinLines keeps the entries spanning at least this many lines.
+	DedupWindow    time.Duration     // DedupWindow collapses the repeats within it on a node, see dedupWithinWindow.
+	Dedup          bool              // Dedup collapses the repeats across nodes, see dedup.
+}
+
+// apply returns the entries f keeps, after extracting their metrics and fields.
+func (f EntryFilters) Apply(entries LogEntries) LogEntries {
+	if len(f.Extractors) > 0 {
+		for _, entry := range entries {
+			ExtractMetrics(entry, f.Extractors)
+		}
+	}
+	if f.Event != EventNone {
+		for _, entry := range entries {
+			ExtractEventMetrics(entry)
+		}
+	}
+	if f.MinLevel > DEBUG {
+		entries = filterByMinLevel(entries, f.MinLevel)
+	}
+	if !f.Since.IsZero() || !f.Until.IsZero() {
+		entries = filterByTimeRange(entries, f.Since, f.Until)
+	}
+	if f.KV || f.KVFilter != nil {
+		for _, entry := range entries {
+			entry.Fields = parseKeyValues(entry.Body())
+		}
+	}
+	if f.KVFilter != nil {
+		entries = filterByKeyValues(entries, f.KVFilter)
+	}
+	if f.GCMinMs > 0 {
+		entries = filterByGCPause(entries, f.GCMinMs)
+	}
+	if f.MetricMinName != "" {
+		entries = filterByMetricMin(entries, f.MetricMinName, f.MetricMinValue)
+	}
+	if f.MinLines > 0 {
+		entries = filterByMinLines(entries, f.MinLines)
+	}
+	if f.DedupWindow > 0 {
+		entries = dedupWithinWindow(entries, f.DedupWindow)
+	}
+	if f.Dedup {
+		entries = dedup(entries)
+	}
+	return entries
+}
+
+// TailEntries returns the n most recent entries in date order, or all of them if there are no more than n. An n of 0
+// means no limit and returns the entries unchanged.
+func TailEntries(entries LogEntries, n int) LogEntries {
+	if n <= 0 {
+		return entries
+	}
+	sorted := append(LogEntries(nil), entries...)
+	sort.Stable(ByDate{LogEntries: sorted})
+	if len(sorted) > n {
+		sorted = sorted[len(sorted)-n:]
+	}
+	return sorted
+}
+
+// filterByTimeRange keeps only the entries dated within [since, until], both inclusive. A zero bound leaves that side
+// of the range open.
+func filterByTimeRange(entries LogEntries, since, until time.Time) LogEntries {
+	var filtered LogEntries
+	for _, entry := range entries {
+		if !since.IsZero() && entry.Date.Before(since) {
+			continue
+		}
+		if !until.IsZero() && entry.Date.After(until) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// filterByMinLevel keeps only the entries with a log level of at least min.
+func filterByMinLevel(entries LogEntries, min LogLevel) LogEntries {
+	var filtered LogEntries
+	for _, entry := range entries {
+		if entry.LogLevel >= min {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// filterByMinLines keeps only the entries spanning at least min lines.
+func filterByMinLines(entries LogEntries, min int) LogEntries {
+	var filtered LogEntries
+	for _, entry := range entries {
+		if entry.LineCount >= min {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
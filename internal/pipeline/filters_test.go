@@ -0,0 +1,171 @@
+package pipeline
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github/kenjords/wetlog/pkg/wetlog"
+)
+
+func TestEntryFilters(t *testing.T) {
+	start := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	newEntries := func() LogEntries {
+		return LogEntries{
+			{LineNumber: 1, LogLevel: INFO, Date: start, Message: "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - pool=Native active=1", LineCount: 1},
+			{LineNumber: 2, LogLevel: WARN, Date: start.Add(time.Minute), Message: "WARN  [main] 2023-07-14 16:01:00,000 Server.java:10 - pool=Native active=2", LineCount: 1},
+			{LineNumber: 3, LogLevel: ERROR, Date: start.Add(2 * time.Minute), Message: "ERROR [main] 2023-07-14 16:02:00,000 Server.java:10 - pool=Compaction\n\tat Server.run", LineCount: 2},
+			{LineNumber: 4, LogLevel: ERROR, Date: start.Add(3 * time.Minute), Message: "ERROR [main] 2023-07-14 16:03:00,000 Server.java:10 - pool=Native failed", LineCount: 1},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		filters EntryFilters
+		want    []int
+	}{
+		{name: "none", filters: EntryFilters{}, want: []int{1, 2, 3, 4}},
+		{name: "min level", filters: EntryFilters{MinLevel: WARN}, want: []int{2, 3, 4}},
+		{name: "time range", filters: EntryFilters{Since: start.Add(time.Minute), Until: start.Add(2 * time.Minute)}, want: []int{2, 3}},
+		{name: "key values", filters: EntryFilters{KVFilter: map[string]string{"pool": "Native"}}, want: []int{1, 2, 4}},
+		{name: "min lines", filters: EntryFilters{MinLines: 2}, want: []int{3}},
+		{name: "combined", filters: EntryFilters{MinLevel: ERROR, KVFilter: map[string]string{"pool": "Native"}}, want: []int{4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, entry := range tt.filters.Apply(newEntries()) {
+				got = append(got, entry.LineNumber)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected lines %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEntryFiltersKV(t *testing.T) {
+	entries := EntryFilters{KV: true}.Apply(LogEntries{{Message: "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - pool=Native active=1"}})
+	if want := map[string]string{"pool": "Native", "active": "1"}; !reflect.DeepEqual(entries[0].Fields, want) {
+		t.Errorf("Expected fields %v, got %v", want, entries[0].Fields)
+	}
+}
+
+func TestTailEntries(t *testing.T) {
+	start := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	entries := LogEntries{
+		{LineNumber: 1, LogLevel: ERROR, Date: start.Add(3 * time.Second)},
+		{LineNumber: 2, LogLevel: INFO, Date: start},
+		{LineNumber: 3, LogLevel: WARN, Date: start.Add(2 * time.Second)},
+		{LineNumber: 4, LogLevel: DEBUG, Date: start.Add(time.Second)},
+	}
+
+	tests := []struct {
+		name string
+		n    int
+		want []int
+	}{
+		{name: "most recent", n: 2, want: []int{3, 1}},
+		{name: "larger than the set", n: 10, want: []int{2, 4, 3, 1}},
+		{name: "exact size", n: 4, want: []int{2, 4, 3, 1}},
+		{name: "unbounded", n: 0, want: []int{1, 2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, entry := range TailEntries(entries, tt.n) {
+				got = append(got, entry.LineNumber)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected entries from lines %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// the requested sort is applied to the tail afterwards
+	tailed := TailEntries(entries, 3)
+	sort.Sort(ByLogLevel{LogEntries: tailed})
+	if tailed[0].LineNumber != 4 || tailed[2].LineNumber != 1 {
+		t.Errorf("Expected the tail sorted by level from line 4 to line 1, got %v", tailed)
+	}
+	if entries[0].LineNumber != 1 {
+		t.Errorf("Expected the original entries to be left in place")
+	}
+}
+
+func TestFilterByTimeRange(t *testing.T) {
+	date := func(s string) time.Time {
+		t.Helper()
+		d, err := wetlog.ParseDate(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	entries := LogEntries{
+		{LineNumber: 1, Date: date("2023-07-14 01:59:59,999")},
+		{LineNumber: 2, Date: date("2023-07-14 02:00:00,000")},
+		{LineNumber: 3, Date: date("2023-07-14 02:07:30,000")},
+		{LineNumber: 4, Date: date("2023-07-14 02:15:00,000")},
+		{LineNumber: 5, Date: date("2023-07-14 02:15:00,001")},
+	}
+
+	tests := []struct {
+		name  string
+		since time.Time
+		until time.Time
+		want  []int
+	}{
+		{name: "inclusive bounds", since: date("2023-07-14 02:00:00,000"), until: date("2023-07-14 02:15:00,000"), want: []int{2, 3, 4}},
+		{name: "open end", since: date("2023-07-14 02:07:30,000"), want: []int{3, 4, 5}},
+		{name: "open start", until: date("2023-07-14 02:00:00,000"), want: []int{1, 2}},
+		{name: "unbounded", want: []int{1, 2, 3, 4, 5}},
+		{name: "empty range", since: date("2023-07-14 03:00:00,000"), until: date("2023-07-14 04:00:00,000"), want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, entry := range filterByTimeRange(entries, tt.since, tt.until) {
+				got = append(got, entry.LineNumber)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected entries from lines %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFilterByMinLevel(t *testing.T) {
+	entries := LogEntries{
+		{LogLevel: DEBUG, LineNumber: 1},
+		{LogLevel: WARN, LineNumber: 2},
+		{LogLevel: INFO, LineNumber: 3},
+		{LogLevel: ERROR, LineNumber: 4},
+	}
+
+	tests := []struct {
+		min  LogLevel
+		want []int
+	}{
+		{min: DEBUG, want: []int{1, 2, 3, 4}},
+		{min: INFO, want: []int{2, 3, 4}},
+		{min: WARN, want: []int{2, 4}},
+		{min: ERROR, want: []int{4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.min.String(), func(t *testing.T) {
+			var got []int
+			for _, entry := range filterByMinLevel(entries, tt.min) {
+				got = append(got, entry.LineNumber)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected entries from lines %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
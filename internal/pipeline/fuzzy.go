@@ -0,0 +1,93 @@
+package pipeline
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// costFuzzy is the cost of comparing every token of the message with the query terms.
+	costFuzzy = 50
+	// DefaultFuzzyDistance is the default number of edits -fuzzy tolerates between a query term and the message.
+	DefaultFuzzyDistance = 1
+)
+
+// tokenize splits s into its words, runs of letters and digits, lowercased if ignoreCase is true.
+func tokenize(s string, ignoreCase bool) []string {
+	if ignoreCase {
+		s = strings.ToLower(s)
+	}
+	return strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+}
+
+// levenshtein returns the number of single rune insertions, deletions and substitutions turning a into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			substitution := prev[j-1]
+			if ra[i-1] != rb[j-1] {
+				substitution++
+			}
+			cur[j] = substitution
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// fuzzyContains returns true if the message tokens hold a run of consecutive tokens within maxDistance edits of the
+// term tokens, the edits of each pair of tokens adding up.
+func fuzzyContains(tokens, term []string, maxDistance int) bool {
+	if len(term) == 0 {
+		return true
+	}
+	for start := 0; start+len(term) <= len(tokens); start++ {
+		distance := 0
+		for i, word := range term {
+			distance += levenshtein(tokens[start+i], word)
+			if distance > maxDistance {
+				break
+			}
+		}
+		if distance <= maxDistance {
+			return true
+		}
+	}
+	return false
+}
+
+// FuzzyMatcher matches entries whose message holds every query term within maxDistance edits, comparing words rather
+// than substrings, see fuzzyContains. Words are compared ignoring case if ignoreCase is true. With matchAny, any of the
+// terms is enough.
+func FuzzyMatcher(queries []string, maxDistance int, ignoreCase, matchAny bool) Matcher {
+	terms := make([][]string, 0, len(queries))
+	for _, query := range queries {
+		terms = append(terms, tokenize(query, ignoreCase))
+	}
+	return Matcher{
+		Name: "fuzzy",
+		Cost: costFuzzy,
+		Match: func(entry *LogEntry) bool {
+			tokens := tokenize(entry.Message, ignoreCase)
+			for _, term := range terms {
+				if fuzzyContains(tokens, term, maxDistance) == matchAny {
+					return matchAny
+				}
+			}
+			return !matchAny || len(terms) == 0
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package pipeline
+
+import "testing"
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"timeout", "timeout", 0},
+		{"timout", "timeout", 1},
+		{"tiemout", "timeout", 2},
+		{"compaction", "compation", 1},
+		{"gossip", "", 6},
+		{"héllo", "hello", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyMatcher(t *testing.T) {
+	entry := &LogEntry{Message: "WARN  [main] 2023-07-14 16:00:00,000 ReadCallback.java:120 - Read timeout: Compaction interrupted"}
+	tests := []struct {
+		name       string
+		queries    []string
+		distance   int
+		ignoreCase bool
+		matchAny   bool
+		want       bool
+	}{
+		{name: "exact word", queries: []string{"timeout"}, distance: 0, want: true},
+		{name: "near miss within distance", queries: []string{"timout"}, distance: 1, want: true},
+		{name: "near miss beyond distance", queries: []string{"tiemout"}, distance: 1, want: false},
+		{name: "near miss at larger distance", queries: []string{"tiemout"}, distance: 2, want: true},
+		{name: "phrase", queries: []string{"Compation interupted"}, distance: 2, want: true},
+		{name: "phrase beyond distance", queries: []string{"Compation interupted"}, distance: 1, want: false},
+		{name: "words, not substrings", queries: []string{"time"}, distance: 1, want: false},
+		{name: "all terms", queries: []string{"timout", "gossip"}, distance: 1, want: false},
+		{name: "any term", queries: []string{"timout", "gossip"}, distance: 1, matchAny: true, want: true},
+		{name: "case-sensitive", queries: []string{"compaction"}, distance: 0, want: false},
+		{name: "ignore case", queries: []string{"COMPACTON"}, distance: 1, ignoreCase: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FuzzyMatcher(tt.queries, tt.distance, tt.ignoreCase, tt.matchAny).Match(entry); got != tt.want {
+				t.Errorf("FuzzyMatcher(%q, %d) = %v, want %v", tt.queries, tt.distance, got, tt.want)
+			}
+		})
+	}
+}
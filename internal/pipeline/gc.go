@@ -0,0 +1,42 @@
+package pipeline
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// gcPauseRegex matches the pause duration GCInspector logs, e.g. "G1 Young Generation GC in 523ms." since Cassandra 2.1
+// or "GC for G1 Young Generation: 523 ms for 1 collections" before.
+var gcPauseRegex = regexp.MustCompile(`GC (?:in |for [\w ]+: )([\d,]+)\s?ms`)
+
+// parseGCPauseMillis returns the GC pause duration in milliseconds reported by msg, and false if it reports none.
+func parseGCPauseMillis(msg string) (int, bool) {
+	match := gcPauseRegex.FindStringSubmatch(msg)
+	if match == nil {
+		return 0, false
+	}
+	millis, err := strconv.Atoi(strings.ReplaceAll(match[1], ",", ""))
+	if err != nil {
+		return 0, false
+	}
+	return millis, true
+}
+
+// filterByGCPause keeps only the entries reporting a GC pause of at least minMillis milliseconds, recording its
+// duration in their gc_pause_ms metric.
+func filterByGCPause(entries LogEntries, minMillis int) LogEntries {
+	var filtered LogEntries
+	for _, entry := range entries {
+		millis, ok := parseGCPauseMillis(entry.Message)
+		if !ok || millis < minMillis {
+			continue
+		}
+		if entry.Metrics == nil {
+			entry.Metrics = make(map[string]float64)
+		}
+		entry.Metrics["gc_pause_ms"] = float64(millis)
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
@@ -0,0 +1,77 @@
+package pipeline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGCPauseMillis(t *testing.T) {
+	tests := []struct {
+		name   string
+		msg    string
+		want   int
+		wantOK bool
+	}{
+		{
+			name:   "G1 young generation",
+			msg:    "INFO  [Service Thread] 2023-07-14 16:00:00,658 GCInspector.java:284 - G1 Young Generation GC in 523ms.  G1 Eden Space: 754974720 -> 0; G1 Old Gen: 1092616192 -> 1186988032;",
+			want:   523,
+			wantOK: true,
+		},
+		{
+			name:   "old generation with a thousands separator",
+			msg:    "WARN  [Service Thread] 2023-07-14 16:00:00,658 GCInspector.java:282 - G1 Old Generation GC in 2,341ms.  G1 Old Gen: 8321499136 -> 4211081216;",
+			want:   2341,
+			wantOK: true,
+		},
+		{
+			name:   "CMS",
+			msg:    "WARN  [Service Thread] 2023-07-14 16:00:00,658 GCInspector.java:282 - ConcurrentMarkSweep GC in 1203ms.  CMS Old Gen: 6322563760 -> 3284751168;",
+			want:   1203,
+			wantOK: true,
+		},
+		{
+			name:   "Cassandra 2.0",
+			msg:    "INFO  [ScheduledTasks:1] 2023-07-14 16:00:00,658 GCInspector.java:116 - GC for G1 Young Generation: 523 ms for 1 collections, 2874934880 used; max is 8422162432",
+			want:   523,
+			wantOK: true,
+		},
+		{
+			name:   "ParNew in Cassandra 2.0",
+			msg:    "INFO  [ScheduledTasks:1] 2023-07-14 16:00:00,658 GCInspector.java:116 - GC for ParNew: 245 ms for 1 collections, 1531520712 used; max is 8422162432",
+			want:   245,
+			wantOK: true,
+		},
+		{
+			name: "no pause",
+			msg:  "INFO  [Service Thread] 2023-07-14 16:00:00,658 StatusLogger.java:47 - Pool Name                    Active   Pending",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseGCPauseMillis(tt.msg)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseGCPauseMillis() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFilterByGCPause(t *testing.T) {
+	entries := LogEntries{
+		{LineNumber: 1, Message: "G1 Young Generation GC in 120ms."},
+		{LineNumber: 2, Message: "Compacted 4 sstables in 523ms"},
+		{LineNumber: 3, Message: "GC for ParNew: 500 ms for 1 collections"},
+		{LineNumber: 4, Message: "G1 Old Generation GC in 2,341ms."},
+	}
+
+	filtered := filterByGCPause(entries, 500)
+	var got []float64
+	for _, entry := range filtered {
+		got = append(got, entry.Metrics["gc_pause_ms"])
+	}
+	if want := []float64{500, 2341}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the pauses of at least 500ms, got %v", got)
+	}
+}
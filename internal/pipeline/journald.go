@@ -0,0 +1,85 @@
+package pipeline
+
+import (
+	"regexp"
+	"time"
+
+	"github/kenjords/wetlog/pkg/wetlog"
+)
+
+// journaldLineRegex matches a line exported by journalctl in the short or short-iso output formats, capturing the
+// timestamp, the hostname and the message following the "process[pid]:" token.
+var journaldLineRegex = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\S+|[A-Z][a-z]{2}\s+\d{1,2}\s\d{2}:\d{2}:\d{2})\s+(\S+)\s+[^\s:]+:\s?(.*)$`)
+
+// journaldLevelRegex matches the log level starting the message of a journald line, e.g. "WARN  [main] ...".
+var journaldLevelRegex = regexp.MustCompile(`^(\w+)\s`)
+
+// journaldDateLayouts are the timestamp layouts of journalctl -o short-iso and short-iso-precise.
+var journaldDateLayouts = []string{
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05.999999-0700",
+	time.RFC3339Nano,
+}
+
+// journaldShortDateLayout is the timestamp layout of journalctl -o short, which has no year.
+const journaldShortDateLayout = "Jan _2 15:04:05"
+
+// journaldMessage returns the message of a journald line without its prefix, or the line itself if it has no prefix.
+func journaldMessage(line string) string {
+	match := journaldLineRegex.FindStringSubmatch(line)
+	if match == nil {
+		return line
+	}
+	return match[3]
+}
+
+// parseJournaldDate parses a journald timestamp. Timestamps without a year are placed in the year of modTime.
+func parseJournaldDate(s string, modTime time.Time) (time.Time, error) {
+	if date, err := time.Parse(journaldShortDateLayout, s); err == nil {
+		return wetlog.InferYear(date, modTime), nil
+	}
+
+	var err error
+	for _, layout := range journaldDateLayouts {
+		var date time.Time
+		if date, err = time.Parse(layout, s); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// processJournaldLine parses a line exported by journald into a log entry. The date and node come from the journald
+// prefix and the log level from the start of the message. Like processLine, it returns a nil entry and error for lines
+// that don't start an entry.
+func processJournaldLine(line string, lineNumber int, filePath string, opts ScanOptions) (*LogEntry, error) {
+	match := journaldLineRegex.FindStringSubmatch(line)
+	if match == nil {
+		return nil, nil
+	}
+	message := match[3]
+
+	logLevelMatch := journaldLevelRegex.FindStringSubmatch(message)
+	if logLevelMatch == nil {
+		return nil, nil
+	}
+	logLevel, err := wetlog.ParseLogLevel(logLevelMatch[1])
+	if err != nil {
+		return nil, err
+	}
+
+	date, err := parseJournaldDate(match[1], opts.modTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LogEntry{
+		LogLevel:   logLevel,
+		Date:       date,
+		LineNumber: lineNumber,
+		NodeIP:     match[2],
+		FilePath:   filePath,
+		Message:    message,
+		LineCount:  1,
+	}, nil
+}
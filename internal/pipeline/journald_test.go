@@ -0,0 +1,110 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestProcessJournaldLine(t *testing.T) {
+	modTime := time.Date(2023, 7, 20, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		line        string
+		wantNil     bool
+		wantErr     bool
+		wantLevel   LogLevel
+		wantDate    time.Time
+		wantHost    string
+		wantMessage string
+	}{
+		{
+			name:        "short-iso",
+			line:        "2023-07-14T16:00:00+0000 cass-node-1 cassandra[1234]: INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting",
+			wantLevel:   INFO,
+			wantDate:    time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC),
+			wantHost:    "cass-node-1",
+			wantMessage: "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting",
+		},
+		{
+			name:        "short-iso-precise",
+			line:        "2023-07-14T16:00:00.658000+0000 10.0.0.1 java[42]: WARN  [Service Thread] GCInspector.java:282 - G1 Young Generation GC in 523ms",
+			wantLevel:   WARN,
+			wantDate:    time.Date(2023, 7, 14, 16, 0, 0, 658000000, time.UTC),
+			wantHost:    "10.0.0.1",
+			wantMessage: "WARN  [Service Thread] GCInspector.java:282 - G1 Young Generation GC in 523ms",
+		},
+		{
+			name:        "short without year",
+			line:        "Jul 14 16:00:00 cass-node-2 cassandra[1234]: ERROR [main] Server.java:10 - Failed",
+			wantLevel:   ERROR,
+			wantDate:    time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC),
+			wantHost:    "cass-node-2",
+			wantMessage: "ERROR [main] Server.java:10 - Failed",
+		},
+		{
+			name:    "continuation",
+			line:    "Jul 14 16:00:00 cass-node-2 cassandra[1234]: \tat org.apache.cassandra.Server.run(Server.java:10)",
+			wantNil: true,
+		},
+		{
+			name:    "not journald",
+			line:    "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting",
+			wantNil: true,
+		},
+		{
+			name:    "invalid level",
+			line:    "Jul 14 16:00:00 cass-node-2 systemd[1]: Started Cassandra",
+			wantNil: true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := processJournaldLine(tt.line, 7, "journal.log", ScanOptions{Journald: true, modTime: modTime})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("processJournaldLine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantNil {
+				if entry != nil {
+					t.Errorf("Expected no entry, got %+v", entry)
+				}
+				return
+			}
+			if entry.LogLevel != tt.wantLevel || !entry.Date.Equal(tt.wantDate) || entry.NodeIP != tt.wantHost || entry.Message != tt.wantMessage || entry.LineNumber != 7 {
+				t.Errorf("processJournaldLine() = %+v, want level %v, date %v, host %s and message %q", entry, tt.wantLevel, tt.wantDate, tt.wantHost, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestProcessFileJournald(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "10.0.0.1"}
+	logFile := writeSystemLog(t, topLevelDir, node.Address,
+		"Jul 14 16:00:00 cass-node-1 cassandra[1234]: ERROR [main] Server.java:10 - Failed\n"+
+			"Jul 14 16:00:00 cass-node-1 cassandra[1234]: \tat org.apache.cassandra.Server.run(Server.java:10)\n"+
+			"Jul 14 16:00:01 cass-node-1 cassandra[1234]: INFO  [main] Server.java:10 - Starting\n")
+	modTime := time.Date(2023, 7, 20, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(logFile, modTime, modTime); err != nil {
+		t.Fatalf("Couldn't set modification time: %v", err)
+	}
+
+	entries := CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, nil, ScanOptions{Journald: true})[node.Address]
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	first := entries[0]
+	if first.LineNumber != 1 {
+		first = entries[1]
+	}
+	wantMessage := "ERROR [main] Server.java:10 - Failed\n\tat org.apache.cassandra.Server.run(Server.java:10)"
+	if first.Message != wantMessage || first.LineCount != 2 {
+		t.Errorf("Expected message %q spanning 2 lines, got %q spanning %d", wantMessage, first.Message, first.LineCount)
+	}
+	if want := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC); !first.Date.Equal(want) {
+		t.Errorf("Expected date %v, got %v", want, first.Date)
+	}
+}
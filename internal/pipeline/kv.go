@@ -0,0 +1,61 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// keyValueRegex matches a key=value pair of a message. The value is either double-quoted, and may then contain spaces,
+// or runs to the next whitespace.
+var keyValueRegex = regexp.MustCompile(`(?:^|\s)([\w.-]+)=("[^"]*"|\S*)`)
+
+// parseKeyValues returns the key=value pairs of s, with the quotes of quoted values removed, or nil if there are none.
+// A key appearing several times keeps its last value.
+func parseKeyValues(s string) map[string]string {
+	matches := keyValueRegex.FindAllStringSubmatch(s, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	fields := make(map[string]string, len(matches))
+	for _, match := range matches {
+		value := match[2]
+		// an unterminated quote is kept as part of the value
+		if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+			value = value[1 : len(value)-1]
+		}
+		fields[match[1]] = value
+	}
+	return fields
+}
+
+// ParseKeyValueFilter parses a comma-separated list of key=value pairs, e.g. "pool=CompactionExecutor,state=done".
+func ParseKeyValueFilter(s string) (map[string]string, error) {
+	filter := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("Invalid key=value filter: %s", pair)
+		}
+		filter[key] = value
+	}
+	return filter, nil
+}
+
+// filterByKeyValues keeps only the entries whose Fields hold every pair of filter.
+func filterByKeyValues(entries LogEntries, filter map[string]string) LogEntries {
+	var filtered LogEntries
+	for _, entry := range entries {
+		matches := true
+		for key, value := range filter {
+			if got, ok := entry.Fields[key]; !ok || got != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,60 @@
+package pipeline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeyValues(t *testing.T) {
+	tests := []struct {
+		body string
+		want map[string]string
+	}{
+		{body: "pool=CompactionExecutor active=2 pending=0", want: map[string]string{"pool": "CompactionExecutor", "active": "2", "pending": "0"}},
+		{body: `Task finished state="in progress" id=7`, want: map[string]string{"state": "in progress", "id": "7"}},
+		{body: "empty= key.name=a-b", want: map[string]string{"empty": "", "key.name": "a-b"}},
+		{body: "x=1 x=2", want: map[string]string{"x": "2"}},
+		{body: `state="abc id=7`, want: map[string]string{"state": `"abc`, "id": "7"}},
+		{body: `quote=" id=7`, want: map[string]string{"quote": `"`, "id": "7"}},
+		{body: "Node /10.0.0.1 is down, retries = 3", want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := parseKeyValues(tt.body); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseKeyValues(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestFilterByKeyValues(t *testing.T) {
+	messages := []string{
+		"INFO  [main] 2023-07-14 16:00:00,000 StatusLogger.java:65 - pool=CompactionExecutor active=2 pending=5",
+		"INFO  [main] 2023-07-14 16:00:00,000 StatusLogger.java:65 - pool=MutationStage active=2 pending=0",
+		"INFO  [main] 2023-07-14 16:00:00,000 StatusLogger.java:65 - No pairs in this message",
+	}
+	var entries LogEntries
+	for i, message := range messages {
+		entry := &LogEntry{LineNumber: i + 1, Message: message}
+		entry.Fields = parseKeyValues(entry.Body())
+		entries = append(entries, entry)
+	}
+
+	filter, err := ParseKeyValueFilter("pool=CompactionExecutor,active=2")
+	if err != nil {
+		t.Fatalf("ParseKeyValueFilter() error = %v", err)
+	}
+	filtered := filterByKeyValues(entries, filter)
+	if len(filtered) != 1 || filtered[0].LineNumber != 1 {
+		t.Fatalf("Expected only the CompactionExecutor entry, got %v", filtered)
+	}
+	want := map[string]string{"pool": "CompactionExecutor", "active": "2", "pending": "5"}
+	if !reflect.DeepEqual(filtered[0].Fields, want) {
+		t.Errorf("Expected fields %v, got %v", want, filtered[0].Fields)
+	}
+
+	for _, invalid := range []string{"pool", "=value", "pool=a,"} {
+		if _, err := ParseKeyValueFilter(invalid); err == nil {
+			t.Errorf("Expected an error for filter %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Relative costs of the built-in matchers. A MatcherChain evaluates cheaper matchers first.
+const (
+	costField     = 0   // costField is the cost of comparing a parsed field such as the log level.
+	costSubstring = 10  // costSubstring is the cost of searching the message for substrings.
+	costRegex     = 100 // costRegex is the cost of running regular expressions over the message.
+)
+
+// Matcher decides whether a log entry is kept.
+type Matcher struct {
+	Name  string               // Name identifies the matcher.
+	Cost  int                  // Cost is the relative cost of Match, cheaper matchers are evaluated first.
+	Match func(*LogEntry) bool // Match returns true if the entry should be kept.
+}
+
+// MatcherChain is a list of matchers that must all match an entry, ordered from cheapest to most expensive.
+type MatcherChain []Matcher
+
+// NewMatcherChain returns a chain of the given matchers ordered by cost, keeping the given order between equal costs.
+func NewMatcherChain(matchers ...Matcher) MatcherChain {
+	chain := append(MatcherChain(nil), matchers...)
+	sort.SliceStable(chain, func(i, j int) bool { return chain[i].Cost < chain[j].Cost })
+	return chain
+}
+
+// Match returns true if every matcher in the chain matches the entry. It stops at the first matcher that doesn't, so
+// expensive matchers never run for entries a cheaper one already rejected.
+func (c MatcherChain) Match(entry *LogEntry) bool {
+	for _, matcher := range c {
+		if !matcher.Match(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// queryMatcher matches entries containing the queries in order, ignoring case if ignoreCase is true, see matchQuery.
+// With matchAny, it matches entries containing any of the queries instead, see matchAnyQuery.
+func queryMatcher(queries []string, ignoreCase, matchAny bool) Matcher {
+	match := matchQuery
+	if matchAny {
+		match = matchAnyQuery
+	}
+	return Matcher{
+		Name:  "query",
+		Cost:  costSubstring,
+		Match: func(entry *LogEntry) bool { return match(entry, queries, ignoreCase) },
+	}
+}
+
+// ExcludeMatcher matches entries whose message contains none of the excluded terms, ignoring case if ignoreCase is
+// true. It rejects an entry as soon as one of them is found. Empty terms, which every message contains, are ignored.
+func ExcludeMatcher(terms []string, ignoreCase bool) Matcher {
+	var excludes []string
+	for _, term := range terms {
+		if term != "" {
+			excludes = append(excludes, term)
+		}
+	}
+	return Matcher{
+		Name: "exclude",
+		Cost: costSubstring,
+		Match: func(entry *LogEntry) bool {
+			return len(excludes) == 0 || !matchAnyQuery(entry, excludes, ignoreCase)
+		},
+	}
+}
+
+// LevelMatcher matches entries with a log level of at least min.
+func LevelMatcher(min LogLevel) Matcher {
+	return Matcher{
+		Name:  "level",
+		Cost:  costField,
+		Match: func(entry *LogEntry) bool { return entry.LogLevel >= min },
+	}
+}
+
+// CompileQueryRegexes compiles each query term as a regular expression, ignoring case if ignoreCase is true.
+func CompileQueryRegexes(terms []string, ignoreCase bool) ([]*regexp.Regexp, error) {
+	regexes := make([]*regexp.Regexp, 0, len(terms))
+	for _, term := range terms {
+		pattern := term
+		if ignoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid query regex %q: %v", term, err)
+		}
+		regexes = append(regexes, re)
+	}
+	return regexes, nil
+}
+
+// RegexMatcher matches entries whose message matches every one of the regular expressions, or any of them with
+// matchAny.
+func RegexMatcher(regexes []*regexp.Regexp, matchAny bool) Matcher {
+	return Matcher{
+		Name: "regex",
+		Cost: costRegex,
+		Match: func(entry *LogEntry) bool {
+			for _, re := range regexes {
+				if re.MatchString(entry.Message) == matchAny {
+					return matchAny
+				}
+			}
+			return !matchAny
+		},
+	}
+}
+
+// ParseQueryTerms splits a comma-separated query into terms. A term wrapped in double quotes is taken literally, so it
+// may contain commas and leading or trailing spaces.
+func ParseQueryTerms(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var terms []string
+	var term strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			terms = append(terms, term.String())
+			term.Reset()
+		default:
+			term.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("Unterminated quote in query: %s", s)
+	}
+	return append(terms, term.String()), nil
+}
+
+// matchQuery returns true if the log entry matches the query. The message and the query terms are compared ignoring
+// case if ignoreCase is true.
+func matchQuery(entry *LogEntry, queries []string, ignoreCase bool) bool {
+	if len(queries) == 0 {
+		return true
+	}
+
+	textToSearch := entry.Message
+	if ignoreCase {
+		// lowercasing the whole message up front keeps the text remaining after each match lowercased too
+		textToSearch = strings.ToLower(textToSearch)
+	}
+
+	for _, query := range queries {
+		if ignoreCase {
+			query = strings.ToLower(query)
+		}
+		if strings.Contains(textToSearch, query) {
+			textToSearch = strings.SplitN(textToSearch, query, 2)[1]
+		} else {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAnyQuery returns true if the message of the log entry contains at least one of the query terms, each searched
+// independently. The message and the query terms are compared ignoring case if ignoreCase is true.
+func matchAnyQuery(entry *LogEntry, queries []string, ignoreCase bool) bool {
+	if len(queries) == 0 {
+		return true
+	}
+
+	textToSearch := entry.Message
+	if ignoreCase {
+		textToSearch = strings.ToLower(textToSearch)
+	}
+
+	for _, query := range queries {
+		if ignoreCase {
+			query = strings.ToLower(query)
+		}
+		if strings.Contains(textToSearch, query) {
+			return true
+		}
+	}
+	return false
+}
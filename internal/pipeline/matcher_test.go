@@ -0,0 +1,348 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestMatcherChain(t *testing.T) {
+	regexEvaluations := 0
+	regex := RegexMatcher([]*regexp.Regexp{regexp.MustCompile(`GC in \d{3,}ms`)}, false)
+	countingRegex := Matcher{
+		Name: regex.Name,
+		Cost: regex.Cost,
+		Match: func(entry *LogEntry) bool {
+			regexEvaluations++
+			return regex.Match(entry)
+		},
+	}
+
+	chain := NewMatcherChain(countingRegex, queryMatcher([]string{"G1"}, false, false), LevelMatcher(WARN))
+	if chain[0].Name != "level" || chain[1].Name != "query" || chain[2].Name != "regex" {
+		t.Fatalf("Expected the chain to be ordered level, query, regex, got %s, %s, %s", chain[0].Name, chain[1].Name, chain[2].Name)
+	}
+
+	testCases := []struct {
+		name     string
+		entry    *LogEntry
+		expected bool
+		regexRun bool
+	}{
+		{
+			name:     "filtered out by level",
+			entry:    &LogEntry{LogLevel: INFO, Message: "G1 Young Generation GC in 523ms"},
+			expected: false,
+			regexRun: false,
+		},
+		{
+			name:     "filtered out by query",
+			entry:    &LogEntry{LogLevel: WARN, Message: "ParNew GC in 523ms"},
+			expected: false,
+			regexRun: false,
+		},
+		{
+			name:     "filtered out by regex",
+			entry:    &LogEntry{LogLevel: WARN, Message: "G1 Young Generation GC in 52ms"},
+			expected: false,
+			regexRun: true,
+		},
+		{
+			name:     "all match",
+			entry:    &LogEntry{LogLevel: ERROR, Message: "G1 Old Generation GC in 5230ms"},
+			expected: true,
+			regexRun: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			regexEvaluations = 0
+			if got := chain.Match(tc.entry); got != tc.expected {
+				t.Errorf("Match() = %v, want %v", got, tc.expected)
+			}
+			if (regexEvaluations > 0) != tc.regexRun {
+				t.Errorf("Expected regex evaluated = %v, got %d evaluations", tc.regexRun, regexEvaluations)
+			}
+		})
+	}
+}
+
+func TestCompileQueryRegexes(t *testing.T) {
+	tests := []struct {
+		name       string
+		terms      []string
+		ignoreCase bool
+		message    string
+		want       bool
+	}{
+		{name: "literal and regex", terms: []string{"GCInspector", `GC in \d{4,}ms`}, message: "GCInspector.java:282 - G1 Young Generation GC in 1523ms", want: true},
+		{name: "regex below threshold", terms: []string{"GCInspector", `GC in \d{4,}ms`}, message: "GCInspector.java:282 - G1 Young Generation GC in 523ms", want: false},
+		{name: "missing literal", terms: []string{"CompactionTask", `GC in \d{4,}ms`}, message: "GCInspector.java:282 - G1 Young Generation GC in 1523ms", want: false},
+		{name: "any order", terms: []string{`\d+ms`, "G1"}, message: "G1 Young Generation GC in 1523ms", want: true},
+		{name: "ignore case", terms: []string{"gc in [0-9]+MS"}, ignoreCase: true, message: "G1 Young Generation GC in 1523ms", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regexes, err := CompileQueryRegexes(tt.terms, tt.ignoreCase)
+			if err != nil {
+				t.Fatalf("CompileQueryRegexes() error = %v", err)
+			}
+			if got := RegexMatcher(regexes, false).Match(&LogEntry{Message: tt.message}); got != tt.want {
+				t.Errorf("Expected match %v for %q, got %v", tt.want, tt.message, got)
+			}
+		})
+	}
+
+	if _, err := CompileQueryRegexes([]string{"GC", "GC in (\\d+ms"}, false); err == nil {
+		t.Errorf("Expected an error for an invalid pattern")
+	}
+}
+
+func TestExcludeMatcher(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "10.0.0.1"}
+	writeSystemLog(t, topLevelDir, node.Address,
+		"INFO  [GossipStage:1] 2023-07-14 16:00:00,000 Gossiper.java:10 - Node /10.0.0.2 is now UP\n"+
+			"WARN  [main] 2023-07-14 16:00:01,000 Server.java:20 - Read timeout\n"+
+			"WARN  [GossipStage:1] 2023-07-14 16:00:02,000 Gossiper.java:30 - Gossip timeout\n"+
+			"INFO  [main] 2023-07-14 16:00:03,000 Server.java:40 - Compaction done\n")
+
+	tests := []struct {
+		name       string
+		queries    []string
+		excludes   []string
+		ignoreCase bool
+		want       []int
+	}{
+		{name: "exclude only", excludes: []string{"Gossip"}, want: []int{2, 4}},
+		{name: "several excludes", excludes: []string{"Gossip", "Compaction"}, want: []int{2}},
+		{name: "include and exclude", queries: []string{"timeout"}, excludes: []string{"Gossip"}, want: []int{2}},
+		{name: "conflicting", queries: []string{"timeout"}, excludes: []string{"timeout"}, want: nil},
+		{name: "ignore case", excludes: []string{"gossip"}, ignoreCase: true, want: []int{2, 4}},
+		{name: "case-sensitive", excludes: []string{"gossip"}, want: []int{1, 2, 3, 4}},
+		{name: "empty term", excludes: []string{""}, want: []int{1, 2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := ScanOptions{IgnoreCase: tt.ignoreCase, Matchers: []Matcher{ExcludeMatcher(tt.excludes, tt.ignoreCase)}}
+			entries := CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, tt.queries, opts)[node.Address]
+			var got []int
+			for _, entry := range entries {
+				got = append(got, entry.LineNumber)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected lines %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func BenchmarkMatcherChain(b *testing.B) {
+	var entries LogEntries
+	for i := 0; i < 1000; i++ {
+		level := DEBUG
+		if i%100 == 0 {
+			level = WARN
+		}
+		entries = append(entries, &LogEntry{LogLevel: level, Message: fmt.Sprintf("G1 Young Generation GC in %dms", i)})
+	}
+	level := LevelMatcher(WARN)
+	regex := RegexMatcher([]*regexp.Regexp{regexp.MustCompile(`GC in \d{3,}ms`)}, false)
+
+	benchmarks := []struct {
+		name  string
+		chain MatcherChain
+	}{
+		{name: "cheapest first", chain: NewMatcherChain(regex, level)},
+		{name: "regex first", chain: MatcherChain{regex, level}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, entry := range entries {
+					bm.chain.Match(entry)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchQuery(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		entry    *LogEntry
+		queries  []string
+		expected bool
+	}{
+		{
+			name: "No Queries",
+			entry: &LogEntry{
+				Message: "No queries to match",
+			},
+			queries:  []string{},
+			expected: true,
+		},
+		{
+			name: "Single Match",
+			entry: &LogEntry{
+				Message: "This is a test message",
+			},
+			queries:  []string{"test"},
+			expected: true,
+		},
+		{
+			name: "Single Non-Match",
+			entry: &LogEntry{
+				Message: "This is a test message",
+			},
+			queries:  []string{"non-match"},
+			expected: false,
+		},
+		{
+			name: "Multiple Matches",
+			entry: &LogEntry{
+				Message: "This is a test message with multiple queries to match",
+			},
+			queries:  []string{"test", "message", "multiple", "queries"},
+			expected: true,
+		},
+		{
+			name: "Multiple Queries with Non-Match",
+			entry: &LogEntry{
+				Message: "This is a test message with multiple queries to match",
+			},
+			queries:  []string{"test", "non-match"},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := matchQuery(tc.entry, tc.queries, false)
+			if actual != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMatchAnyQuery(t *testing.T) {
+	entry := &LogEntry{Message: "WARN  [main] Read timeout: 2 replicas timed out"}
+	tests := []struct {
+		name       string
+		queries    []string
+		ignoreCase bool
+		wantAll    bool
+		wantAny    bool
+	}{
+		{name: "overlapping terms in order", queries: []string{"timeout", "out"}, wantAll: true, wantAny: true},
+		{name: "repeated term", queries: []string{"timeout", "timeout"}, wantAll: false, wantAny: true},
+		{name: "one term found", queries: []string{"exception", "timed out", "dropped"}, wantAll: false, wantAny: true},
+		{name: "out of order", queries: []string{"timed", "Read"}, wantAll: false, wantAny: true},
+		{name: "no term found", queries: []string{"exception", "dropped"}, wantAll: false, wantAny: false},
+		{name: "ignore case", queries: []string{"EXCEPTION", "TIMED"}, ignoreCase: true, wantAll: false, wantAny: true},
+		{name: "no queries", queries: nil, wantAll: true, wantAny: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchQuery(entry, tt.queries, tt.ignoreCase); got != tt.wantAll {
+				t.Errorf("matchQuery(%q) = %v, want %v", tt.queries, got, tt.wantAll)
+			}
+			if got := matchAnyQuery(entry, tt.queries, tt.ignoreCase); got != tt.wantAny {
+				t.Errorf("matchAnyQuery(%q) = %v, want %v", tt.queries, got, tt.wantAny)
+			}
+			if got := queryMatcher(tt.queries, tt.ignoreCase, true).Match(entry); got != tt.wantAny {
+				t.Errorf("queryMatcher(%q) with matchAny = %v, want %v", tt.queries, got, tt.wantAny)
+			}
+		})
+	}
+
+	regexes, err := CompileQueryRegexes([]string{`exception`, `\d+ replicas`}, false)
+	if err != nil {
+		t.Fatalf("CompileQueryRegexes() error = %v", err)
+	}
+	if !RegexMatcher(regexes, true).Match(entry) {
+		t.Errorf("Expected the regexes to match with matchAny when one of them matches")
+	}
+	if RegexMatcher(regexes, false).Match(entry) {
+		t.Errorf("Expected the regexes not to match without matchAny when one of them doesn't")
+	}
+}
+
+func TestMatchQueryIgnoreCase(t *testing.T) {
+	entry := &LogEntry{Message: "WARN  [Native-Transport-Requests-1] Read Timeout: 2 replicas TIMED OUT, timeout again"}
+	tests := []struct {
+		name       string
+		queries    []string
+		ignoreCase bool
+		want       bool
+	}{
+		{name: "lowercase query", queries: []string{"timeout"}, ignoreCase: true, want: true},
+		{name: "uppercase query", queries: []string{"READ TIMEOUT"}, ignoreCase: true, want: true},
+		{name: "terms in order", queries: []string{"Timeout", "timed out", "TIMEOUT"}, ignoreCase: true, want: true},
+		{name: "terms out of order", queries: []string{"timed out", "read"}, ignoreCase: true, want: false},
+		{name: "remaining text", queries: []string{"timeout", "timeout", "timeout"}, ignoreCase: true, want: false},
+		{name: "case-sensitive by default", queries: []string{"timed out"}, ignoreCase: false, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchQuery(entry, tt.queries, tt.ignoreCase); got != tt.want {
+				t.Errorf("matchQuery(%q) = %v, want %v", tt.queries, got, tt.want)
+			}
+		})
+	}
+
+	topLevelDir := t.TempDir()
+	node := Node{Address: "10.0.0.1", Datacenter: "DC1"}
+	writeSystemLog(t, topLevelDir, node.Address, "WARN  [main] 2023-07-14 16:00:00,000 Server.java:10 - Read TIMEOUT\n"+
+		"INFO  [main] 2023-07-14 16:00:01,000 Server.java:20 - Read timeout\n"+
+		"INFO  [main] 2023-07-14 16:00:02,000 Server.java:30 - Compacted\n")
+	entries := CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, []string{"Timeout"}, ScanOptions{IgnoreCase: true})[node.Address]
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 entries matching Timeout ignoring case, got %d", len(entries))
+	}
+}
+
+func TestParseQueryTerms(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", query: "", want: nil},
+		{name: "plain terms", query: "error,timeout", want: []string{"error", "timeout"}},
+		{name: "quoted comma", query: `"error, retrying",timeout`, want: []string{"error, retrying", "timeout"}},
+		{name: "quoted spaces", query: `" leading","trailing "`, want: []string{" leading", "trailing "}},
+		{name: "unquoted spaces", query: "out of memory", want: []string{"out of memory"}},
+		{name: "unterminated quote", query: `"error,timeout`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQueryTerms(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQueryTerms(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQueryTerms(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	queries, _ := ParseQueryTerms(`"error, retrying",timeout`)
+	entry := &LogEntry{Message: "Got error, retrying after timeout"}
+	if !matchQuery(entry, queries, false) {
+		t.Errorf("Expected %q to match %q", entry.Message, queries)
+	}
+}
@@ -0,0 +1,158 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github/kenjords/wetlog/pkg/wetlog"
+)
+
+// MetricExtractor extracts a named numeric value from a log message. The first capture group of Regex holds the value.
+type MetricExtractor struct {
+	Name  string
+	Regex *regexp.Regexp
+}
+
+// metricExtractors are the well-known Cassandra metric patterns selectable with -metrics-patterns.
+var metricExtractors = []MetricExtractor{
+	// e.g. "G1 Young Generation GC in 523ms." or "GC for ParNew: 245 ms for 1 collections"
+	{Name: "gc_pause_ms", Regex: gcPauseRegex},
+	// e.g. "Pending tasks: 12" or "pending tasks 12"
+	{Name: "pending_tasks", Regex: regexp.MustCompile(`(?i)pending tasks:?\s+([\d,]+)`)},
+	// e.g. "Compaction progress: 3 compactions remaining"
+	{Name: "compaction_remaining", Regex: regexp.MustCompile(`(?i)([\d,]+) (?:compactions? |tasks? )?remaining`)},
+	// e.g. "Read Throughput = 12.345MiB/s, Write Throughput = 11.000MiB/s"
+	{Name: "compaction_throughput_mibs", Regex: regexp.MustCompile(`Read Throughput = ([\d,.]+)\s?MiB/s`)},
+}
+
+// ParseMetricExtractors returns the extractors named in the comma-separated list names, or all of them for "all".
+func ParseMetricExtractors(names string) ([]MetricExtractor, error) {
+	if names == "" {
+		return nil, nil
+	}
+	if names == "all" {
+		return metricExtractors, nil
+	}
+
+	var extractors []MetricExtractor
+	for _, name := range strings.Split(names, ",") {
+		extractor, ok := FindMetricExtractor(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("Invalid metric pattern: %s", name)
+		}
+		extractors = append(extractors, extractor)
+	}
+	return extractors, nil
+}
+
+// FindMetricExtractor returns the well-known extractor with the given name.
+func FindMetricExtractor(name string) (MetricExtractor, bool) {
+	for _, extractor := range metricExtractors {
+		if extractor.Name == name {
+			return extractor, true
+		}
+	}
+	return MetricExtractor{}, false
+}
+
+// WithMetricExtractors returns extractors along with the extractors of the named metrics it lacks, so the metrics
+// filtered or sorted by are extracted whatever -metrics-patterns selects.
+func WithMetricExtractors(extractors []MetricExtractor, names ...string) []MetricExtractor {
+	for _, name := range names {
+		extractor, ok := FindMetricExtractor(name)
+		if !ok || hasMetricExtractor(extractors, name) {
+			continue
+		}
+		extractors = append(extractors[:len(extractors):len(extractors)], extractor)
+	}
+	return extractors
+}
+
+// hasMetricExtractor returns true if extractors hold the extractor of the named metric.
+func hasMetricExtractor(extractors []MetricExtractor, name string) bool {
+	for _, extractor := range extractors {
+		if extractor.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractMetrics attaches to the entry the value of every extractor that matches its message.
+func ExtractMetrics(entry *LogEntry, extractors []MetricExtractor) {
+	for _, extractor := range extractors {
+		match := extractor.Regex.FindStringSubmatch(entry.Message)
+		if match == nil {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.ReplaceAll(match[1], ",", ""), 64)
+		if err != nil {
+			continue
+		}
+		if entry.Metrics == nil {
+			entry.Metrics = make(map[string]float64)
+		}
+		entry.Metrics[extractor.Name] = value
+	}
+}
+
+// ParseMetricThreshold parses a "name=value" metric threshold.
+func ParseMetricThreshold(s string) (string, float64, error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", 0, fmt.Errorf("Invalid metric threshold: %s", s)
+	}
+	if _, ok := FindMetricExtractor(name); !ok {
+		return "", 0, fmt.Errorf("Invalid metric pattern: %s", name)
+	}
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("Invalid metric threshold: %s", s)
+	}
+	return name, threshold, nil
+}
+
+// filterByMetricMin keeps only the entries that carry the named metric with a value of at least min.
+func filterByMetricMin(entries LogEntries, name string, min float64) LogEntries {
+	var filtered LogEntries
+	for _, entry := range entries {
+		if value, ok := entry.Metrics[name]; ok && value >= min {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// ByMetric sorts LogEntries by the value of the named metric, then by date. Entries without the metric sort first.
+type ByMetric struct {
+	LogEntries
+	Name string
+}
+
+// Less returns true if the named metric of the LogEntry at index i is lower than that of the LogEntry at index j.
+func (s ByMetric) Less(i, j int) bool {
+	if c := CompareMetric(s.Name, s.LogEntries[i], s.LogEntries[j]); c != 0 {
+		return c < 0
+	}
+	return wetlog.DateLess(s.LogEntries[i], s.LogEntries[j])
+}
+
+// CompareMetric compares the named metric of a and b like a compareFunc, entries without the metric first.
+func CompareMetric(name string, a, b *LogEntry) int {
+	va, oka := a.Metrics[name]
+	vb, okb := b.Metrics[name]
+	switch {
+	case oka != okb && !oka:
+		return -1
+	case oka != okb:
+		return 1
+	case va < vb:
+		return -1
+	case va > vb:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,150 @@
+package pipeline
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestExtractMetrics(t *testing.T) {
+	testCases := []struct {
+		name    string
+		message string
+		want    map[string]float64
+	}{
+		{
+			name:    "G1 GC pause",
+			message: "INFO  [Service Thread] 2023-07-05 13:03:37,128  GCInspector.java:284 - G1 Young Generation GC in 523ms.  G1 Eden Space: 1234 -> 0",
+			want:    map[string]float64{"gc_pause_ms": 523},
+		},
+		{
+			name:    "ParNew GC pause",
+			message: "WARN  [Service Thread] 2023-07-05 13:03:37,128  GCInspector.java:282 - GC for ParNew: 1,245 ms for 1 collections, 5678 used; max is 9999",
+			want:    map[string]float64{"gc_pause_ms": 1245},
+		},
+		{
+			name:    "pending tasks",
+			message: "INFO  [CompactionExecutor:1] 2023-07-05 13:03:37,128  CompactionManager.java:100 - Pending tasks: 12",
+			want:    map[string]float64{"pending_tasks": 12},
+		},
+		{
+			name:    "compaction remaining and throughput",
+			message: "INFO  [CompactionExecutor:1] 2023-07-05 13:03:37,128  CompactionTask.java:241 - Compacted 4 sstables, 3 compactions remaining. Read Throughput = 12.5MiB/s, Write Throughput = 11.0MiB/s",
+			want:    map[string]float64{"compaction_remaining": 3, "compaction_throughput_mibs": 12.5},
+		},
+		{
+			name:    "no metrics",
+			message: "INFO  [main] 2023-07-05 13:03:37,128  StorageService.java:100 - Node is ready",
+			want:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			entry := &LogEntry{Message: tc.message}
+			ExtractMetrics(entry, metricExtractors)
+			if len(entry.Metrics) != len(tc.want) {
+				t.Fatalf("ExtractMetrics() = %v, want %v", entry.Metrics, tc.want)
+			}
+			for name, value := range tc.want {
+				if entry.Metrics[name] != value {
+					t.Errorf("Expected metric %s = %v, got %v", name, value, entry.Metrics[name])
+				}
+			}
+		})
+	}
+}
+
+func TestParseMetricExtractors(t *testing.T) {
+	extractors, err := ParseMetricExtractors("gc_pause_ms,pending_tasks")
+	if err != nil {
+		t.Fatalf("ParseMetricExtractors() error = %v", err)
+	}
+	if len(extractors) != 2 || extractors[0].Name != "gc_pause_ms" || extractors[1].Name != "pending_tasks" {
+		t.Errorf("ParseMetricExtractors() = %v, want gc_pause_ms and pending_tasks", extractors)
+	}
+
+	all, err := ParseMetricExtractors("all")
+	if err != nil || len(all) != len(metricExtractors) {
+		t.Errorf("Expected all extractors, got %v (error %v)", all, err)
+	}
+
+	if _, err := ParseMetricExtractors("heap_used"); err == nil {
+		t.Errorf("Expected an error for an unknown metric pattern")
+	}
+}
+
+func TestFilterAndSortByMetric(t *testing.T) {
+	entries := LogEntries{
+		{Message: "G1 Young Generation GC in 900ms."},
+		{Message: "Node is ready"},
+		{Message: "G1 Young Generation GC in 150ms."},
+		{Message: "G1 Old Generation GC in 2500ms."},
+	}
+	for _, entry := range entries {
+		ExtractMetrics(entry, metricExtractors)
+	}
+
+	name, min, err := ParseMetricThreshold("gc_pause_ms=500")
+	if err != nil {
+		t.Fatalf("ParseMetricThreshold() error = %v", err)
+	}
+	filtered := filterByMetricMin(entries, name, min)
+	if len(filtered) != 2 {
+		t.Fatalf("Expected 2 entries with gc_pause_ms >= 500, got %d", len(filtered))
+	}
+
+	sort.Sort(ByMetric{entries, "gc_pause_ms"})
+	want := []string{"Node is ready", "G1 Young Generation GC in 150ms.", "G1 Young Generation GC in 900ms.", "G1 Old Generation GC in 2500ms."}
+	for i, entry := range entries {
+		if entry.Message != want[i] {
+			t.Errorf("Expected %q at index %d, got %q", want[i], i, entry.Message)
+		}
+	}
+}
+
+func newGCEntries() LogEntries {
+	return LogEntries{
+		{LineNumber: 1, Message: "G1 Young Generation GC in 900ms."},
+		{LineNumber: 2, Message: "Node is ready"},
+		{LineNumber: 3, Message: "G1 Young Generation GC in 150ms."},
+	}
+}
+
+func TestMetricMinWithoutPatterns(t *testing.T) {
+	// -metric-min gc_pause_ms=500 without -metrics-patterns
+	extractors := WithMetricExtractors(nil, "gc_pause_ms")
+	filters := EntryFilters{Extractors: extractors, MetricMinName: "gc_pause_ms", MetricMinValue: 500}
+
+	kept := filters.Apply(newGCEntries())
+	if len(kept) != 1 || kept[0].LineNumber != 1 {
+		t.Errorf("Expected only the 900ms pause to be kept, got %v", kept)
+	}
+}
+
+func TestSortByMetricWithoutPatterns(t *testing.T) {
+	// -sort metric:gc_pause_ms without -metrics-patterns
+	entries := EntryFilters{Extractors: WithMetricExtractors(nil, "gc_pause_ms")}.Apply(newGCEntries())
+	sort.Stable(ByMetric{entries, "gc_pause_ms"})
+
+	var got []int
+	for _, entry := range entries {
+		got = append(got, entry.LineNumber)
+	}
+	if want := []int{2, 3, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected lines %v, got %v", want, got)
+	}
+}
+
+func TestWithMetricExtractors(t *testing.T) {
+	pending, _ := FindMetricExtractor("pending_tasks")
+	extractors := WithMetricExtractors([]MetricExtractor{pending}, "gc_pause_ms", "pending_tasks", "gc_pause_ms")
+	if len(extractors) != 2 || extractors[0].Name != "pending_tasks" || extractors[1].Name != "gc_pause_ms" {
+		t.Errorf("Expected the pending_tasks and gc_pause_ms extractors once each, got %v", extractors)
+	}
+
+	// the extractors of all patterns are left untouched
+	if all := WithMetricExtractors(metricExtractors, "gc_pause_ms"); len(all) != len(metricExtractors) {
+		t.Errorf("Expected %d extractors, got %d", len(metricExtractors), len(all))
+	}
+}
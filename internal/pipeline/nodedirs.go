@@ -0,0 +1,90 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// normalizeNodeName lowercases a node address or directory name and turns the separators bundles use to sanitize
+// addresses, such as "10_0_0_1" or "2001-db8--1", into dots.
+func normalizeNodeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', ':':
+			return '.'
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// containsToken returns true if token appears in s without a letter or digit right before or after it, so that
+// "10.0.0.1" is found in "node.10.0.0.1" but not in "10.0.0.12".
+func containsToken(s, token string) bool {
+	isAlnum := func(b byte) bool { return b < 0x80 && (unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b))) }
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], token)
+		if i < 0 {
+			return false
+		}
+		start := offset + i
+		end := start + len(token)
+		if (start == 0 || !isAlnum(s[start-1])) && (end == len(s) || !isAlnum(s[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+// nodeDirMatches returns true if the directory name dir looks like it holds the logs of the node at address: the
+// address appears in it, sanitized or not, or it is the first label of the address as a hostname.
+func nodeDirMatches(address, dir string) bool {
+	address, dir = normalizeNodeName(address), normalizeNodeName(dir)
+	return containsToken(dir, address) || strings.HasPrefix(address, dir+".")
+}
+
+// ReconcileNodeDirs lists the nodes directory under topLevelDir and returns, for each node without a directory named
+// after its address, the single other directory matching it, see nodeDirMatches. Nodes matching several directories
+// are left out and returned with their candidates in ambiguous.
+func ReconcileNodeDirs(topLevelDir string, nodes []Node) (dirs map[string]string, ambiguous map[string][]string, err error) {
+	dirEntries, err := os.ReadDir(filepath.Join(topLevelDir, "nodes"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exact := make(map[string]bool)
+	for _, node := range nodes {
+		exact[node.Address] = true
+	}
+	var candidates []string
+	for _, entry := range dirEntries {
+		if entry.IsDir() && !exact[entry.Name()] {
+			candidates = append(candidates, entry.Name())
+		}
+	}
+	sort.Strings(candidates)
+
+	dirs = make(map[string]string)
+	ambiguous = make(map[string][]string)
+	for _, node := range nodes {
+		if _, err := os.Stat(filepath.Join(topLevelDir, "nodes", node.Address)); err == nil {
+			continue
+		}
+		var matches []string
+		for _, dir := range candidates {
+			if nodeDirMatches(node.Address, dir) {
+				matches = append(matches, dir)
+			}
+		}
+		switch len(matches) {
+		case 0:
+		case 1:
+			dirs[node.Address] = matches[0]
+		default:
+			ambiguous[node.Address] = matches
+		}
+	}
+	return dirs, ambiguous, nil
+}
@@ -0,0 +1,48 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReconcileNodeDirs(t *testing.T) {
+	topLevelDir := t.TempDir()
+	for _, dir := range []string{"10.0.0.1", "node-10_0_0_2", "10.0.0.20", "cass3", "cass4-a", "cass4-b", "2001-db8--5"} {
+		writeSystemLog(t, topLevelDir, dir, "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting on "+dir+"\n")
+	}
+	nodes := []Node{
+		{Address: "10.0.0.1"},          // exact directory
+		{Address: "10.0.0.2"},          // sanitized IP, not confused with 10.0.0.20
+		{Address: "cass3.example.com"}, // hostname prefix
+		{Address: "cass4"},             // two candidates
+		{Address: "2001:db8::5"},       // sanitized IPv6
+		{Address: "10.0.0.9"},          // no directory
+	}
+
+	dirs, ambiguous, err := ReconcileNodeDirs(topLevelDir, nodes)
+	if err != nil {
+		t.Fatalf("ReconcileNodeDirs() error = %v", err)
+	}
+	wantDirs := map[string]string{"10.0.0.2": "node-10_0_0_2", "cass3.example.com": "cass3", "2001:db8::5": "2001-db8--5"}
+	if !reflect.DeepEqual(dirs, wantDirs) {
+		t.Errorf("Expected directories %v, got %v", wantDirs, dirs)
+	}
+	wantAmbiguous := map[string][]string{"cass4": {"cass4-a", "cass4-b"}}
+	if !reflect.DeepEqual(ambiguous, wantAmbiguous) {
+		t.Errorf("Expected ambiguous matches %v, got %v", wantAmbiguous, ambiguous)
+	}
+
+	entries := CollectNodeEntries(context.Background(), nodes[1:3], topLevelDir, nil, ScanOptions{NodeDirs: dirs})
+	for _, node := range nodes[1:3] {
+		if len(entries[node.Address]) != 1 {
+			t.Errorf("Expected 1 entry for node %s read from its reconciled directory, got %d", node.Address, len(entries[node.Address]))
+		}
+	}
+
+	if _, _, err := ReconcileNodeDirs(filepath.Join(topLevelDir, "missing"), nodes); !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error without a nodes directory, got %v", err)
+	}
+}
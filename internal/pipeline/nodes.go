@@ -0,0 +1,248 @@
+package pipeline
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ValidateNodes sanity-checks the nodes parsed from nodetool status and returns every problem found: duplicate
+// addresses, nodes without a datacenter, and no node being up.
+func ValidateNodes(nodes []Node) []error {
+	var problems []error
+	seen := make(map[string]struct{}, len(nodes))
+	anyUp := false
+
+	for _, node := range nodes {
+		if _, ok := seen[node.Address]; ok {
+			problems = append(problems, fmt.Errorf("Duplicate node address: %s", node.Address))
+		}
+		seen[node.Address] = struct{}{}
+
+		if node.Datacenter == "" {
+			problems = append(problems, fmt.Errorf("Node %s has no datacenter", node.Address))
+		}
+
+		if node.IsUp() {
+			anyUp = true
+		}
+	}
+
+	if !anyUp {
+		problems = append(problems, fmt.Errorf("No node is up"))
+	}
+	return problems
+}
+
+// Datacenters returns the datacenters of the nodes, sorted and without duplicates.
+func Datacenters(nodes []Node) []string {
+	dcSet := make(map[string]struct{})
+	for _, node := range nodes {
+		dcSet[node.Datacenter] = struct{}{}
+	}
+
+	dcNames := make([]string, 0, len(dcSet))
+	for dc := range dcSet {
+		dcNames = append(dcNames, dc)
+	}
+	sort.Strings(dcNames)
+	return dcNames
+}
+
+// IdentifierKey returns the form of an identifier such as a datacenter or a node address used to compare it, lowercased
+// when ignoreCase is true.
+func IdentifierKey(s string, ignoreCase bool) string {
+	if ignoreCase {
+		return strings.ToLower(s)
+	}
+	return s
+}
+
+// NodeSelection holds the flags selecting the nodes whose logs are processed.
+type NodeSelection struct {
+	Datacenters string // Datacenters is the -datacenters list, empty for every datacenter.
+	Racks       string // Racks is the -racks list, empty for every rack.
+	Statuses    string // Statuses is the -status list, empty for every status.
+	LimitDCs    int    // LimitDCs is -limit-dcs, 0 for no limit.
+	NodesFrom   string // NodesFrom is the -nodes-from file, empty for every node.
+	OnlyUp      bool   // OnlyUp is -only-up.
+	OnlyDown    bool   // OnlyDown is -only-down.
+	IgnoreCase  bool   // IgnoreCase is -ignore-case-dc-and-node.
+}
+
+// SelectNodes returns the nodes sel selects, logging the addresses of the -nodes-from file that aren't among them.
+func SelectNodes(nodes []Node, sel NodeSelection) ([]Node, error) {
+	selected := nodes
+	if sel.Datacenters != "" {
+		selected = filterNodesByDatacenters(selected, strings.Split(sel.Datacenters, ","), sel.IgnoreCase)
+	}
+	if sel.Racks != "" {
+		selected = filterNodesByRacks(selected, strings.Split(sel.Racks, ","), sel.IgnoreCase)
+	}
+	if sel.Statuses != "" {
+		selected = filterNodesByStatus(selected, strings.Split(sel.Statuses, ","))
+	}
+	if sel.LimitDCs > 0 {
+		selected = limitDatacenters(selected, sel.LimitDCs)
+	}
+	if sel.NodesFrom != "" {
+		addresses, err := loadNodeList(sel.NodesFrom)
+		if err != nil {
+			return nil, fmt.Errorf("Error while reading nodes from %s: %v", sel.NodesFrom, err)
+		}
+		var missing []string
+		selected, missing = filterNodesByAddresses(selected, addresses, sel.IgnoreCase)
+		for _, address := range missing {
+			log.Printf("Node %s from %s was not found in the selected nodes", address, sel.NodesFrom)
+		}
+	}
+	if sel.OnlyUp || sel.OnlyDown {
+		selected = filterNodesByUp(selected, sel.OnlyUp)
+	}
+	return selected, nil
+}
+
+// filterNodesByDatacenters filters nodes by datacenters, ignoring case if ignoreCase is true.
+func filterNodesByDatacenters(nodes []Node, datacenters []string, ignoreCase bool) []Node {
+	var filteredNodes []Node
+	dcSet := make(map[string]struct{})
+
+	for _, dc := range datacenters {
+		dcSet[IdentifierKey(dc, ignoreCase)] = struct{}{}
+	}
+
+	for _, node := range nodes {
+		if _, ok := dcSet[IdentifierKey(node.Datacenter, ignoreCase)]; ok {
+			filteredNodes = append(filteredNodes, node)
+		}
+	}
+
+	return filteredNodes
+}
+
+// filterNodesByRacks filters nodes by racks, ignoring case if ignoreCase is true. Racks are matched by name whatever
+// their datacenter, e.g. rack1 selects the rack1 of every datacenter.
+func filterNodesByRacks(nodes []Node, racks []string, ignoreCase bool) []Node {
+	var filteredNodes []Node
+	rackSet := make(map[string]struct{})
+
+	for _, rack := range racks {
+		rackSet[IdentifierKey(rack, ignoreCase)] = struct{}{}
+	}
+
+	for _, node := range nodes {
+		if _, ok := rackSet[IdentifierKey(node.Rack, ignoreCase)]; ok {
+			filteredNodes = append(filteredNodes, node)
+		}
+	}
+
+	return filteredNodes
+}
+
+// loadNodeList reads node addresses from a file, one per line, ignoring blank lines and lines starting with #.
+func loadNodeList(path string) ([]string, error) {
+	file, err := os.Open(path) //nosec G304
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = file.Close()
+	}()
+
+	var addresses []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addresses = append(addresses, line)
+	}
+	return addresses, scanner.Err()
+}
+
+// filterNodesByAddresses keeps the nodes whose address is in addresses and returns the addresses matching no node.
+// Addresses are compared ignoring case if ignoreCase is true.
+func filterNodesByAddresses(nodes []Node, addresses []string, ignoreCase bool) ([]Node, []string) {
+	addrSet := make(map[string]struct{}, len(addresses))
+	for _, address := range addresses {
+		addrSet[IdentifierKey(address, ignoreCase)] = struct{}{}
+	}
+
+	var filteredNodes []Node
+	found := make(map[string]struct{})
+	for _, node := range nodes {
+		key := IdentifierKey(node.Address, ignoreCase)
+		if _, ok := addrSet[key]; ok {
+			filteredNodes = append(filteredNodes, node)
+			found[key] = struct{}{}
+		}
+	}
+
+	var missing []string
+	for _, address := range addresses {
+		if _, ok := found[IdentifierKey(address, ignoreCase)]; !ok {
+			missing = append(missing, address)
+		}
+	}
+	return filteredNodes, missing
+}
+
+// ParseNodePaths parses comma-separated address=path pairs into a map of node address to log file path.
+func ParseNodePaths(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	nodePaths := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		address, path, ok := strings.Cut(pair, "=")
+		if !ok || address == "" || path == "" {
+			return nil, fmt.Errorf("Invalid node path: %s", pair)
+		}
+		nodePaths[address] = path
+	}
+	return nodePaths, nil
+}
+
+// filterNodesByStatus filters nodes by their status in nodetool status, e.g. UN.
+func filterNodesByStatus(nodes []Node, statuses []string) []Node {
+	var filteredNodes []Node
+	statusSet := make(map[string]struct{})
+
+	for _, status := range statuses {
+		statusSet[status] = struct{}{}
+	}
+
+	for _, node := range nodes {
+		if _, ok := statusSet[node.Status]; ok {
+			filteredNodes = append(filteredNodes, node)
+		}
+	}
+
+	return filteredNodes
+}
+
+// filterNodesByUp keeps only the nodes that are up if up is true, or only the nodes that are down otherwise.
+func filterNodesByUp(nodes []Node, up bool) []Node {
+	var filteredNodes []Node
+	for _, node := range nodes {
+		if node.IsUp() == up {
+			filteredNodes = append(filteredNodes, node)
+		}
+	}
+	return filteredNodes
+}
+
+// limitDatacenters keeps only the nodes of the first n datacenters, in sorted order.
+func limitDatacenters(nodes []Node, n int) []Node {
+	dcNames := Datacenters(nodes)
+
+	if n < len(dcNames) {
+		dcNames = dcNames[:n]
+	}
+	return filterNodesByDatacenters(nodes, dcNames, false)
+}
@@ -0,0 +1,346 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDatacenters(t *testing.T) {
+	nodes := []Node{
+		{Address: "192.168.1.1", Datacenter: "DC2"},
+		{Address: "192.168.1.2", Datacenter: "DC10"},
+		{Address: "192.168.1.3", Datacenter: "DC1"},
+		{Address: "192.168.1.4", Datacenter: "DC2"},
+		{Address: "192.168.1.5", Datacenter: "DC1"},
+	}
+
+	want := []string{"DC1", "DC10", "DC2"}
+	if got := Datacenters(nodes); !reflect.DeepEqual(got, want) {
+		t.Errorf("Datacenters() = %v, want %v", got, want)
+	}
+	if got := Datacenters(nil); len(got) != 0 {
+		t.Errorf("Expected no datacenters without nodes, got %v", got)
+	}
+}
+
+func TestFilterNodesByDatacenters(t *testing.T) {
+	// Define test nodes and datacenters
+	nodes := []Node{
+		{Address: "192.168.1.1", Datacenter: "dc1"},
+		{Address: "192.168.1.2", Datacenter: "dc1"},
+		{Address: "192.168.1.3", Datacenter: "dc2"},
+		{Address: "192.168.1.4", Datacenter: "dc3"},
+		{Address: "192.168.1.5", Datacenter: "dc4"},
+	}
+	datacenters := []string{"dc1", "dc3"}
+
+	// Run the filter function
+	result := filterNodesByDatacenters(nodes, datacenters, false)
+
+	// Expected result
+	expected := []Node{
+		{Address: "192.168.1.1", Datacenter: "dc1"},
+		{Address: "192.168.1.2", Datacenter: "dc1"},
+		{Address: "192.168.1.4", Datacenter: "dc3"},
+	}
+
+	// Check if result matches expected
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("filterNodesByDatacenters() = %v, want %v", result, expected)
+	}
+}
+
+func TestFilterNodesByRacks(t *testing.T) {
+	nodes := []Node{
+		{Address: "10.0.0.1", Datacenter: "DC1", Rack: "rack1"},
+		{Address: "10.0.0.2", Datacenter: "DC1", Rack: "rack2"},
+		{Address: "10.0.0.3", Datacenter: "DC1", Rack: "rack3"},
+		{Address: "10.0.1.1", Datacenter: "DC2"},
+		{Address: "10.0.2.1", Datacenter: "DC3", Rack: "rack1"},
+	}
+
+	filtered := filterNodesByRacks(nodes, []string{"RACK1", "rack3"}, true)
+	var got []string
+	for _, node := range filtered {
+		got = append(got, node.Address)
+	}
+	if want := []string{"10.0.0.1", "10.0.0.3", "10.0.2.1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("filterNodesByRacks() = %v, want %v", got, want)
+	}
+	if filtered := filterNodesByRacks(nodes, []string{"RACK1"}, false); len(filtered) != 0 {
+		t.Errorf("Expected no node in RACK1 when matching case, got %v", filtered)
+	}
+}
+
+func TestFilterNodesByStatus(t *testing.T) {
+	nodes := []Node{
+		{Address: "192.168.1.1", Datacenter: "dc1", Status: "UN"},
+		{Address: "192.168.1.2", Datacenter: "dc1", Status: "DN"},
+		{Address: "192.168.1.3", Datacenter: "dc2", Status: "UM"},
+		{Address: "192.168.1.4", Datacenter: "dc2", Status: "UJ"},
+	}
+
+	result := filterNodesByStatus(nodes, []string{"UN", "UM"})
+
+	expected := []Node{
+		{Address: "192.168.1.1", Datacenter: "dc1", Status: "UN"},
+		{Address: "192.168.1.3", Datacenter: "dc2", Status: "UM"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("filterNodesByStatus() = %v, want %v", result, expected)
+	}
+}
+
+func TestLimitDatacenters(t *testing.T) {
+	nodes := []Node{
+		{Address: "192.168.1.1", Datacenter: "dc3"},
+		{Address: "192.168.1.2", Datacenter: "dc1"},
+		{Address: "192.168.1.3", Datacenter: "dc2"},
+		{Address: "192.168.1.4", Datacenter: "dc1"},
+	}
+
+	result := limitDatacenters(nodes, 2)
+	expected := []Node{
+		{Address: "192.168.1.2", Datacenter: "dc1"},
+		{Address: "192.168.1.3", Datacenter: "dc2"},
+		{Address: "192.168.1.4", Datacenter: "dc1"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("limitDatacenters() = %v, want %v", result, expected)
+	}
+
+	if result := limitDatacenters(nodes, 10); !reflect.DeepEqual(result, nodes) {
+		t.Errorf("limitDatacenters() with a limit above the number of datacenters = %v, want %v", result, nodes)
+	}
+}
+
+func TestParseNodePaths(t *testing.T) {
+	got, err := ParseNodePaths("10.0.0.5=/custom/path/system.log,10.0.0.6=/other/system.log")
+	if err != nil {
+		t.Fatalf("ParseNodePaths() error = %v", err)
+	}
+	want := map[string]string{"10.0.0.5": "/custom/path/system.log", "10.0.0.6": "/other/system.log"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseNodePaths() = %v, want %v", got, want)
+	}
+
+	if _, err := ParseNodePaths("10.0.0.5"); err == nil {
+		t.Errorf("Expected an error for a pair without a path")
+	}
+}
+
+func TestValidateNodes(t *testing.T) {
+	testCases := []struct {
+		name         string
+		nodes        []Node
+		wantProblems []string
+	}{
+		{
+			name: "valid nodes",
+			nodes: []Node{
+				{Address: "10.0.0.1", Datacenter: "DC1", Status: "UN"},
+				{Address: "10.0.0.2", Datacenter: "DC1", Status: "DN"},
+			},
+			wantProblems: nil,
+		},
+		{
+			name: "duplicate address",
+			nodes: []Node{
+				{Address: "10.0.0.1", Datacenter: "DC1", Status: "UN"},
+				{Address: "10.0.0.1", Datacenter: "DC2", Status: "UN"},
+			},
+			wantProblems: []string{"Duplicate node address: 10.0.0.1"},
+		},
+		{
+			name: "missing datacenter",
+			nodes: []Node{
+				{Address: "10.0.0.1", Datacenter: "DC1", Status: "UN"},
+				{Address: "10.0.0.2", Status: "UN"},
+			},
+			wantProblems: []string{"Node 10.0.0.2 has no datacenter"},
+		},
+		{
+			name: "no node up",
+			nodes: []Node{
+				{Address: "10.0.0.1", Datacenter: "DC1", Status: "DN"},
+				{Address: "10.0.0.2", Datacenter: "DC1", Status: "DL"},
+			},
+			wantProblems: []string{"No node is up"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, problem := range ValidateNodes(tc.nodes) {
+				got = append(got, problem.Error())
+			}
+			if !reflect.DeepEqual(got, tc.wantProblems) {
+				t.Errorf("ValidateNodes() = %v, want %v", got, tc.wantProblems)
+			}
+		})
+	}
+}
+
+func TestFilterNodesByAddressesFromFile(t *testing.T) {
+	nodeList := filepath.Join(t.TempDir(), "nodes.txt")
+	if err := os.WriteFile(nodeList, []byte("# nodes from the alert\n192.168.1.3\n\n192.168.1.1\n10.9.9.9\n"), 0o644); err != nil {
+		t.Fatalf("Couldn't write to file: %v", err)
+	}
+
+	addresses, err := loadNodeList(nodeList)
+	if err != nil {
+		t.Fatalf("loadNodeList() error = %v", err)
+	}
+	if !reflect.DeepEqual(addresses, []string{"192.168.1.3", "192.168.1.1", "10.9.9.9"}) {
+		t.Errorf("loadNodeList() = %v", addresses)
+	}
+
+	nodes := []Node{
+		{Address: "192.168.1.1", Datacenter: "dc1"},
+		{Address: "192.168.1.2", Datacenter: "dc1"},
+		{Address: "192.168.1.3", Datacenter: "dc2"},
+	}
+	filtered, missing := filterNodesByAddresses(nodes, addresses, false)
+
+	expected := []Node{
+		{Address: "192.168.1.1", Datacenter: "dc1"},
+		{Address: "192.168.1.3", Datacenter: "dc2"},
+	}
+	if !reflect.DeepEqual(filtered, expected) {
+		t.Errorf("filterNodesByAddresses() = %v, want %v", filtered, expected)
+	}
+	if !reflect.DeepEqual(missing, []string{"10.9.9.9"}) {
+		t.Errorf("Expected 10.9.9.9 to be reported missing, got %v", missing)
+	}
+}
+
+func TestFilterNodesByUp(t *testing.T) {
+	topLevelDir := t.TempDir()
+	nodes := []Node{
+		{Address: "10.0.0.1", Datacenter: "DC1", Status: "UN"},
+		{Address: "10.0.0.2", Datacenter: "DC1", Status: "DN"},
+		{Address: "10.0.0.3", Datacenter: "DC2", Status: "UJ"},
+		{Address: "10.0.0.4", Datacenter: "DC2", Status: "DL"},
+	}
+	for _, node := range nodes {
+		writeSystemLog(t, topLevelDir, node.Address, "WARN  [main] 2023-07-14 16:00:00,000 Server.java:10 - Slow\n")
+	}
+
+	tests := []struct {
+		name string
+		up   bool
+		want []string
+	}{
+		{name: "only up", up: true, want: []string{"10.0.0.1", "10.0.0.3"}},
+		{name: "only down", up: false, want: []string{"10.0.0.2", "10.0.0.4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeEntries := CollectNodeEntries(context.Background(), filterNodesByUp(nodes, tt.up), topLevelDir, nil, ScanOptions{})
+			var got []string
+			for address, entries := range nodeEntries {
+				if len(entries) > 0 {
+					got = append(got, address)
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected entries from %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIgnoreCaseDCAndNode(t *testing.T) {
+	nodes := []Node{
+		{Address: "cass-Node-1.example.com", Datacenter: "DC1"},
+		{Address: "cass-node-2.example.com", Datacenter: "dc2"},
+		{Address: "2001:DB8::3", Datacenter: "Dc3"},
+	}
+
+	tests := []struct {
+		name       string
+		ignoreCase bool
+		dcs        []string
+		addresses  []string
+		want       []string
+	}{
+		{name: "datacenters", ignoreCase: true, dcs: []string{"dc1", "DC2"}, want: []string{"cass-Node-1.example.com", "cass-node-2.example.com"}},
+		{name: "datacenters case-sensitive", ignoreCase: false, dcs: []string{"dc1", "DC2"}, want: nil},
+		{name: "addresses", ignoreCase: true, addresses: []string{"CASS-NODE-1.example.com", "2001:db8::3"}, want: []string{"cass-Node-1.example.com", "2001:DB8::3"}},
+		{name: "addresses case-sensitive", ignoreCase: false, addresses: []string{"CASS-NODE-1.example.com", "2001:db8::3"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filtered []Node
+			if tt.dcs != nil {
+				filtered = filterNodesByDatacenters(nodes, tt.dcs, tt.ignoreCase)
+			} else {
+				var missing []string
+				filtered, missing = filterNodesByAddresses(nodes, tt.addresses, tt.ignoreCase)
+				if tt.ignoreCase && len(missing) != 0 {
+					t.Errorf("Expected every address to be found, missing %v", missing)
+				}
+			}
+			var got []string
+			for _, node := range filtered {
+				got = append(got, node.Address)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected nodes %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSelectNodes(t *testing.T) {
+	nodes := []Node{
+		{Address: "10.0.0.1", Datacenter: "DC1", Rack: "rack1", Status: "UN"},
+		{Address: "10.0.0.2", Datacenter: "DC1", Rack: "rack2", Status: "DN"},
+		{Address: "10.0.0.3", Datacenter: "DC2", Rack: "rack1", Status: "UN"},
+		{Address: "10.0.0.4", Datacenter: "DC2", Rack: "rack1", Status: "UN"},
+	}
+	nodesFrom := filepath.Join(t.TempDir(), "nodes.txt")
+	if err := os.WriteFile(nodesFrom, []byte("10.0.0.1\n10.0.0.3\n10.0.0.9\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		sel  NodeSelection
+		want []string
+	}{
+		{name: "all", sel: NodeSelection{}, want: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}},
+		{name: "datacenter and rack", sel: NodeSelection{Datacenters: "dc1", Racks: "RACK1", IgnoreCase: true}, want: []string{"10.0.0.1"}},
+		{name: "status and limit", sel: NodeSelection{Statuses: "UN", LimitDCs: 1}, want: []string{"10.0.0.1"}},
+		{name: "nodes from and up", sel: NodeSelection{NodesFrom: nodesFrom, OnlyUp: true}, want: []string{"10.0.0.1", "10.0.0.3"}},
+		{name: "only down", sel: NodeSelection{OnlyDown: true}, want: []string{"10.0.0.2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := SelectNodes(nodes, tt.sel)
+			if err != nil {
+				t.Fatalf("SelectNodes() error = %v", err)
+			}
+			var got []string
+			for _, node := range selected {
+				got = append(got, node.Address)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected nodes %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := SelectNodes(nodes, NodeSelection{NodesFrom: filepath.Join(t.TempDir(), "missing.txt")}); err == nil {
+		t.Error("Expected an error for a missing -nodes-from file")
+	}
+}
@@ -0,0 +1,45 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// DefaultPathTemplate is the layout of the log files in diagnostic bundles, the default of -path-template.
+const DefaultPathTemplate = "{{.TopLevelDir}}/nodes/{{.Address}}/logs/cassandra/{{.File}}"
+
+// defaultPathTmpl is DefaultPathTemplate parsed, used when ScanOptions.PathTemplate is nil.
+var defaultPathTmpl = template.Must(template.New("path").Parse(DefaultPathTemplate))
+
+// logPathFields are the fields a path template can use to build the path of a log file of a node.
+type logPathFields struct {
+	TopLevelDir string // TopLevelDir is the top-level directory given on the command line.
+	Address     string // Address is the address of the node, or the name of its directory, see ReconcileNodeDirs.
+	File        string // File is the name of the log file, e.g. system.log, see ScanOptions.LogFiles.
+}
+
+// ParsePathTemplate parses a -path-template value, e.g. "{{.TopLevelDir}}/{{.Address}}/cassandra/logs/{{.File}}".
+// The template is executed once with sample fields so that unknown fields are reported before scanning.
+func ParsePathTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("path").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid path template: %v", err)
+	}
+	sample := logPathFields{TopLevelDir: ".", Address: "127.0.0.1", File: DefaultLogFile}
+	if err := tmpl.Execute(io.Discard, sample); err != nil {
+		return nil, fmt.Errorf("Invalid path template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// executePathTemplate returns the path tmpl builds from fields, with the separators of the platform.
+func executePathTemplate(tmpl *template.Template, fields logPathFields) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, fields); err != nil {
+		return "", err
+	}
+	return filepath.Clean(filepath.FromSlash(b.String())), nil
+}
@@ -0,0 +1,92 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNodeLogFilesPathTemplate(t *testing.T) {
+	node := Node{Address: "10.0.0.1"}
+	custom, err := ParsePathTemplate("{{.TopLevelDir}}/{{.Address}}/cassandra/logs/{{.File}}")
+	if err != nil {
+		t.Fatalf("ParsePathTemplate() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts ScanOptions
+		want []string
+	}{
+		{
+			name: "default",
+			opts: ScanOptions{},
+			want: []string{filepath.Join("bundle", "nodes", "10.0.0.1", "logs", "cassandra", "system.log")},
+		},
+		{
+			name: "default with node directory",
+			opts: ScanOptions{NodeDirs: map[string]string{"10.0.0.1": "node1"}},
+			want: []string{filepath.Join("bundle", "nodes", "node1", "logs", "cassandra", "system.log")},
+		},
+		{
+			name: "custom",
+			opts: ScanOptions{PathTemplate: custom, LogFiles: []string{"system.log", "debug.log"}},
+			want: []string{
+				filepath.Join("bundle", "10.0.0.1", "cassandra", "logs", "system.log"),
+				filepath.Join("bundle", "10.0.0.1", "cassandra", "logs", "debug.log"),
+			},
+		},
+		{
+			name: "node path takes precedence",
+			opts: ScanOptions{PathTemplate: custom, NodePaths: map[string]string{"10.0.0.1": "/tmp/system.log"}},
+			want: []string{"/tmp/system.log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NodeLogFiles(node, "bundle", tt.opts)
+			if err != nil {
+				t.Fatalf("NodeLogFiles() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NodeLogFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePathTemplate(t *testing.T) {
+	if _, err := ParsePathTemplate(DefaultPathTemplate); err != nil {
+		t.Errorf("ParsePathTemplate(%q) error = %v", DefaultPathTemplate, err)
+	}
+	for _, text := range []string{"{{.TopLevelDir}/{{.File}}", "{{.TopLevelDir}}/{{.Host}}/{{.File}}"} {
+		if _, err := ParsePathTemplate(text); err == nil {
+			t.Errorf("Expected an error for %q", text)
+		}
+	}
+}
+
+func TestProcessFileCustomPathTemplate(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "10.0.0.1"}
+	logDir := filepath.Join(topLevelDir, node.Address, "cassandra", "logs")
+	if err := os.MkdirAll(logDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n"
+	if err := os.WriteFile(filepath.Join(logDir, "system.log"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := ParsePathTemplate("{{.TopLevelDir}}/{{.Address}}/cassandra/logs/{{.File}}")
+	if err != nil {
+		t.Fatalf("ParsePathTemplate() error = %v", err)
+	}
+	entries := CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, nil, ScanOptions{PathTemplate: tmpl})[node.Address]
+	if len(entries) != 1 || entries[0].FilePath != filepath.Join(logDir, "system.log") {
+		t.Errorf("Expected the entry of %s, got %v", filepath.Join(logDir, "system.log"), entries)
+	}
+}
@@ -0,0 +1,38 @@
+package pipeline
+
+import (
+	"io"
+	"os"
+)
+
+// LogBytes returns the total size of the local log files of the nodes under topLevelDir a scan with opts reads, which
+// opts.Progress counts up to.
+func LogBytes(nodes []Node, topLevelDir string, opts ScanOptions) int64 {
+	var total int64
+	for _, node := range nodes {
+		// nodes whose log paths can't be built are reported by the scan
+		logFiles, _ := NodeLogFiles(node, topLevelDir, opts)
+		for _, logFile := range logFiles {
+			info, err := os.Stat(logFile)
+			if err != nil || info.ModTime().Before(opts.ModifiedSince) {
+				continue
+			}
+			total += info.Size()
+		}
+	}
+	return total
+}
+
+// progressReader reports the number of bytes read from r to progress.
+type progressReader struct {
+	r        io.Reader
+	progress func(n int64)
+}
+
+func (p progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.progress(int64(n))
+	}
+	return n, err
+}
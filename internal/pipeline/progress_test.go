@@ -0,0 +1,65 @@
+package pipeline
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLogBytes(t *testing.T) {
+	topLevelDir := t.TempDir()
+	writeSystemLog(t, topLevelDir, "10.0.0.1", strings.Repeat("a", 100))
+	old := writeSystemLog(t, topLevelDir, "10.0.0.2", strings.Repeat("b", 50))
+	lastWeek := time.Now().Add(-7 * 24 * time.Hour)
+	if err := os.Chtimes(old, lastWeek, lastWeek); err != nil {
+		t.Fatal(err)
+	}
+	// a node without logs adds nothing
+	nodes := []Node{{Address: "10.0.0.1"}, {Address: "10.0.0.2"}, {Address: "10.0.0.3"}}
+
+	if got := LogBytes(nodes, topLevelDir, ScanOptions{}); got != 150 {
+		t.Errorf("Expected 150 bytes of logs, got %d", got)
+	}
+	if got := LogBytes(nodes, topLevelDir, ScanOptions{ModifiedSince: time.Now().Add(-time.Hour)}); got != 100 {
+		t.Errorf("Expected 100 bytes of logs modified in the last hour, got %d", got)
+	}
+}
+
+func TestProcessFileProgress(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "10.0.0.1"}
+	content := "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n" +
+		"WARN  [main] 2023-07-14 16:00:02,000 Server.java:30 - Slow\n"
+	systemLog := writeSystemLog(t, topLevelDir, node.Address, content)
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(filepath.Dir(systemLog), "system.log.1.gz"), compressed.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := ScanOptions{LogFiles: []string{"system.log", "system.log.1.gz"}}
+	var read int64
+	opts.Progress = func(n int64) { atomic.AddInt64(&read, n) }
+	if err := ProcessFile(context.Background(), node, topLevelDir, nil, make(chan *LogEntry, 10), opts); err != nil {
+		t.Fatal(err)
+	}
+	want := LogBytes([]Node{node}, topLevelDir, opts)
+	if want != int64(len(content)+compressed.Len()) {
+		t.Errorf("Expected %d bytes of logs, got %d", len(content)+compressed.Len(), want)
+	}
+	if read != want {
+		t.Errorf("Expected %d bytes read, got %d", want, read)
+	}
+}
@@ -0,0 +1,37 @@
+package pipeline
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// FindNodeLogFiles walks topLevelDir for system.log files in <address>/logs/cassandra directories at any depth and maps
+// each node address to its log file. If an address appears more than once, the last file in lexical order wins, which
+// is the most recent one for dated subdirectories.
+func FindNodeLogFiles(topLevelDir string) (map[string]string, error) {
+	nodePaths := make(map[string]string)
+	err := filepath.WalkDir(topLevelDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != "system.log" {
+			return nil
+		}
+
+		cassandraDir := filepath.Dir(path)
+		logsDir := filepath.Dir(cassandraDir)
+		if filepath.Base(cassandraDir) != "cassandra" || filepath.Base(logsDir) != "logs" {
+			return nil
+		}
+		nodeDir := filepath.Dir(logsDir)
+		if nodeDir == topLevelDir || nodeDir == "." {
+			return nil
+		}
+		nodePaths[filepath.Base(nodeDir)] = path
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nodePaths, nil
+}
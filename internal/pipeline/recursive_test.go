@@ -0,0 +1,49 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindNodeLogFiles(t *testing.T) {
+	topLevelDir := t.TempDir()
+	files := []string{
+		"2023-07-13/nodes/10.0.0.1/logs/cassandra/system.log",
+		"2023-07-14/nodes/10.0.0.1/logs/cassandra/system.log",
+		"2023-07-14/dc2/rack1/10.0.0.2/logs/cassandra/system.log",
+		"10.0.0.3/logs/cassandra/system.log",
+		"2023-07-14/nodes/10.0.0.4/logs/cassandra/debug.log",
+		"2023-07-14/nodes/10.0.0.5/logs/system.log",
+	}
+	for _, file := range files {
+		path := filepath.Join(topLevelDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := FindNodeLogFiles(topLevelDir)
+	if err != nil {
+		t.Fatalf("FindNodeLogFiles() error = %v", err)
+	}
+	want := map[string]string{
+		"10.0.0.1": filepath.Join(topLevelDir, "2023-07-14/nodes/10.0.0.1/logs/cassandra/system.log"),
+		"10.0.0.2": filepath.Join(topLevelDir, "2023-07-14/dc2/rack1/10.0.0.2/logs/cassandra/system.log"),
+		"10.0.0.3": filepath.Join(topLevelDir, "10.0.0.3/logs/cassandra/system.log"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindNodeLogFiles() = %v, want %v", got, want)
+	}
+
+	nodes := []Node{{Address: "10.0.0.2"}}
+	nodeEntries := CollectNodeEntries(context.Background(), nodes, topLevelDir, nil, ScanOptions{NodePaths: got})
+	if len(nodeEntries["10.0.0.2"]) != 1 {
+		t.Errorf("Expected the discovered log file to be scanned, got %d entries", len(nodeEntries["10.0.0.2"]))
+	}
+}
@@ -0,0 +1,323 @@
+// Package pipeline selects the nodes to scan, reads their logs into the entries matching the queries, and filters the
+// entries collected before they are printed.
+package pipeline
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github/kenjords/wetlog/pkg/wetlog"
+)
+
+// ScanOptions controls how ProcessFile parses log files.
+type ScanOptions struct {
+	InferYear bool              // InferYear parses dates without a year using the year of the log file's modification time.
+	ErrorsOut io.Writer         // ErrorsOut receives every line that couldn't be parsed. It must be safe for concurrent use.
+	Matchers  []Matcher         // Matchers are checked along with the queries before an entry is emitted.
+	NodePaths map[string]string // NodePaths maps node addresses to log files read instead of the standard layout.
+	// NodeDone is called after the logs of each node have been processed, see scanNode. It must be safe for concurrent
+	// use.
+	NodeDone func(node Node)
+	// Concurrency is the number of nodes processed at once by forEachNode, 0 means runtime.NumCPU().
+	Concurrency int
+	// PerDCConcurrency is the number of nodes of a same datacenter processed at once by forEachNode, within
+	// Concurrency, 0 means no limit per datacenter.
+	PerDCConcurrency int
+	// DateLayout is a Go time layout tried before the built-in ones to parse the date of each line.
+	DateLayout string
+	// Deterministic processes the nodes one at a time in address order, so entries are produced in the same order on
+	// every run.
+	Deterministic bool
+	// IgnoreCase matches the queries ignoring case, see matchQuery.
+	IgnoreCase bool
+	// MatchAny keeps the entries matching any of the queries instead of all of them, see matchAnyQuery.
+	MatchAny bool
+	// Journald parses lines exported by journald, "timestamp hostname process[pid]: message", taking the date and node
+	// from the prefix.
+	Journald bool
+	// NodeDirs maps node addresses to the name of their directory under nodes/ when it differs from the address, see
+	// ReconcileNodeDirs.
+	NodeDirs map[string]string
+	// LogFiles are the names of the files read in the log directory of each node, e.g. system.log and debug.log.
+	// Files missing from a node are skipped. Empty reads system.log only.
+	LogFiles []string
+	// PathTemplate builds the path of each log file of a node from its logPathFields. Nil uses DefaultPathTemplate.
+	PathTemplate *template.Template
+	// LineContext sets PrevLine and NextLine of every entry to the line numbers of its neighbors in the file.
+	LineContext bool
+	// ModifiedSince skips log files last modified before it without opening them. The zero time scans every file.
+	ModifiedSince time.Time
+	// SSH reads the log of each node from the live node over SSH instead of from the top-level directory, nil for none.
+	SSH *SSHConfig
+	// SkippedLines, if not nil, is atomically incremented for every line belonging to no entry, e.g. a banner preceding
+	// the first entry of a file or the continuation of a line that couldn't be parsed.
+	SkippedLines *int64
+	// FilesRead, if not nil, is atomically incremented for every log processLog reads, local or remote.
+	FilesRead *int64
+	// BytesRead, if not nil, is atomically incremented by the bytes processLog reads, after decompression.
+	BytesRead *int64
+	// Progress, if not nil, is called with the number of bytes read from each local log file, before decompression. It
+	// must be safe for concurrent use.
+	Progress func(n int64)
+	// NodeErrors, if not nil, collects the error of every node whose logs couldn't be processed instead of logging it
+	// as soon as it happens.
+	NodeErrors *NodeErrors
+
+	modTime time.Time // modTime is the modification time of the file being processed, set when InferYear or Journald is.
+}
+
+// DefaultLogFile is the log file read in the log directory of each node when ScanOptions.LogFiles is empty.
+const DefaultLogFile = "system.log"
+
+// NodeLogFiles returns the paths of the log files of the node under topLevelDir, built with opts.PathTemplate, or the
+// single path given for the node in opts.NodePaths.
+func NodeLogFiles(node Node, topLevelDir string, opts ScanOptions) ([]string, error) {
+	if nodePath, ok := opts.NodePaths[node.Address]; ok {
+		return []string{nodePath}, nil
+	}
+	names := opts.LogFiles
+	if len(names) == 0 {
+		names = []string{DefaultLogFile}
+	}
+	dirName := node.Address
+	if nodeDir, ok := opts.NodeDirs[node.Address]; ok {
+		dirName = nodeDir
+	}
+	tmpl := opts.PathTemplate
+	if tmpl == nil {
+		tmpl = defaultPathTmpl
+	}
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		path, err := executePathTemplate(tmpl, logPathFields{TopLevelDir: topLevelDir, Address: dirName, File: name})
+		if err != nil {
+			return nil, fmt.Errorf("Error while building the log path of node %s: %v", node.Address, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// ProcessFile processes the log files of a node. Missing files are skipped, unless none of them exists. It stops early
+// and returns ctx.Err() once ctx is cancelled.
+func ProcessFile(ctx context.Context, node Node, topLevelDir string, queries []string, logEntryChan chan *LogEntry, opts ScanOptions) error {
+	if opts.SSH != nil {
+		return processRemoteLog(ctx, node, queries, logEntryChan, opts)
+	}
+
+	logFiles, err := NodeLogFiles(node, topLevelDir, opts)
+	if err != nil {
+		return err
+	}
+	var missingErr error
+	found := false
+	for _, logFile := range logFiles {
+		err := processLogFile(ctx, node, logFile, queries, logEntryChan, opts)
+		if errors.Is(err, fs.ErrNotExist) {
+			if missingErr == nil {
+				missingErr = err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+	}
+	if !found {
+		return missingErr
+	}
+	return nil
+}
+
+// processLogFile processes a single log file of a node.
+func processLogFile(ctx context.Context, node Node, logFile string, queries []string, logEntryChan chan *LogEntry, opts ScanOptions) error {
+	if !opts.ModifiedSince.IsZero() {
+		info, err := os.Stat(logFile)
+		if err != nil {
+			return err
+		}
+		if info.ModTime().Before(opts.ModifiedSince) {
+			return nil
+		}
+	}
+
+	file, err := os.Open(logFile) //nosec G304
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = file.Close()
+	}()
+
+	if opts.InferYear || opts.Journald {
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		opts.modTime = info.ModTime()
+	}
+
+	var r io.Reader = file
+	if opts.Progress != nil {
+		r = progressReader{r: file, progress: opts.Progress}
+	}
+	// rotated logs such as system.log.1.gz are decompressed on the fly
+	if strings.HasSuffix(logFile, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("Invalid gzip file %s: %v", logFile, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	return processLog(ctx, node, r, logFile, queries, logEntryChan, opts)
+}
+
+// processLog parses the log of node read from r and sends the entries matching the queries and opts.Matchers to
+// logEntryChan. logFile is the path recorded in the entries. It returns ctx.Err() as soon as ctx is cancelled, without
+// sending the remaining entries.
+func processLog(ctx context.Context, node Node, r io.Reader, logFile string, queries []string, logEntryChan chan *LogEntry, opts ScanOptions) error {
+	if opts.FilesRead != nil {
+		atomic.AddInt64(opts.FilesRead, 1)
+	}
+	if opts.BytesRead != nil {
+		r = countingReader{r: r, n: opts.BytesRead}
+	}
+	matchers := NewMatcherChain(append([]Matcher{queryMatcher(queries, opts.IgnoreCase, opts.MatchAny)}, opts.Matchers...)...)
+	scanner := bufio.NewScanner(r)
+	var currentEntry *LogEntry
+	// with LineContext, a finished entry is held back until the next entry gives its NextLine
+	var heldEntry *LogEntry
+	prevLine := 0
+	done := ctx.Done()
+	// send doesn't block past the cancellation of ctx, the consumer of logEntryChan may have stopped reading
+	send := func(entry *LogEntry) {
+		select {
+		case logEntryChan <- entry:
+		case <-done:
+		}
+	}
+	finish := func(entry *LogEntry) {
+		if !matchers.Match(entry) {
+			return
+		}
+		if opts.LineContext {
+			heldEntry = entry
+			return
+		}
+		send(entry)
+	}
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		select {
+		case <-done:
+			return ctx.Err()
+		default:
+		}
+
+		line := scanner.Text()
+		content := line
+		if opts.Journald {
+			content = journaldMessage(line)
+		}
+
+		if currentEntry != nil && !wetlog.StartsEntry(content) {
+			currentEntry.Message += "\n" + content
+			if opts.Journald {
+				currentEntry.RawLine += "\n" + line
+			} else {
+				// the message is the raw lines, sharing its memory
+				currentEntry.RawLine = currentEntry.Message
+			}
+			currentEntry.LineCount++
+			continue
+		}
+
+		if currentEntry != nil {
+			finish(currentEntry)
+		}
+
+		var err error
+		currentEntry, err = processLine(line, lineNumber, logFile, opts)
+		if currentEntry != nil {
+			currentEntry.RawLine = line
+			// entries parsed with -journald already carry the host from their prefix
+			if currentEntry.NodeIP == "" {
+				currentEntry.NodeIP = node.Address
+			}
+			currentEntry.Datacenter = node.Datacenter
+			if opts.LineContext {
+				currentEntry.PrevLine = prevLine
+				prevLine = lineNumber
+				if heldEntry != nil {
+					heldEntry.NextLine = lineNumber
+					send(heldEntry)
+					heldEntry = nil
+				}
+			}
+		}
+		if currentEntry == nil && opts.ErrorsOut != nil {
+			writeParseError(opts.ErrorsOut, logFile, lineNumber, line, err)
+		}
+		if currentEntry == nil && opts.SkippedLines != nil {
+			atomic.AddInt64(opts.SkippedLines, 1)
+		}
+		if err != nil {
+			continue
+		}
+	}
+
+	if currentEntry != nil {
+		finish(currentEntry)
+	}
+	if heldEntry != nil {
+		send(heldEntry)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return scanner.Err()
+}
+
+// errNoLevelAndDate is reported for lines processLine rejects without an error of its own.
+var errNoLevelAndDate = fmt.Errorf("No log level and date found")
+
+// writeParseError records a line that couldn't be parsed, with its file and line number, to w.
+func writeParseError(w io.Writer, filePath string, lineNumber int, line string, err error) {
+	if err == nil {
+		err = errNoLevelAndDate
+	}
+	_, _ = fmt.Fprintf(w, "%s:%d: %v: %s\n", filePath, lineNumber, err, line)
+}
+
+// processLine processes a line of a log file using the given scan options.
+func processLine(line string, lineNumber int, filePath string, opts ScanOptions) (*LogEntry, error) {
+	if opts.Journald {
+		return processJournaldLine(line, lineNumber, filePath, opts)
+	}
+	return wetlog.ParseLine(line, lineNumber, filePath, wetlog.LineOptions{DateLayout: opts.DateLayout, InferYear: opts.InferYear, ModTime: opts.modTime})
+}
+
+// countingReader counts the bytes read from r in n, atomically since the nodes are read concurrently.
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	atomic.AddInt64(c.n, int64(n))
+	return n, err
+}
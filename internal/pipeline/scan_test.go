@@ -0,0 +1,524 @@
+package pipeline
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessFile(t *testing.T) {
+	t.Helper()
+	node := Node{Address: "192.0.2.0"} // This IP address is a placeholder. It should be replaced with a valid IP.
+	topLevelDir := "test"
+	queries := []string{"INFO"}
+
+	// Create the necessary path and file for the test
+	testPath := filepath.Join(topLevelDir, "nodes", node.Address, "logs", "cassandra")
+	err := os.MkdirAll(testPath, os.ModePerm)
+	if err != nil {
+		t.Fatalf("Couldn't create path: %v", err)
+	}
+
+	// Write sample data to the system.log file
+	testFilePath := filepath.Join(testPath, "system.log")
+	err = os.WriteFile(testFilePath, []byte("Sample log data"), 0o644)
+	if err != nil {
+		t.Fatalf("Couldn't write to file: %v", err)
+	}
+
+	// Make sure to clean up after test
+	defer func() {
+		err := os.RemoveAll(filepath.Join(topLevelDir, "nodes"))
+		if err != nil {
+			t.Errorf("Couldn't clean up test files: %v", err)
+		}
+	}()
+
+	logEntryChan := make(chan *LogEntry)
+	errChan := make(chan error)
+
+	go func() {
+		err := ProcessFile(context.Background(), node, topLevelDir, queries, logEntryChan, ScanOptions{})
+		if err != nil {
+			errChan <- err
+		}
+		close(logEntryChan)
+	}()
+
+	// Check whether it sends LogEntry to the channel correctly.
+	for logEntry := range logEntryChan {
+		if logEntry.Message == "" {
+			t.Fatalf("Expected message in log entry, got empty")
+		}
+	}
+
+	// Check for errors
+	select {
+	case err := <-errChan:
+		t.Fatalf("ProcessFile() error = %v", err)
+	default:
+	}
+}
+
+// TestProcessFileInferYear tests that yearless dates are only parsed with InferYear, using the log file's modification year.
+func TestProcessFileBracketedLevel(t *testing.T) {
+	// a continuation line starting with a bracketed word other than a level stays part of its entry
+	topLevelDir := t.TempDir()
+	node := Node{Address: "10.0.0.1"}
+	writeSystemLog(t, topLevelDir, node.Address,
+		"[WARN] 2023-07-14 16:00:00,658 Server.java:10 - Slow query\n"+
+			"[main] details\n"+
+			"[INFO] 2023-07-14 16:00:01,000 Server.java:10 - Done\n")
+	entries := CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, nil, ScanOptions{})[node.Address]
+	sort.Sort(ByDate{LogEntries: entries})
+	if len(entries) != 2 || entries[0].LineCount != 2 || entries[1].LogLevel != INFO {
+		t.Errorf("Expected a 2-line WARN entry followed by an INFO entry, got %+v", entries)
+	}
+}
+
+func TestProcessFileRequiresLevelAndDate(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "192.0.2.3"}
+	writeSystemLog(t, topLevelDir, node.Address,
+		"WARN  [main] 2023-07-14 16:00:00,000 Server.java:10 - Slow\n"+
+			"INFO replaying segment written 2023-07-14 16:00:01,000\n"+
+			"WARN  [main] 2023-07-14 16:00:02,000 Server.java:10 - Slow again\n")
+	var lineNumbers []int
+	for _, entry := range CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, nil, ScanOptions{})[node.Address] {
+		lineNumbers = append(lineNumbers, entry.LineNumber)
+	}
+	sort.Ints(lineNumbers)
+	if !reflect.DeepEqual(lineNumbers, []int{1, 3}) {
+		t.Errorf("Expected entries from lines 1 and 3, got %v", lineNumbers)
+	}
+}
+
+func TestProcessFileInferYear(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "192.0.2.1"}
+	logFile := writeSystemLog(t, topLevelDir, node.Address,
+		"INFO  [main] 12-31 23:59:00,000 Server.java:10 - Before new year\n"+
+			"INFO  [main] 01-01 00:01:00,000 Server.java:10 - After new year\n")
+	modTime := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(logFile, modTime, modTime); err != nil {
+		t.Fatalf("Couldn't set modification time: %v", err)
+	}
+
+	collect := func(opts ScanOptions) LogEntries {
+		logEntryChan := make(chan *LogEntry)
+		go func() {
+			if err := ProcessFile(context.Background(), node, topLevelDir, nil, logEntryChan, opts); err != nil {
+				t.Errorf("ProcessFile() error = %v", err)
+			}
+			close(logEntryChan)
+		}()
+		var entries LogEntries
+		for entry := range logEntryChan {
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+
+	if entries := collect(ScanOptions{}); len(entries) != 0 {
+		t.Errorf("Expected no entries without InferYear, got %d", len(entries))
+	}
+
+	entries := collect(ScanOptions{InferYear: true})
+	want := []time.Time{
+		time.Date(2022, 12, 31, 23, 59, 0, 0, time.UTC),
+		time.Date(2023, 1, 1, 0, 1, 0, 0, time.UTC),
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d entries with InferYear, got %d", len(want), len(entries))
+	}
+	for i, entry := range entries {
+		if !entry.Date.Equal(want[i]) {
+			t.Errorf("Expected date %v at index %d, got %v", want[i], i, entry.Date)
+		}
+	}
+}
+
+// TestProcessFileErrorsOut tests that malformed lines are written to ErrorsOut while valid entries are still emitted.
+func TestProcessFileErrorsOut(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "192.0.2.2"}
+	logFile := writeSystemLog(t, topLevelDir, node.Address,
+		"INFO  [main] 2023-07-14 16:00:00,658 Server.java:10 - Starting\n"+
+			"BOGUS [main] 2023-07-14 16:00:01,000 Server.java:10 - Unknown level\n"+
+			"WARN  [main] without a date\n"+
+			"WARN  [main] 2023-07-14 16:00:02,000 Server.java:10 - Slow query\n")
+
+	var errorsOut bytes.Buffer
+	logEntryChan := make(chan *LogEntry)
+	go func() {
+		if err := ProcessFile(context.Background(), node, topLevelDir, nil, logEntryChan, ScanOptions{ErrorsOut: &errorsOut}); err != nil {
+			t.Errorf("ProcessFile() error = %v", err)
+		}
+		close(logEntryChan)
+	}()
+
+	var lineNumbers []int
+	for entry := range logEntryChan {
+		lineNumbers = append(lineNumbers, entry.LineNumber)
+	}
+	if !reflect.DeepEqual(lineNumbers, []int{1, 4}) {
+		t.Errorf("Expected entries from lines 1 and 4, got %v", lineNumbers)
+	}
+
+	want := logFile + ":2: Invalid log level: BOGUS: BOGUS [main] 2023-07-14 16:00:01,000 Server.java:10 - Unknown level\n" +
+		logFile + ":3: No log level and date found: WARN  [main] without a date\n"
+	if errorsOut.String() != want {
+		t.Errorf("Expected errors output:\n%s\nGot:\n%s", want, errorsOut.String())
+	}
+}
+
+// TestProcessFileNodePath tests that an overridden node reads its log from the custom location.
+func TestProcessFileNodePath(t *testing.T) {
+	topLevelDir := t.TempDir()
+	customLog := filepath.Join(t.TempDir(), "collected", "system.log")
+	if err := os.MkdirAll(filepath.Dir(customLog), os.ModePerm); err != nil {
+		t.Fatalf("Couldn't create path: %v", err)
+	}
+	if err := os.WriteFile(customLog, []byte("INFO  [main] 2023-07-14 16:00:00,658 Server.java:10 - From the custom path\n"), 0o644); err != nil {
+		t.Fatalf("Couldn't write to file: %v", err)
+	}
+	writeSystemLog(t, topLevelDir, "10.0.0.6", "INFO  [main] 2023-07-14 16:00:00,658 Server.java:10 - From the standard path\n")
+
+	opts := ScanOptions{NodePaths: map[string]string{"10.0.0.5": customLog}}
+	nodeEntries := CollectNodeEntries(context.Background(), []Node{{Address: "10.0.0.5"}, {Address: "10.0.0.6"}}, topLevelDir, nil, opts)
+
+	if entries := nodeEntries["10.0.0.5"]; len(entries) != 1 || entries[0].FilePath != customLog {
+		t.Errorf("Expected one entry from %s for the overridden node, got %v", customLog, entries)
+	}
+	if entries := nodeEntries["10.0.0.6"]; len(entries) != 1 || !strings.HasSuffix(entries[0].Message, "From the standard path") {
+		t.Errorf("Expected one entry from the standard path for the other node, got %v", entries)
+	}
+}
+
+// TestProcessFileLineCount tests that continuation lines are counted and can be filtered and sorted on.
+func TestProcessFileLineCount(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "192.0.2.3"}
+	writeSystemLog(t, topLevelDir, node.Address,
+		"INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n"+
+			"ERROR [main] 2023-07-14 16:00:01,000 Server.java:20 - Exception thrown\n"+
+			"java.lang.RuntimeException: boom\n"+
+			"\tat org.apache.cassandra.Server.start(Server.java:20)\n"+
+			"\tat org.apache.cassandra.Server.main(Server.java:5)\n"+
+			"WARN  [main] 2023-07-14 16:00:02,000 Server.java:30 - Slow\n"+
+			"\tdetail\n")
+
+	entries := CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, nil, ScanOptions{})[node.Address]
+	sort.Sort(ByLineNumber{LogEntries: entries})
+
+	wantCounts := []int{1, 4, 2}
+	if len(entries) != len(wantCounts) {
+		t.Fatalf("Expected %d entries, got %d", len(wantCounts), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.LineCount != wantCounts[i] {
+			t.Errorf("Expected line count %d for entry %d, got %d", wantCounts[i], i, entry.LineCount)
+		}
+	}
+
+	filtered := filterByMinLines(entries, 2)
+	if len(filtered) != 2 || filtered[0].LineNumber != 2 || filtered[1].LineNumber != 6 {
+		t.Errorf("Expected entries from lines 2 and 6 with -min-lines 2, got %v", filtered)
+	}
+
+	sort.Sort(ByLineCount{LogEntries: entries})
+	if entries[2].LineNumber != 2 {
+		t.Errorf("Expected the entry with the most lines to sort last, got line %d", entries[2].LineNumber)
+	}
+}
+
+func TestProcessFileJavaExceptions(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "192.0.2.4"}
+	writeSystemLog(t, topLevelDir, node.Address,
+		"Cassandra log, rotated 2023-07-14\n"+
+			"\n"+
+			"ERROR [CompactionExecutor:3] 2023-07-14 16:00:00,000 CassandraDaemon.java:581 - Exception in thread Thread[CompactionExecutor:3,1,main]\n"+
+			"java.lang.RuntimeException: java.io.IOException: Corrupt sstable\n"+
+			"\tat org.apache.cassandra.db.compaction.CompactionTask.runMayThrow(CompactionTask.java:241)\n"+
+			"\tat org.apache.cassandra.utils.WrappedRunnable.run(WrappedRunnable.java:28)\n"+
+			"Caused by: java.io.IOException: Corrupt sstable\n"+
+			"\tat org.apache.cassandra.io.sstable.SSTableReader.open(SSTableReader.java:412)\n"+
+			"\t... 2 common frames omitted\n"+
+			"Caused by: java.nio.BufferUnderflowException: null\n"+
+			"\tat java.nio.Buffer.nextGetIndex(Buffer.java:510)\n"+
+			"\t... 3 common frames omitted\n"+
+			"WARN  [main] 2023-07-14 16:00:01,000 StartupChecks.java:143 - JMX is not enabled\n"+
+			"Exception in thread \"main\" java.lang.OutOfMemoryError: Java heap space\n"+
+			"\tat java.util.Arrays.copyOf(Arrays.java:3332)\n"+
+			"INFO  [main] 2023-07-14 16:00:02,000 Server.java:10 - Started\n")
+
+	var skipped int64
+	entries := CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, nil, ScanOptions{SkippedLines: &skipped})[node.Address]
+	sort.Sort(ByLineNumber{LogEntries: entries})
+
+	wantLines := []struct{ lineNumber, lineCount int }{{3, 10}, {13, 3}, {16, 1}}
+	if len(entries) != len(wantLines) {
+		t.Fatalf("Expected %d entries, got %d", len(wantLines), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.LineNumber != wantLines[i].lineNumber || entry.LineCount != wantLines[i].lineCount {
+			t.Errorf("Expected entry %d at line %d with %d lines, got line %d with %d lines", i, wantLines[i].lineNumber, wantLines[i].lineCount, entry.LineNumber, entry.LineCount)
+		}
+	}
+	if !strings.HasSuffix(entries[0].Message, "\tat java.nio.Buffer.nextGetIndex(Buffer.java:510)\n\t... 3 common frames omitted") {
+		t.Errorf("Expected the causes to be part of the exception entry, got %q", entries[0].Message)
+	}
+
+	if skipped != 2 {
+		t.Errorf("Expected the 2 lines preceding the first entry to be skipped, got %d", skipped)
+	}
+}
+
+// TestProcessFileDatacenter tests that entries carry the datacenter of their node.
+func TestProcessFileDatacenter(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "192.0.2.4", Datacenter: "DC2"}
+	writeSystemLog(t, topLevelDir, node.Address, "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n")
+
+	entries := CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, nil, ScanOptions{})[node.Address]
+	if len(entries) != 1 || entries[0].Datacenter != "DC2" {
+		t.Errorf("Expected one entry in DC2, got %v", entries)
+	}
+}
+
+func TestProcessFileLogFiles(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "10.0.0.1", Datacenter: "DC1"}
+	systemLog := writeSystemLog(t, topLevelDir, node.Address, "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n")
+	debugLog := filepath.Join(filepath.Dir(systemLog), "debug.log")
+	if err := os.WriteFile(debugLog, []byte("DEBUG [main] 2023-07-14 16:00:01,000 Server.java:20 - Loading\n"), 0o644); err != nil {
+		t.Fatalf("Couldn't write to file: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		logFiles  []string
+		wantFiles []string
+	}{
+		{name: "default", logFiles: nil, wantFiles: []string{systemLog}},
+		{name: "system and debug", logFiles: []string{"system.log", "debug.log"}, wantFiles: []string{systemLog, debugLog}},
+		{name: "missing file skipped", logFiles: []string{"system.log.1", "debug.log"}, wantFiles: []string{debugLog}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, nil, ScanOptions{LogFiles: tt.logFiles})[node.Address]
+			var files []string
+			for _, entry := range entries {
+				files = append(files, entry.FilePath)
+			}
+			sort.Strings(files)
+			want := append([]string(nil), tt.wantFiles...)
+			sort.Strings(want)
+			if !reflect.DeepEqual(files, want) {
+				t.Errorf("Expected entries from %v, got %v", want, files)
+			}
+		})
+	}
+
+	logEntryChan := make(chan *LogEntry, 10)
+	err := ProcessFile(context.Background(), node, topLevelDir, nil, logEntryChan, ScanOptions{LogFiles: []string{"system.log.1", "system.log.2"}})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a not exist error when no log file exists, got %v", err)
+	}
+}
+
+func TestProcessFileGzip(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "10.0.0.1", Datacenter: "DC1"}
+	content := "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n" +
+		"ERROR [main] 2023-07-14 16:00:01,000 Server.java:20 - Exception thrown\n" +
+		"java.lang.RuntimeException: boom\n" +
+		"\tat org.apache.cassandra.Server.start(Server.java:20)\n" +
+		"WARN  [main] 2023-07-14 16:00:02,000 Server.java:30 - Slow\n"
+	systemLog := writeSystemLog(t, topLevelDir, node.Address, content)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	gzLog := filepath.Join(filepath.Dir(systemLog), "system.log.1.gz")
+	if err := os.WriteFile(gzLog, compressed.Bytes(), 0o644); err != nil {
+		t.Fatalf("Couldn't write to file: %v", err)
+	}
+
+	plain := CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, nil, ScanOptions{})[node.Address]
+	unzipped := CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, nil, ScanOptions{LogFiles: []string{"system.log.1.gz"}})[node.Address]
+	sort.Sort(ByLineNumber{LogEntries: plain})
+	sort.Sort(ByLineNumber{LogEntries: unzipped})
+	if len(plain) != 3 || len(unzipped) != len(plain) {
+		t.Fatalf("Expected 3 entries from both files, got %d and %d", len(plain), len(unzipped))
+	}
+	for i := range plain {
+		if unzipped[i].FilePath != gzLog {
+			t.Errorf("Expected file path %s, got %s", gzLog, unzipped[i].FilePath)
+		}
+		unzipped[i].FilePath = plain[i].FilePath
+		if !reflect.DeepEqual(unzipped[i], plain[i]) {
+			t.Errorf("Expected entry %+v from the gzipped log, got %+v", plain[i], unzipped[i])
+		}
+	}
+
+	if err := os.WriteFile(gzLog, []byte(content), 0o644); err != nil {
+		t.Fatalf("Couldn't write to file: %v", err)
+	}
+	err := ProcessFile(context.Background(), node, topLevelDir, nil, make(chan *LogEntry, 10), ScanOptions{LogFiles: []string{"system.log.1.gz"}})
+	if err == nil {
+		t.Errorf("Expected an error for a .gz file that isn't gzipped")
+	}
+}
+
+func TestProcessFileCancel(t *testing.T) {
+	const lineCount = 100000
+	topLevelDir := t.TempDir()
+	node := Node{Address: "192.0.2.5"}
+	var content strings.Builder
+	for i := 0; i < lineCount; i++ {
+		fmt.Fprintf(&content, "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Entry %d\n", i)
+	}
+	writeSystemLog(t, topLevelDir, node.Address, content.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logEntryChan := make(chan *LogEntry)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- ProcessFile(ctx, node, topLevelDir, nil, logEntryChan, ScanOptions{})
+		close(logEntryChan)
+	}()
+
+	received := 0
+	for range logEntryChan {
+		received++
+		if received == 10 {
+			cancel()
+			break
+		}
+	}
+	// at most the entry being sent when the context was cancelled may still come through
+	afterCancel := 0
+	for range logEntryChan {
+		afterCancel++
+	}
+
+	if err := <-errChan; !errors.Is(err, context.Canceled) {
+		t.Errorf("ProcessFile() error = %v, want %v", err, context.Canceled)
+	}
+	if afterCancel > 1 {
+		t.Errorf("Expected no more entries after the cancellation, got %d of the %d remaining", afterCancel, lineCount-received)
+	}
+}
+
+func TestProcessFileModifiedSince(t *testing.T) {
+	topLevelDir := t.TempDir()
+	content := "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n"
+	oldFile := writeSystemLog(t, topLevelDir, "10.0.0.1", content)
+	writeSystemLog(t, topLevelDir, "10.0.0.2", content)
+	oldTime := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(oldFile, oldTime, oldTime); err != nil {
+		t.Fatalf("Couldn't set modification time: %v", err)
+	}
+	nodes := []Node{{Address: "10.0.0.1"}, {Address: "10.0.0.2"}}
+
+	nodeEntries := CollectNodeEntries(context.Background(), nodes, topLevelDir, nil, ScanOptions{ModifiedSince: time.Now().Add(-24 * time.Hour)})
+	if len(nodeEntries["10.0.0.1"]) != 0 {
+		t.Errorf("Expected the old file to be skipped, got %d entries", len(nodeEntries["10.0.0.1"]))
+	}
+	if len(nodeEntries["10.0.0.2"]) != 1 {
+		t.Errorf("Expected 1 entry from the recent file, got %d", len(nodeEntries["10.0.0.2"]))
+	}
+
+	nodeEntries = CollectNodeEntries(context.Background(), nodes, topLevelDir, nil, ScanOptions{})
+	if len(nodeEntries["10.0.0.1"]) != 1 {
+		t.Errorf("Expected the old file to be scanned without ModifiedSince, got %d entries", len(nodeEntries["10.0.0.1"]))
+	}
+}
+
+func TestProcessFileLineContext(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "192.0.2.1"}
+	writeSystemLog(t, topLevelDir, node.Address,
+		"INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - First\n"+
+			"WARN  [main] 2023-07-14 16:00:01,000 Server.java:10 - Second\n"+
+			"\tat org.apache.cassandra.Server.run(Server.java:10)\n"+
+			"INFO  [main] 2023-07-14 16:00:02,000 Server.java:10 - Third\n"+
+			"ERROR [main] 2023-07-14 16:00:03,000 Server.java:10 - Fourth\n")
+
+	entries := CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, nil, ScanOptions{LineContext: true})[node.Address]
+	sort.Sort(ByLineNumber{LogEntries: entries})
+	want := []struct{ line, prev, next int }{
+		{line: 1, prev: 0, next: 2},
+		{line: 2, prev: 1, next: 4},
+		{line: 4, prev: 2, next: 5},
+		{line: 5, prev: 4, next: 0},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %d", len(want), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.LineNumber != want[i].line || entry.PrevLine != want[i].prev || entry.NextLine != want[i].next {
+			t.Errorf("Expected line %d with neighbors %d and %d, got line %d with neighbors %d and %d",
+				want[i].line, want[i].prev, want[i].next, entry.LineNumber, entry.PrevLine, entry.NextLine)
+		}
+	}
+
+	// neighbors are the entries of the file, whether they match the query or not
+	entries = CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, []string{"Third"}, ScanOptions{LineContext: true})[node.Address]
+	if len(entries) != 1 || entries[0].PrevLine != 2 || entries[0].NextLine != 5 {
+		t.Errorf("Expected the matching entry to have neighbors 2 and 5, got %+v", entries)
+	}
+}
+
+func TestProcessFileNodeIP(t *testing.T) {
+	topLevelDir := t.TempDir()
+	node := Node{Address: "192.0.2.7", Datacenter: "DC1"}
+	writeSystemLog(t, topLevelDir, node.Address,
+		"INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n"+
+			"ERROR [main] 2023-07-14 16:00:01,000 Server.java:10 - Failed\n"+
+			"\tat org.apache.cassandra.Server.run(Server.java:10)\n"+
+			"WARN  [main] 2023-07-14 16:00:02,000 Server.java:10 - Slow\n")
+
+	entries := CollectNodeEntries(context.Background(), []Node{node}, topLevelDir, nil, ScanOptions{})[node.Address]
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	for _, entry := range entries {
+		if entry.NodeIP != node.Address {
+			t.Errorf("Expected entry from line %d to carry node %s, got %q", entry.LineNumber, node.Address, entry.NodeIP)
+		}
+	}
+}
+
+// writeSystemLog writes content to the system.log of the node with the given address under topLevelDir.
+func writeSystemLog(t *testing.T, topLevelDir, address, content string) string {
+	t.Helper()
+	logDir := filepath.Join(topLevelDir, "nodes", address, "logs", "cassandra")
+	if err := os.MkdirAll(logDir, os.ModePerm); err != nil {
+		t.Fatalf("Couldn't create path: %v", err)
+	}
+	logFile := filepath.Join(logDir, "system.log")
+	if err := os.WriteFile(logFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("Couldn't write to file: %v", err)
+	}
+	return logFile
+}
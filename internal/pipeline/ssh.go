@@ -0,0 +1,101 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	// DefaultSSHLogPath is the path of the Cassandra log on the nodes read with -ssh.
+	DefaultSSHLogPath = "/var/log/cassandra/system.log"
+	// sshDialTimeout bounds how long connecting to a node over SSH may take.
+	sshDialTimeout = 10 * time.Second
+)
+
+// SSHConfig describes how to read the logs of live nodes over SSH.
+type SSHConfig struct {
+	Client    *ssh.ClientConfig // Client holds the user, authentication and host key checks of the connections.
+	Port      int               // Port is the SSH port of every node.
+	LogPath   string            // LogPath is the path of the log file on the nodes.
+	TailLines int               // TailLines only reads the last lines of the log file, 0 reads it whole.
+}
+
+// NewSSHClientConfig returns the configuration of SSH connections as user, authenticated with the private key in
+// keyFile and checking the host keys of the nodes against the knownHostsFile.
+func NewSSHClientConfig(user, keyFile, knownHostsFile string) (*ssh.ClientConfig, error) {
+	key, err := os.ReadFile(keyFile) //nosec G304
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid SSH private key %s: %v", keyFile, err)
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, err
+	}
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sshDialTimeout,
+	}, nil
+}
+
+// shellQuote quotes s as a single word for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// remoteLogCommand returns the shell command printing the log file, or only its last tailLines lines if positive.
+func remoteLogCommand(logPath string, tailLines int) string {
+	if tailLines > 0 {
+		return fmt.Sprintf("tail -n %d -- %s", tailLines, shellQuote(logPath))
+	}
+	return "cat -- " + shellQuote(logPath)
+}
+
+// processRemoteLog reads the log file of node over SSH as described by opts.SSH and processes it like ProcessFile,
+// streaming the output of the remote command into the parser.
+func processRemoteLog(ctx context.Context, node Node, queries []string, logEntryChan chan *LogEntry, opts ScanOptions) error {
+	client, err := ssh.Dial("tcp", net.JoinHostPort(node.Address, strconv.Itoa(opts.SSH.Port)), opts.SSH.Client)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr strings.Builder
+	session.Stderr = &stderr
+	if err := session.Start(remoteLogCommand(opts.SSH.LogPath, opts.SSH.TailLines)); err != nil {
+		return err
+	}
+
+	// dates without a year are taken to be from the current year, the remote file being live
+	opts.modTime = time.Now()
+	if err := processLog(ctx, node, stdout, opts.SSH.LogPath, queries, logEntryChan, opts); err != nil {
+		return err
+	}
+	if err := session.Wait(); err != nil {
+		return fmt.Errorf("Reading %s over SSH failed: %v: %s", opts.SSH.LogPath, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
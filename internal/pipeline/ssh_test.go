@@ -0,0 +1,172 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// startMockSSHServer starts an SSH server on localhost accepting clientKey that answers every exec request with
+// content, and returns its address. Every command run is sent to commands.
+func startMockSSHServer(t *testing.T, hostKey ssh.Signer, clientKey ssh.PublicKey, content string, commands chan<- string) string {
+	t.Helper()
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, os.ErrPermission
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveMockSSHConn(conn, config, content, commands)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveMockSSHConn(conn net.Conn, config *ssh.ServerConfig, content string, commands chan<- string) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer channel.Close()
+			for req := range channelRequests {
+				if req.Type != "exec" {
+					_ = req.Reply(false, nil)
+					continue
+				}
+				var payload struct{ Command string }
+				_ = ssh.Unmarshal(req.Payload, &payload)
+				commands <- payload.Command
+				_ = req.Reply(true, nil)
+				_, _ = channel.Write([]byte(content))
+				_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+				return
+			}
+		}()
+	}
+}
+
+// newTestSSHKey returns a new ed25519 key as a signer along with its private key PEM-encoded.
+func newTestSSHKey(t *testing.T) (ssh.Signer, []byte) {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestProcessRemoteLog(t *testing.T) {
+	hostKey, _ := newTestSSHKey(t)
+	clientKey, clientPEM := newTestSSHKey(t)
+	content := "INFO  [main] 2023-07-14 16:00:00,000 StorageService.java:10 - Starting\n" +
+		"WARN  [main] 2023-07-14 16:00:01,000 Gossiper.java:20 - Node /10.0.0.2 is down\n" +
+		"\tdetail\n"
+	commands := make(chan string, 1)
+	addr := startMockSSHServer(t, hostKey, clientKey.PublicKey(), content, commands)
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "id_ed25519")
+	knownHostsFile := filepath.Join(dir, "known_hosts")
+	if err := os.WriteFile(keyFile, clientPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	knownHost := knownhosts.Line([]string{knownhosts.Normalize(addr)}, hostKey.PublicKey()) + "\n"
+	if err := os.WriteFile(knownHostsFile, []byte(knownHost), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	clientConfig, err := NewSSHClientConfig("cassandra", keyFile, knownHostsFile)
+	if err != nil {
+		t.Fatalf("NewSSHClientConfig() error = %v", err)
+	}
+	opts := ScanOptions{SSH: &SSHConfig{Client: clientConfig, Port: port, LogPath: "/var/log/cassandra/system.log", TailLines: 100}}
+	node := Node{Address: host, Datacenter: "DC1"}
+
+	entries := CollectNodeEntries(context.Background(), []Node{node}, "", []string{"down"}, opts)[node.Address]
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry matching the query, got %d", len(entries))
+	}
+	if entries[0].LogLevel != WARN || entries[0].LineNumber != 2 || entries[0].LineCount != 2 || entries[0].Datacenter != "DC1" {
+		t.Errorf("Unexpected entry %+v", entries[0])
+	}
+	if got, want := <-commands, "tail -n 100 -- '/var/log/cassandra/system.log'"; got != want {
+		t.Errorf("Expected remote command %q, got %q", want, got)
+	}
+
+	// a host key missing from known_hosts is refused
+	if err := os.WriteFile(knownHostsFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	opts.SSH.Client, err = NewSSHClientConfig("cassandra", keyFile, knownHostsFile)
+	if err != nil {
+		t.Fatalf("NewSSHClientConfig() error = %v", err)
+	}
+	if err := processRemoteLog(context.Background(), node, nil, make(chan *LogEntry, 10), opts); err == nil {
+		t.Errorf("Expected an error for an unknown host key")
+	}
+}
+
+func TestRemoteLogCommand(t *testing.T) {
+	tests := []struct {
+		path string
+		tail int
+		want string
+	}{
+		{path: "/var/log/cassandra/system.log", tail: 0, want: "cat -- '/var/log/cassandra/system.log'"},
+		{path: "/logs/it's here.log", tail: 50, want: `tail -n 50 -- '/logs/it'\''s here.log'`},
+	}
+
+	for _, tt := range tests {
+		if got := remoteLogCommand(tt.path, tt.tail); got != tt.want {
+			t.Errorf("remoteLogCommand(%q, %d) = %q, want %q", tt.path, tt.tail, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package pipeline
+
+import "github/kenjords/wetlog/pkg/wetlog"
+
+// The types of pkg/wetlog the pipeline works on, aliased for brevity.
+type (
+	Node         = wetlog.Node
+	LogLevel     = wetlog.LogLevel
+	LogEntry     = wetlog.LogEntry
+	LogEntries   = wetlog.LogEntries
+	ByDate       = wetlog.ByDate
+	ByLogLevel   = wetlog.ByLogLevel
+	ByLineNumber = wetlog.ByLineNumber
+	ByLineCount  = wetlog.ByLineCount
+)
+
+// Log levels, see wetlog.LogLevel.
+const (
+	DEBUG = wetlog.DEBUG
+	INFO  = wetlog.INFO
+	WARN  = wetlog.WARN
+	ERROR = wetlog.ERROR
+)
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github/kenjords/wetlog/pkg/wetlog"
+)
+
+// byLoad sorts LogEntries by the load of their node, from the least to the most loaded, then by date. Entries of nodes
+// with an unknown load come last.
+type byLoad struct {
+	LogEntries
+	loads map[string]int64
+}
+
+// Less returns true if the node of the LogEntry at index i is less loaded than the node of the LogEntry at index j.
+func (s byLoad) Less(i, j int) bool {
+	load1, ok1 := s.loads[s.LogEntries[i].NodeIP]
+	load2, ok2 := s.loads[s.LogEntries[j].NodeIP]
+	if ok1 != ok2 {
+		return ok1
+	}
+	if load1 != load2 {
+		return load1 < load2
+	}
+	return wetlog.DateLess(s.LogEntries[i], s.LogEntries[j])
+}
+
+// newLoadSorter returns the sort.Interface ordering entries by the LoadBytes of their node in nodes.
+func newLoadSorter(entries LogEntries, nodes []Node) sort.Interface {
+	loads := make(map[string]int64, len(nodes))
+	for _, node := range nodes {
+		if node.LoadBytes >= 0 {
+			loads[node.Address] = node.LoadBytes
+		}
+	}
+	return byLoad{entries, loads}
+}
+
+// sortNodesByLoad returns the nodes sorted by their LoadBytes, from the least to the most loaded like -sort load. Nodes
+// with an unknown load come last, and nodes with the same load are sorted by address.
+func sortNodesByLoad(nodes []Node) []Node {
+	sorted := append([]Node(nil), nodes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch {
+		case (a.LoadBytes < 0) != (b.LoadBytes < 0):
+			return b.LoadBytes < 0
+		case a.LoadBytes != b.LoadBytes:
+			return a.LoadBytes < b.LoadBytes
+		default:
+			return a.Address < b.Address
+		}
+	})
+	return sorted
+}
+
+// PrintNodes writes the nodes to w in columns, one per line with its address, datacenter, rack, status and load as
+// nodetool status reported it, "?" when unknown.
+func PrintNodes(w io.Writer, nodes []Node) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "Address\tDatacenter\tRack\tStatus\tLoad"); err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		load := node.Load
+		if node.LoadBytes < 0 {
+			load = "?"
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", node.Address, node.Datacenter, node.Rack, node.Status, load); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestLoadSorter(t *testing.T) {
+	nodes := []Node{
+		{Address: "10.0.0.1", Load: "1.2 GiB", LoadBytes: 1288490188},
+		{Address: "10.0.0.2", Load: "?", LoadBytes: -1},
+		{Address: "10.0.0.3", Load: "512.5 KiB", LoadBytes: 524800},
+	}
+	entries := LogEntries{
+		{NodeIP: "10.0.0.2", LineNumber: 1},
+		{NodeIP: "10.0.0.1", LineNumber: 2},
+		{NodeIP: "10.0.0.3", LineNumber: 3},
+		{NodeIP: "10.0.0.1", LineNumber: 4},
+	}
+
+	sortWith(func(e LogEntries) sort.Interface { return newLoadSorter(e, nodes) }, false)(entries)
+
+	var got []int
+	for _, entry := range entries {
+		got = append(got, entry.LineNumber)
+	}
+	if want := []int{3, 2, 4, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected line order %v, got %v", want, got)
+	}
+}
+
+func TestSortNodesByLoad(t *testing.T) {
+	nodes := []Node{
+		{Address: "10.0.0.1", Load: "1.2 GiB", LoadBytes: 1288490188},
+		{Address: "10.0.0.2", Load: "?", LoadBytes: -1},
+		{Address: "10.0.0.3", Load: "512.5 KiB", LoadBytes: 524800},
+		{Address: "10.0.0.4", LoadBytes: -1},
+		{Address: "10.0.0.5", Load: "0 bytes", LoadBytes: 0},
+		{Address: "10.0.0.0", Load: "1.2 GiB", LoadBytes: 1288490188},
+	}
+
+	var got []string
+	for _, node := range sortNodesByLoad(nodes) {
+		got = append(got, node.Address)
+	}
+	want := []string{"10.0.0.5", "10.0.0.3", "10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected node order %v, got %v", want, got)
+	}
+	if nodes[0].Address != "10.0.0.1" {
+		t.Errorf("Expected the nodes to be left unsorted")
+	}
+}
+
+func TestPrintNodes(t *testing.T) {
+	nodes := []Node{
+		{Address: "10.0.0.3", Datacenter: "DC1", Rack: "rack2", Status: "UN", Load: "512.5 KiB", LoadBytes: 524800},
+		{Address: "10.0.1.1", Datacenter: "DC2", Status: "DN", LoadBytes: -1},
+	}
+
+	var buf bytes.Buffer
+	if err := PrintNodes(&buf, nodes); err != nil {
+		t.Fatalf("PrintNodes() error = %v", err)
+	}
+	want := "Address   Datacenter  Rack   Status  Load\n" +
+		"10.0.0.3  DC1         rack2  UN      512.5 KiB\n" +
+		"10.0.1.1  DC2                DN      ?\n"
+	if buf.String() != want {
+		t.Errorf("PrintNodes() = %q, want %q", buf.String(), want)
+	}
+}
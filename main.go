@@ -1,8 +1,6 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"flag"
 	"fmt"
 	"io"
@@ -11,90 +9,59 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
 	"strings"
-	"sync"
 	"syscall"
 	"time"
+
+	"github/kenjords/wetlog/internal/pipeline"
+	"github/kenjords/wetlog/pkg/wetlog"
 )
 
 var (
 	wetlogVersion = "v0.4"
 )
 
-// Node represents a node in the cluster.
-type Node struct {
-	Address    string
-	Datacenter string
+// nodeIPSorter sorts LogEntries by node IP, parsing every address once up front instead of on each comparison.
+type nodeIPSorter struct {
+	entries LogEntries
+	ips     []net.IP
 }
 
-// LogLevel represents a log level as an iota integer constant. The iota starts at 0 and increments by 1 for each LogLevel higher.
-type LogLevel int
-
-const (
-	// DEBUG is the lowest log level in Cassandra and will have detailed information about Cassandra actions.
-	DEBUG LogLevel = iota // 0
-	// INFO is the second lowest log level and is typically used in Cassandra for informative actions.
-	INFO // 1
-	// WARN in Cassandra is used to indicate that something is not right, but Cassandra can still function.
-	WARN // 2
-	// ERROR is the highest log level in Cassandra and is used to indicate that the particular action taken by cassandra has failed.
-	ERROR //	3
-)
+func (s nodeIPSorter) Len() int { return len(s.entries) }
 
-// LogEntry represents a log entry.
-type LogEntry struct {
-	LogLevel   LogLevel  // LogLevel is the log level of the entry.
-	Date       time.Time // Date is the date of the entry.
-	LineNumber int       // LineNumber is the line number of the entry.
-	NodeIP     string    // NodeIP is the IP address of the node that generated the entry.
-	FilePath   string    // FilePath is the path to the log file that generated the entry.
-	Message    string    // Message is the message of the entry.
+func (s nodeIPSorter) Swap(i, j int) {
+	s.entries[i], s.entries[j] = s.entries[j], s.entries[i]
+	s.ips[i], s.ips[j] = s.ips[j], s.ips[i]
 }
 
-// LogEntries is a pointer to a slice of LogEntry.
-type LogEntries []*LogEntry
-
-// Len returns the length of the LogEntries slice.
-func (s LogEntries) Len() int { return len(s) }
-
-// Swap swaps the elements at the given indices.
-func (s LogEntries) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
-
-// ByDate sorts LogEntries by date.
-type ByDate struct{ LogEntries }
-
-// ByLogLevel sorts LogEntries by log level.
-type ByLogLevel struct{ LogEntries }
-
-// ByLineNumber sorts LogEntries by line number.
-type ByLineNumber struct{ LogEntries }
-
-// ByNodeIP sorts LogEntries by node IP.
-type ByNodeIP struct{ LogEntries }
-
-// Less returns true if the date of the LogEntry at index i is before the date of the LogEntry at index j.
-func (s ByDate) Less(i, j int) bool { return s.LogEntries[i].Date.Before(s.LogEntries[j].Date) }
-
-// Less returns true if the log level of the LogEntry at index i is before the log level of the LogEntry at index j.
-func (s ByLogLevel) Less(i, j int) bool { return s.LogEntries[i].LogLevel < s.LogEntries[j].LogLevel }
-
-// Less returns true if the line number of the LogEntry at index i is before the line number of the LogEntry at index j.
-func (s ByLineNumber) Less(i, j int) bool {
-	return s.LogEntries[i].LineNumber < s.LogEntries[j].LineNumber
+func (s nodeIPSorter) Less(i, j int) bool {
+	if s.entries[i].NodeIP != s.entries[j].NodeIP {
+		return wetlog.NodeIPLess(s.entries[i].NodeIP, s.entries[j].NodeIP, s.ips[i], s.ips[j])
+	}
+	return wetlog.DateLess(s.entries[i], s.entries[j])
 }
 
-// Less returns true if the node IP of the LogEntry at index i is before the node IP of the LogEntry at index j.
-func (s ByNodeIP) Less(i, j int) bool {
-	ip1 := net.ParseIP(s.LogEntries[i].NodeIP)
-	ip2 := net.ParseIP(s.LogEntries[j].NodeIP)
-
-	// Fallback to lexicographical comparison if parsing fails
-	if ip1 == nil || ip2 == nil {
-		return strings.Compare(s.LogEntries[i].NodeIP, s.LogEntries[j].NodeIP) < 0
+// newNodeIPSorter returns the sort.Interface ordering entries by node IP like ByNodeIP, parsing every address once.
+func newNodeIPSorter(entries LogEntries) sort.Interface {
+	ips := make([]net.IP, len(entries))
+	for i, entry := range entries {
+		ips[i] = net.ParseIP(entry.NodeIP)
 	}
+	return nodeIPSorter{entries: entries, ips: ips}
+}
 
-	return bytes.Compare(ip1, ip2) < 0
+// sortWith returns the function sorting entries with the sort.Interface newSorter returns for them, in descending order
+// if reverse is true. Entries that compare equal keep their order.
+func sortWith(newSorter func(LogEntries) sort.Interface, reverse bool) func(LogEntries) {
+	return func(entries LogEntries) {
+		sorter := newSorter(entries)
+		if reverse {
+			sorter = sort.Reverse(sorter)
+		}
+		sort.Stable(sorter)
+	}
 }
 
 func PrintVersion() string {
@@ -102,13 +69,102 @@ func PrintVersion() string {
 }
 
 func main() {
-	// TODO split main into smaller functions
-	nodetoolFile := flag.String("file", "", "Path to the nodetool status output file")
+	nodetoolFile := flag.String("file", "", "Path to the nodetool status output file, or - to read it from stdin")
 	datacenters := flag.String("datacenters", "", "Comma-separated list of datacenter names")
 	listDCs := flag.Bool("list-dcs", false, "List all datacenters")
-	sortOption := flag.String("sort", "date", "Sort by date, loglevel, linenumber, or nodeip")
-	query := flag.String("query", "", "Comma-separated search terms in log entries")
+	sortOption := flag.String("sort", "date", "Sort by date, loglevel, linenumber, nodeip, linecount, load, relevance, or metric:<name>")
+	query := flag.String("query", "", "Comma-separated search terms in log entries, double quotes keep commas and spaces in a term")
 	version := flag.Bool("version", false, "Print version and exit")
+	format := flag.String("format", FormatText, "Output format: text, json, csv, proto, or raw for the original log lines")
+	output := flag.String("output", OutputText, "Output mode: text prints the entries one at a time in the -format, json prints them as a single JSON array")
+	metricsPatterns := flag.String("metrics-patterns", "", "Comma-separated metric patterns to extract from messages (gc_pause_ms, pending_tasks, compaction_remaining, compaction_throughput_mibs) or all")
+	metricMin := flag.String("metric-min", "", "Only keep entries whose extracted metric is at least a value, e.g. gc_pause_ms=500")
+	inferYear := flag.Bool("infer-year", false, "Parse dates without a year using the year of the log file's modification time")
+	limitDCs := flag.Int("limit-dcs", 0, "Only process the nodes of the first N datacenters in sorted order (0 means no limit)")
+	errorsOut := flag.String("errors-out", "", "Write the lines that couldn't be parsed to this file")
+	nodePath := flag.String("node-path", "", "Comma-separated address=path pairs reading a node's log from a custom path, e.g. 10.0.0.5=/custom/path/system.log")
+	collapseWS := flag.Bool("collapse-ws", false, "Collapse whitespace and newlines in messages into single spaces")
+	sortExpr := flag.String("sort-expr", "", "Sort by several fields with directions, e.g. loglevel:desc,date:asc (overrides -sort)")
+	validateStatus := flag.Bool("validate-status", false, "Check the parsed node list for duplicate addresses, missing datacenters and no nodes up before scanning")
+	inputJSON := flag.String("input-json", "", "Read previously emitted JSON entries from this file instead of scanning logs")
+	minLines := flag.Int("min-lines", 0, "Only keep entries spanning at least N lines, e.g. stack traces")
+	dedupWindow := flag.Duration("dedup-window", 0, "Collapse repeats of the same message signature on a node that occur within this duration of the previous one, e.g. 10s")
+	gaps := flag.Duration("gaps", 0, "Report periods longer than this duration in which a node logged nothing, e.g. 5m")
+	nodesFrom := flag.String("nodes-from", "", "Only process the node addresses listed in this file, one per line")
+	showDC := flag.Bool("show-dc", false, "Prefix each text output line with the datacenter of the entry")
+	dateFormat := flag.String("date-format", "", "Go time layout of the log dates, tried before the built-in layouts, e.g. \"02/01/2006 15:04:05.000\"")
+	color := flag.String("color", ColorAuto, "Color the output: auto (when stdout is a terminal), always, or never")
+	matchHighlight := flag.Bool("match-highlight", false, "Highlight the query hits in text output when colors are enabled")
+	fieldSep := flag.String("field-sep", "", "Separator between the node, file path and line number in text output (default \":\", or \"|\" for IPv6 nodes)")
+	matchPreview := flag.Bool("match-preview", false, "Report how many entries match the filters on each node instead of printing them")
+	recursive := flag.Bool("recursive", false, "Find the system.log of each node in <address>/logs/cassandra directories at any depth under the top-level directory")
+	warnFuture := flag.Bool("warn-on-future-dates", false, "Warn on stderr about entries dated in the future, which usually means clock skew")
+	dropFuture := flag.Bool("drop-future", false, "Warn about and leave out entries dated in the future")
+	groupByNode := flag.Bool("group-by-node", false, "Print the entries of each node under a header, collapsing consecutive repeats of a message")
+	timeout := flag.Duration("timeout", 0, "Stop scanning after this duration and print the entries collected so far, e.g. 30s")
+	correlate := flag.String("correlate", "", "Group entries across nodes by the correlation ID this regex extracts from their message (first capture group or whole match)")
+	explain := flag.String("explain", "", "Parse this single log line, print the fields extracted from it or the parse error, and exit")
+	listNodes := flag.Bool("list-nodes", false, "List the selected nodes sorted by their load in nodetool status, from the least to the most loaded")
+	allDCs := flag.Bool("all-dcs", false, "Process the nodes of every datacenter instead of requiring -datacenters")
+	dedupAll := flag.Bool("dedup", false, "Collapse the entries of all nodes with the same level and message, ignoring their date, into one with an occurrence count")
+	pathTemplate := flag.String("path-template", pipeline.DefaultPathTemplate, "Go template of the path of each log file, with the fields {{.TopLevelDir}}, {{.Address}} and {{.File}}")
+	outPath := flag.String("out", "", "Write the results to this file instead of stdout")
+	gcMinMs := flag.Int("gc-min-ms", 0, "Only keep the GCInspector entries reporting a pause of at least this many milliseconds, recorded in their gc_pause_ms metric")
+	event := flag.String("event", "", "Only keep the entries reporting this Cassandra event, e.g. dropped-mutations, and extract its counts into their metrics")
+	fuzzy := flag.Bool("fuzzy", false, "Match the query terms against the words of each message tolerating typos, up to -fuzzy-distance edits per term")
+	fuzzyDistance := flag.Int("fuzzy-distance", pipeline.DefaultFuzzyDistance, "Number of inserted, deleted or substituted characters -fuzzy tolerates per query term")
+	listSources := flag.Bool("list-sources", false, "Print the distinct source files of the matching entries, e.g. GCInspector.java, with their entry counts instead of the entries")
+	slowTraceThreshold := flag.Duration("slow-traces", 0, "With -correlate, report the correlation IDs whose first and last entries are at least this far apart, slowest first, instead of the traces, e.g. 500ms")
+	exclude := flag.String("exclude", "", "Comma-separated terms leaving out the entries whose message contains any of them, double quotes keep commas and spaces in a term")
+	perDCConcurrency := flag.Int("per-dc-concurrency", 0, "Number of nodes of each datacenter whose logs are processed at once, within -concurrency (0 means no limit per datacenter)")
+	matchAny := flag.Bool("match-any", false, "Keep the entries containing any of the query terms instead of all of them in order")
+	racks := flag.String("racks", "", "Comma-separated list of rack names whose nodes are processed, in every selected datacenter")
+	rawNodePrefix := flag.Bool("raw-node-prefix", false, "With -format raw, prefix every line with the node it comes from")
+	reverse := flag.Bool("reverse", false, "Reverse the order of -sort, e.g. newest entries first with -sort date")
+	restartLoops := flag.Duration("restart-loops", 0, "Report the nodes that started at least -restart-threshold times within this sliding window, e.g. 10m")
+	restartThreshold := flag.Int("restart-threshold", defaultRestartThreshold, "Number of startups within the -restart-loops window that flags a node as crash looping")
+	mergeSortBuffer := flag.Int("merge-sort-buffer", defaultMergeSortBuffer, "Sort the entries printed one at a time by date within about this many MiB of memory, spilling sorted runs to temporary files (0 sorts in memory)")
+	count := flag.Bool("count", false, "Print the total of matching entries and their counts per level and node instead of the entries")
+	concurrency := flag.Int("concurrency", runtime.NumCPU(), "Number of nodes whose logs are processed at once")
+	summaryOnly := flag.Bool("summary-json-only", false, "Only print the summary of the entries as a JSON object, without the entries")
+	tail := flag.Int("tail", 0, "Only keep the N most recent entries across all nodes (0 means no limit)")
+	kv := flag.Bool("kv", false, "Parse the key=value pairs of each message body into the fields of the entry, included in JSON output")
+	kvFilter := flag.String("kv-filter", "", "Only keep entries whose message has these comma-separated key=value pairs, e.g. pool=CompactionExecutor (implies -kv)")
+	since := flag.String("since", "", "Only keep entries dated at or after this date, in a log date layout, e.g. \"2023-07-14 02:00:00,000\"")
+	until := flag.String("until", "", "Only keep entries dated at or before this date, in a log date layout, e.g. \"2023-07-14 02:15:00,000\"")
+	throttleRate := flag.Int("throttle", 0, "Print at most N entries per second, flushing each one (0 means no limit)")
+	throttleMode := flag.String("throttle-mode", ThrottleBuffer, "What -throttle does with entries exceeding the rate: buffer (wait for their turn) or drop")
+	logFiles := flag.String("log-files", pipeline.DefaultLogFile, "Comma-separated names of the log files read in the log directory of each node, e.g. system.log,debug.log,system.log.1")
+	sshMode := flag.Bool("ssh", false, "Experimental: read the log of each node from the live node over SSH instead of from a top-level directory")
+	sshUser := flag.String("ssh-user", os.Getenv("USER"), "User to log in as with -ssh")
+	sshKey := flag.String("ssh-key", "", "Private key file to authenticate with -ssh, e.g. ~/.ssh/id_ed25519")
+	sshKnownHosts := flag.String("ssh-known-hosts", "", "known_hosts file checked for the host keys of the nodes with -ssh (default ~/.ssh/known_hosts)")
+	sshPort := flag.Int("ssh-port", 22, "SSH port of the nodes with -ssh")
+	sshLogPath := flag.String("ssh-log-path", pipeline.DefaultSSHLogPath, "Path of the log file on the nodes with -ssh")
+	sshTail := flag.Int("ssh-tail", 0, "Only read the last N lines of the log file of each node with -ssh (0 reads it whole)")
+	regexQuery := flag.Bool("regex", false, "Treat each query term as a Go regular expression that must match the message, e.g. 'GC in \\d{4,}ms'")
+	failLevel := flag.String("fail-level", "", "Exit with status 1 if any printed entry has at least this log level: DEBUG, INFO, WARN or ERROR")
+	ignoreCase := flag.Bool("ignore-case", false, "Match the query terms ignoring case, e.g. timeout also matches Timeout and TIMEOUT")
+	firstSource := flag.Bool("first-per-source", false, "Only print the earliest entry of each distinct source file, e.g. GCInspector.java, sorted by date")
+	minLevel := flag.String("min-level", "", "Only keep entries with at least this log level: DEBUG, INFO, WARN or ERROR")
+	bucketDetail := flag.Duration("bucket-detail", 0, "Print the entry count of each time bucket of this width, e.g. 10m, followed by sample entries")
+	bucketSamples := flag.Int("bucket-samples", 3, "Number of sample entries printed under each bucket with -bucket-detail")
+	ignoreCaseIDs := flag.Bool("ignore-case-dc-and-node", false, "Match datacenter names, rack names, node addresses and source files ignoring case in every filter")
+	statuses := flag.String("status", "", "Comma-separated list of node statuses to process, e.g. UN,UM (default all)")
+	onlyUp := flag.Bool("only-up", false, "Only process the nodes nodetool status reports as up")
+	onlyDown := flag.Bool("only-down", false, "Only process the nodes nodetool status reports as down")
+	showProgressBar := flag.Bool("progress-bar", false, "Show the share of log bytes read on stderr when it is a terminal")
+	summary := flag.Bool("summary", false, "With -format json, print a single object holding a summary of the entries and the entries")
+	stripPrefix := flag.String("strip-prefix", "", "Remove this leading directory from the file path of every entry, e.g. the bundle directory")
+	jsonMaxMsg := flag.Int("json-max-msg", 0, "Truncate the message of JSON output to N runes (0 means no limit)")
+	deterministic := flag.Bool("deterministic", false, "Process the nodes one at a time in address order so the output is identical across runs")
+	journald := flag.Bool("journald", false, "Parse logs exported by journald, taking the date and host from the journald prefix")
+	zeroNodes := flag.Bool("zero-nodes", false, "Report the scanned nodes without matching entries and whether their log file is missing")
+	lineContext := flag.Bool("include-line-context", false, "Record the line numbers of the previous and next entries of the same file in each entry")
+	modifiedSince := flag.Duration("modified-since", 0, "Skip log files not modified within this duration before now, e.g. 24h")
+	outputBufferSize := flag.Int("output-buffer-size", defaultOutputBufferSize, "Size in bytes of the buffer used to write entries")
+	benchmark := flag.Bool("benchmark", false, "Scan the logs and report throughput and timings instead of printing entries")
+	diffMode := flag.Bool("diff", false, "Compare two top-level dirs and report entries unique to each side per node")
 	flag.Parse()
 
 	if *version {
@@ -116,270 +172,502 @@ func main() {
 		os.Exit(0)
 	}
 
-	if *nodetoolFile == "" || (*datacenters == "" && !*listDCs) || flag.NArg() != 1 {
+	if *explain != "" {
+		opts := pipeline.ScanOptions{InferYear: *inferYear, Journald: *journald}
+		if err := pipeline.ExplainLine(os.Stdout, *explain, opts); err != nil {
+			syscall.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	wantArgs := 1
+	if *diffMode {
+		wantArgs = 2
+	}
+	if *sshMode {
+		wantArgs = 0
+	}
+
+	if !validCommandLine(commandLine{InputJSON: *inputJSON, NodetoolFile: *nodetoolFile, Datacenters: *datacenters, Racks: *racks, NodesFrom: *nodesFrom,
+		AllDCs: *allDCs, ListDCs: *listDCs, ListNodes: *listNodes, LimitDCs: *limitDCs, Args: flag.Args(), WantArgs: wantArgs}) {
 		flag.Usage()
 		os.Exit(1)
 	}
-
-	if _, err := os.Stat(*nodetoolFile); os.IsNotExist(err) {
-		log.Fatalf("File %s does not exist", *nodetoolFile)
+	if *allDCs && *datacenters != "" {
+		log.Printf("-all-dcs can't be combined with -datacenters")
+		syscall.Exit(2)
 	}
 
-	file, err := os.Open(*nodetoolFile)
+	queries, err := pipeline.ParseQueryTerms(*query)
 	if err != nil {
-		log.Fatal(err)
+		log.Print(err)
+		syscall.Exit(2)
 	}
-	defer func() {
-		err = file.Close()
-	}()
 
-	nodes, err := ParseNodetoolStatus(file)
+	excludes, err := pipeline.ParseQueryTerms(*exclude)
 	if err != nil {
-		log.Printf("Error while parsing the nodetool status output: %v", err)
-		syscall.Exit(1)
+		log.Print(err)
+		syscall.Exit(2)
+	}
+
+	// with -regex the terms are matched as compiled regexes instead of substrings
+	var queryRegexes []*regexp.Regexp
+	if *regexQuery && queries != nil {
+		queryRegexes, err = pipeline.CompileQueryRegexes(queries, *ignoreCase)
+		if err != nil {
+			log.Print(err)
+			syscall.Exit(2)
+		}
+		queries = nil
 	}
 
-	if *listDCs {
-		PrintDatacenters(nodes)
-		return
+	if *fuzzy && *regexQuery {
+		log.Printf("-fuzzy and -regex are mutually exclusive")
+		syscall.Exit(2)
+	}
+	if *fuzzyDistance < 0 {
+		log.Printf("Invalid fuzzy distance: %d", *fuzzyDistance)
+		syscall.Exit(2)
+	}
+	// with -fuzzy the terms are compared with the words of the message instead of searched as substrings, like -regex
+	var fuzzyQueries []string
+	if *fuzzy && queries != nil {
+		fuzzyQueries, queries = queries, nil
 	}
 
-	// determine topLevelDir from nodetoolFile path
-	topLevelDir := flag.Arg(0)
-	dcNames := strings.Split(*datacenters, ",")
-	queries := strings.Split(*query, ",")
-	filteredNodes := filterNodesByDatacenters(nodes, dcNames)
+	// the query terms are counted as they are, -regex counts the hits of its expressions
+	relevance := func(message string) int { return relevanceScore(message, queries, *ignoreCase) }
+	if queryRegexes != nil {
+		relevance = func(message string) int { return relevanceRegexScore(message, queryRegexes) }
+	}
+	if *sortOption == "relevance" && fuzzyQueries != nil {
+		log.Printf("-sort relevance can't be combined with -fuzzy")
+		syscall.Exit(2)
+	}
 
-	sortFunctions := map[string]func(LogEntries){
-		"date":       func(entries LogEntries) { sort.Sort(ByDate{entries}) },
-		"loglevel":   func(entries LogEntries) { sort.Sort(ByLogLevel{entries}) },
-		"linenumber": func(entries LogEntries) { sort.Sort(ByLineNumber{entries}) },
-		"nodeip":     func(entries LogEntries) { sort.Sort(ByNodeIP{entries}) },
+	// the load sorter needs the nodes, parsed from the nodetool status output once the flags are validated
+	sortFunctions := map[string]func(LogEntries, []Node) sort.Interface{
+		"date":       func(entries LogEntries, _ []Node) sort.Interface { return ByDate{LogEntries: entries} },
+		"loglevel":   func(entries LogEntries, _ []Node) sort.Interface { return ByLogLevel{LogEntries: entries} },
+		"linenumber": func(entries LogEntries, _ []Node) sort.Interface { return ByLineNumber{LogEntries: entries} },
+		"nodeip":     func(entries LogEntries, _ []Node) sort.Interface { return newNodeIPSorter(entries) },
+		"linecount":  func(entries LogEntries, _ []Node) sort.Interface { return ByLineCount{LogEntries: entries} },
+		"load":       newLoadSorter,
+		"relevance":  func(entries LogEntries, _ []Node) sort.Interface { return newRelevanceSorter(entries, relevance) },
 	}
 
-	sortFunc, ok := sortFunctions[*sortOption]
+	newSorter, ok := sortFunctions[*sortOption]
+	if metricName, found := strings.CutPrefix(*sortOption, "metric:"); found {
+		_, ok = pipeline.FindMetricExtractor(metricName)
+		newSorter = func(entries LogEntries, _ []Node) sort.Interface {
+			return pipeline.ByMetric{LogEntries: entries, Name: metricName}
+		}
+	}
 	if !ok {
 		log.Printf("Invalid sort option: %s", *sortOption)
 		syscall.Exit(2)
 	}
-
-	var wg sync.WaitGroup
-	logEntryChan := make(chan *LogEntry, len(filteredNodes))
-
-	for _, node := range filteredNodes {
-		wg.Add(1)
-		go func(node Node) {
-			defer wg.Done()
-			err := ProcessFile(node, topLevelDir, queries, logEntryChan)
-			if err != nil {
-				log.Printf("Error while processing logs for node %s: %v\n", node.Address, err)
-			}
-		}(node)
+	newSortFunc := func(nodes []Node) func(LogEntries) {
+		return sortWith(func(entries LogEntries) sort.Interface { return newSorter(entries, nodes) }, *reverse)
 	}
 
-	go func() {
-		wg.Wait()
-		close(logEntryChan)
-	}()
-
-	// create logEntries slice
-	var logEntries LogEntries
-	for entry := range logEntryChan {
-		logEntries = append(logEntries, entry)
+	if *reverse && *sortExpr != "" {
+		log.Printf("-reverse doesn't apply to -sort-expr, use :desc on its fields instead")
+		syscall.Exit(2)
+	}
+	if *sortExpr != "" {
+		sortFunc, err := ParseSortExpr(*sortExpr)
+		if err != nil {
+			log.Print(err)
+			syscall.Exit(2)
+		}
+		newSortFunc = func([]Node) func(LogEntries) { return sortFunc }
 	}
 
-	// use sortFunc to sort logEntries
-	sortFunc(logEntries)
-
-	for _, entry := range logEntries {
-		fmt.Printf("%s:%s:%d: %v [%s] %s\n", entry.NodeIP, entry.FilePath, entry.LineNumber, entry.LogLevel, entry.Date, entry.Message)
+	formatOpts := FormatOptions{Format: *format, CollapseWhitespace: *collapseWS, ShowDatacenter: *showDC, JSONMaxMessage: *jsonMaxMsg, StripPrefix: *stripPrefix, FieldSeparator: *fieldSep, RawNodePrefix: *rawNodePrefix}
+	switch formatOpts.Format {
+	case FormatText, FormatJSON, FormatCSV, FormatProto, FormatRaw:
+	default:
+		log.Printf("Invalid output format: %s", *format)
+		syscall.Exit(2)
 	}
-}
 
-// ParseNodetoolStatus parses the output of nodetool status.
-func ParseNodetoolStatus(r io.Reader) ([]Node, error) {
-	// TODO implement being able to tell the status of the node (UN, DN, DL, UL, DJ, Uj, UM) and make it apart of the Node struct
+	switch *output {
+	case OutputText:
+	case OutputJSON:
+		if formatOpts.Format != FormatText && formatOpts.Format != FormatJSON {
+			log.Printf("-output json can't be combined with -format %s", formatOpts.Format)
+			syscall.Exit(2)
+		}
+	default:
+		log.Printf("Invalid output mode: %s", *output)
+		syscall.Exit(2)
+	}
 
-	scanner := bufio.NewScanner(r)
-	var nodes []Node
-	var datacenter string
-	var foundNodeStatus bool
+	if *color != ColorAuto && *color != ColorAlways && *color != ColorNever {
+		log.Printf("Invalid color mode: %s", *color)
+		syscall.Exit(2)
+	}
+	if *matchHighlight {
+		switch {
+		case fuzzyQueries != nil:
+			log.Printf("-match-highlight can't be combined with -fuzzy")
+			syscall.Exit(2)
+		case queryRegexes != nil:
+			formatOpts.Highlight = highlightRegexes(queryRegexes)
+		default:
+			formatOpts.Highlight = highlightRegex(queries, *ignoreCase)
+		}
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	var correlateRegex *regexp.Regexp
+	if *correlate != "" {
+		correlateRegex, err = regexp.Compile(*correlate)
+		if err != nil {
+			log.Printf("Invalid correlation regex: %v", err)
+			syscall.Exit(2)
+		}
+	}
+	if *slowTraceThreshold < 0 || (*slowTraceThreshold > 0 && correlateRegex == nil) {
+		log.Printf("-slow-traces requires -correlate and a positive duration")
+		syscall.Exit(2)
+	}
 
-		switch {
-		case strings.HasPrefix(line, "Datacenter:"):
-			fields := strings.Fields(line)
-			if len(fields) > 1 {
-				datacenter = fields[1]
-			}
-		case strings.HasPrefix(line, "UN") || strings.HasPrefix(line, "DN") || strings.HasPrefix(line, "UL") || strings.HasPrefix(line, "DL") || strings.HasPrefix(line, "UU") || strings.HasPrefix(line, "UJ") || strings.HasPrefix(line, "UM"):
-			fields := strings.Fields(line)
-			if len(fields) > 1 {
-				nodes = append(nodes, Node{Address: fields[1], Datacenter: datacenter})
-				foundNodeStatus = true
+	if *statuses != "" {
+		for _, status := range strings.Split(*statuses, ",") {
+			if !wetlog.IsNodeStatus(status) {
+				log.Printf("Invalid node status: %s", status)
+				syscall.Exit(2)
 			}
 		}
 	}
 
-	if !foundNodeStatus {
-		return nil, fmt.Errorf("No nodes found in nodetool status output")
+	if *onlyUp && *onlyDown {
+		log.Printf("-only-up and -only-down are mutually exclusive")
+		syscall.Exit(2)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	if *summary && formatOpts.Format != FormatJSON {
+		log.Printf("-summary requires -format json")
+		syscall.Exit(2)
 	}
 
-	return nodes, nil
-}
-
-// ParseDate parses a date string in the format "2006-01-02 15:04:05,000".
-func ParseDate(dateTimeStr string) (time.Time, error) {
-	return time.Parse("2006-01-02 15:04:05,000", dateTimeStr)
-}
+	if *concurrency <= 0 {
+		log.Printf("Invalid concurrency: %d", *concurrency)
+		syscall.Exit(2)
+	}
+	if *perDCConcurrency < 0 {
+		log.Printf("Invalid concurrency per datacenter: %d", *perDCConcurrency)
+		syscall.Exit(2)
+	}
 
-// ParseLogLevel parses a log level string into an iota.
-func ParseLogLevel(logLevelStr string) (LogLevel, error) {
-	switch logLevelStr {
-	case "DEBUG":
-		return DEBUG, nil
-	case "INFO":
-		return INFO, nil
-	case "WARN":
-		return WARN, nil
-	case "ERROR":
-		return ERROR, nil
-	default:
-		return 0, fmt.Errorf("Invalid log level: %s", logLevelStr)
+	if *restartThreshold < 2 {
+		log.Printf("Invalid restart threshold: %d", *restartThreshold)
+		syscall.Exit(2)
 	}
-}
 
-// ProcessFile processes a log file.
-func ProcessFile(node Node, topLevelDir string, queries []string, logEntryChan chan *LogEntry) error {
-	logDir := filepath.Join(topLevelDir, "nodes", node.Address, "logs", "cassandra")
-	logFile := filepath.Join(logDir, "system.log")
-	file, err := os.Open(logFile) //nosec G304
-	if err != nil {
-		return err
+	if *mergeSortBuffer < 0 {
+		log.Printf("Invalid merge sort buffer: %d", *mergeSortBuffer)
+		syscall.Exit(2)
+	}
+	// the external merge sort streams the entries to the output, so what needs them all at once keeps them in memory
+	streamsSorted := !(*sortOption != "date" || *reverse || *sortExpr != "" || *inputJSON != "" || *dedupWindow > 0 || *dedupAll || *tail > 0 ||
+		*failLevel != "" || *count || *listSources || *summaryOnly || *summary || *correlate != "" || *bucketDetail > 0 || *firstSource || *output == OutputJSON)
+	if *mergeSortBuffer > 0 && !streamsSorted && isFlagSet("merge-sort-buffer") {
+		log.Printf("-merge-sort-buffer only supports printing the scanned entries with -sort date, one at a time")
+		syscall.Exit(2)
 	}
-	defer func() {
-		err = file.Close()
-	}()
-	scanner := bufio.NewScanner(file)
-	var currentEntry *LogEntry
 
-	for lineNumber := 1; scanner.Scan(); lineNumber++ {
-		line := scanner.Text()
+	if *tail < 0 {
+		log.Printf("Invalid number of tail entries: %d", *tail)
+		syscall.Exit(2)
+	}
 
-		if currentEntry != nil && !startsWithLogLevel(line) {
-			currentEntry.Message += "\n" + line
-			continue
+	var kvFilterPairs map[string]string
+	if *kvFilter != "" {
+		kvFilterPairs, err = pipeline.ParseKeyValueFilter(*kvFilter)
+		if err != nil {
+			log.Print(err)
+			syscall.Exit(2)
 		}
+	}
 
-		if currentEntry != nil && matchQuery(currentEntry, queries) {
-			logEntryChan <- currentEntry
+	var sinceDate, untilDate time.Time
+	if *since != "" {
+		sinceDate, err = wetlog.ParseDate(*since)
+		if err != nil {
+			log.Printf("Invalid -since date: %s", *since)
+			syscall.Exit(2)
 		}
-
-		currentEntry, err = ProcessLine(line, lineNumber, logFile)
+	}
+	if *until != "" {
+		untilDate, err = wetlog.ParseDate(*until)
 		if err != nil {
-			continue
+			log.Printf("Invalid -until date: %s", *until)
+			syscall.Exit(2)
 		}
 	}
+	if !sinceDate.IsZero() && !untilDate.IsZero() && untilDate.Before(sinceDate) {
+		log.Printf("-until %s is before -since %s", *until, *since)
+		syscall.Exit(2)
+	}
 
-	if currentEntry != nil && matchQuery(currentEntry, queries) {
-		logEntryChan <- currentEntry
+	var limit *throttle
+	if *throttleRate < 0 {
+		log.Printf("Invalid throttle rate: %d", *throttleRate)
+		syscall.Exit(2)
+	}
+	if *throttleMode != ThrottleBuffer && *throttleMode != ThrottleDrop {
+		log.Printf("Invalid throttle mode: %s", *throttleMode)
+		syscall.Exit(2)
+	}
+	if *throttleRate > 0 {
+		limit = newThrottle(*throttleRate, *throttleMode == ThrottleDrop)
 	}
-	return scanner.Err()
-}
 
-// ProcessLine processes a line of a log file.
-func ProcessLine(line string, lineNumber int, filePath string) (*LogEntry, error) {
-	logLevelRegex := regexp.MustCompile(`^(\w+)\s`)
-	logLevelMatch := logLevelRegex.FindStringSubmatch(line)
+	var logFileNames []string
+	for _, name := range strings.Split(*logFiles, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			log.Printf("Invalid log file list: %s", *logFiles)
+			syscall.Exit(2)
+		}
+		logFileNames = append(logFileNames, name)
+	}
+	pathTmpl, err := pipeline.ParsePathTemplate(*pathTemplate)
+	if err != nil {
+		log.Print(err)
+		syscall.Exit(2)
+	}
 
-	if logLevelMatch == nil {
-		return nil, nil
+	if *sshMode {
+		switch {
+		case *diffMode:
+			log.Printf("-ssh can't be combined with -diff")
+			syscall.Exit(2)
+		case *sshKey == "":
+			log.Printf("-ssh requires -ssh-key")
+			syscall.Exit(2)
+		case *sshPort <= 0 || *sshPort > 65535:
+			log.Printf("Invalid SSH port: %d", *sshPort)
+			syscall.Exit(2)
+		case *sshTail < 0:
+			log.Printf("Invalid number of SSH tail lines: %d", *sshTail)
+			syscall.Exit(2)
+		}
 	}
 
-	logLevel, err := ParseLogLevel(logLevelMatch[1])
-	if err != nil {
-		return nil, err
+	if *jsonMaxMsg < 0 {
+		log.Printf("Invalid JSON message limit: %d", *jsonMaxMsg)
+		syscall.Exit(2)
+	}
+
+	if *bucketDetail < 0 {
+		log.Printf("Invalid bucket width: %s", *bucketDetail)
+		syscall.Exit(2)
 	}
 
-	dateTimeRegex := regexp.MustCompile(`(\d{4}-\d{2}-\d{2}\s\d{2}:\d{2}:\d{2},\d{3})`)
-	dateTimeMatch := dateTimeRegex.FindStringSubmatch(line)
+	if *bucketSamples < 0 {
+		log.Printf("Invalid number of bucket samples: %d", *bucketSamples)
+		syscall.Exit(2)
+	}
 
-	if dateTimeMatch == nil {
-		return nil, nil
+	if *outputBufferSize <= 0 {
+		log.Printf("Invalid output buffer size: %d", *outputBufferSize)
+		syscall.Exit(2)
 	}
 
-	date, err := ParseDate(dateTimeMatch[1])
+	extractors, err := pipeline.ParseMetricExtractors(*metricsPatterns)
 	if err != nil {
-		return nil, err
+		log.Print(err)
+		syscall.Exit(2)
 	}
 
-	return &LogEntry{
-		LogLevel:   logLevel,
-		Date:       date,
-		LineNumber: lineNumber,
-		NodeIP:     "",
-		FilePath:   filePath,
-		Message:    line,
-	}, err
-}
+	if *gcMinMs < 0 {
+		log.Printf("Invalid GC pause threshold: %d", *gcMinMs)
+		syscall.Exit(2)
+	}
 
-// PrintDatacenters prints the datacenters in the nodetool status output.
-func PrintDatacenters(nodes []Node) {
-	dcSet := make(map[string]struct{})
-	for _, node := range nodes {
-		dcSet[node.Datacenter] = struct{}{}
+	eventType := pipeline.EventNone
+	if *event != "" {
+		eventType, err = pipeline.ParseEventType(*event)
+		if err != nil {
+			log.Print(err)
+			syscall.Exit(2)
+		}
 	}
 
-	fmt.Println("Datacenters:")
-	for dc := range dcSet {
-		fmt.Println(dc)
+	var minLogLevel LogLevel
+	if *minLevel != "" {
+		minLogLevel, err = wetlog.ParseLogLevel(*minLevel)
+		if err != nil {
+			log.Print(err)
+			syscall.Exit(2)
+		}
 	}
-}
 
-// filterNodesByDatacenters filters nodes by datacenters.
-func filterNodesByDatacenters(nodes []Node, datacenters []string) []Node {
-	var filteredNodes []Node
-	dcSet := make(map[string]struct{})
+	var failLogLevel LogLevel
+	if *failLevel != "" {
+		failLogLevel, err = wetlog.ParseLogLevel(*failLevel)
+		if err != nil {
+			log.Print(err)
+			syscall.Exit(2)
+		}
+	}
 
-	for _, dc := range datacenters {
-		dcSet[dc] = struct{}{}
+	var metricMinName string
+	var metricMinValue float64
+	if *metricMin != "" {
+		metricMinName, metricMinValue, err = pipeline.ParseMetricThreshold(*metricMin)
+		if err != nil {
+			log.Print(err)
+			syscall.Exit(2)
+		}
+	}
+	// the metrics filtered or sorted by are extracted without being listed in -metrics-patterns
+	metricNames := sortExprMetrics(*sortExpr)
+	if metricName, found := strings.CutPrefix(*sortOption, "metric:"); found && *sortExpr == "" {
+		metricNames = append(metricNames, metricName)
+	}
+	if metricMinName != "" {
+		metricNames = append(metricNames, metricMinName)
+	}
+	extractors = pipeline.WithMetricExtractors(extractors, metricNames...)
+
+	// the filters that work on collected entries also apply to -input-json
+	filters := pipeline.EntryFilters{Extractors: extractors, Event: eventType, Since: sinceDate, Until: untilDate, KV: *kv, KVFilter: kvFilterPairs, GCMinMs: *gcMinMs,
+		MetricMinName: metricMinName, MetricMinValue: metricMinValue, MinLines: *minLines, DedupWindow: *dedupWindow, Dedup: *dedupAll}
+	// scans leave -min-level to pipeline.LevelMatcher
+	if *inputJSON != "" {
+		filters.MinLevel = minLogLevel
 	}
 
-	for _, node := range nodes {
-		if _, ok := dcSet[node.Datacenter]; ok {
-			filteredNodes = append(filteredNodes, node)
+	nodePaths, err := pipeline.ParseNodePaths(*nodePath)
+	if err != nil {
+		log.Print(err)
+		syscall.Exit(2)
+	}
+	scanOpts := pipeline.ScanOptions{InferYear: *inferYear, LineContext: *lineContext, Journald: *journald, Deterministic: *deterministic, DateLayout: *dateFormat, IgnoreCase: *ignoreCase, MatchAny: *matchAny, LogFiles: logFileNames, PathTemplate: pathTmpl, Concurrency: *concurrency, PerDCConcurrency: *perDCConcurrency}
+	if *minLevel != "" {
+		scanOpts.Matchers = append(scanOpts.Matchers, pipeline.LevelMatcher(minLogLevel))
+	}
+	if queryRegexes != nil {
+		scanOpts.Matchers = append(scanOpts.Matchers, pipeline.RegexMatcher(queryRegexes, *matchAny))
+	}
+	if fuzzyQueries != nil {
+		scanOpts.Matchers = append(scanOpts.Matchers, pipeline.FuzzyMatcher(fuzzyQueries, *fuzzyDistance, *ignoreCase, *matchAny))
+	}
+	if excludes != nil {
+		scanOpts.Matchers = append(scanOpts.Matchers, pipeline.ExcludeMatcher(excludes, *ignoreCase))
+	}
+	if eventType != pipeline.EventNone {
+		scanOpts.Matchers = append(scanOpts.Matchers, pipeline.EventMatcher(eventType))
+	}
+	if *sshMode && *inputJSON == "" {
+		knownHostsFile := *sshKnownHosts
+		if knownHostsFile == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				log.Fatal(err)
+			}
+			knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
 		}
+		clientConfig, err := pipeline.NewSSHClientConfig(*sshUser, *sshKey, knownHostsFile)
+		if err != nil {
+			log.Fatalf("Error while setting up SSH: %v", err)
+		}
+		scanOpts.SSH = &pipeline.SSHConfig{Client: clientConfig, Port: *sshPort, LogPath: *sshLogPath, TailLines: *sshTail}
+	}
+	if *modifiedSince > 0 {
+		scanOpts.ModifiedSince = time.Now().Add(-*modifiedSince)
 	}
 
-	return filteredNodes
+	cfg := runConfig{OutPath: *outPath, Color: *color, Format: formatOpts, OutputBufferSize: *outputBufferSize, Output: *output, Limit: limit,
+		InputJSON: *inputJSON, NodetoolFile: *nodetoolFile, ValidateStatus: *validateStatus, ListDCs: *listDCs, ListNodes: *listNodes,
+		Selection: pipeline.NodeSelection{Datacenters: *datacenters, Racks: *racks, Statuses: *statuses, LimitDCs: *limitDCs,
+			NodesFrom: *nodesFrom, OnlyUp: *onlyUp, OnlyDown: *onlyDown, IgnoreCase: *ignoreCaseIDs},
+		Dirs: flag.Args(), NodePaths: nodePaths, Recursive: *recursive, ErrorsOut: *errorsOut, ProgressBar: *showProgressBar,
+		Diff: *diffMode, Gaps: *gaps, RestartLoops: *restartLoops, RestartThreshold: *restartThreshold, GroupByNode: *groupByNode,
+		MatchPreview: *matchPreview, ZeroNodes: *zeroNodes, Benchmark: *benchmark, MergeSortBuffer: *mergeSortBuffer, StreamsSorted: streamsSorted,
+		WarnFuture: *warnFuture, DropFuture: *dropFuture, FirstSource: *firstSource, Summary: *summary, NewSortFunc: newSortFunc,
+		Report: reportModes{Count: *count, ListSources: *listSources, IgnoreCaseIDs: *ignoreCaseIDs, SummaryOnly: *summaryOnly,
+			Correlate: correlateRegex, SlowTraces: *slowTraceThreshold, BucketDetail: *bucketDetail, BucketSamples: *bucketSamples}}
+	if *failLevel != "" {
+		cfg.FailLevel = &failLogLevel
+	}
+
+	setup := scanSetup{queries: queries, opts: scanOpts, timeout: *timeout, filter: filters.Apply, tail: *tail}
+	if exitCode := setup.run(cfg); exitCode != 0 {
+		syscall.Exit(exitCode)
+	}
+}
+
+// isFlagSet returns true if the flag name was given on the command line, rather than left to its default.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
 
-// startsWithLogLevel returns true if the line starts with a log level.
-func startsWithLogLevel(line string) bool {
-	logLevelRegex := regexp.MustCompile(`^\w+\s`)
-	return logLevelRegex.MatchString(line)
+// commandLine holds the flags and arguments checked by validCommandLine.
+type commandLine struct {
+	InputJSON    string // InputJSON is the -input-json file, which replaces the nodetool status file and the directories.
+	NodetoolFile string // NodetoolFile is the -file nodetool status output.
+	Datacenters  string // Datacenters is the -datacenters list.
+	Racks        string // Racks is the -racks list.
+	NodesFrom    string // NodesFrom is the -nodes-from file.
+	AllDCs       bool   // AllDCs is -all-dcs.
+	ListDCs      bool   // ListDCs is -list-dcs.
+	ListNodes    bool   // ListNodes is -list-nodes.
+	LimitDCs     int    // LimitDCs is -limit-dcs.
+	Args         []string
+	WantArgs     int // WantArgs is the number of directories expected in Args, e.g. 2 with -diff.
 }
 
-// matchQuery returns true if the log entry matches the query.
-func matchQuery(entry *LogEntry, queries []string) bool {
-	if len(queries) == 0 {
+// validCommandLine returns true if the command line gives a nodetool status file, selects the nodes to process with
+// -datacenters, -all-dcs or another node filter, or lists them, and names the expected directories. With -input-json,
+// none of them is needed.
+func validCommandLine(c commandLine) bool {
+	if c.InputJSON != "" {
 		return true
 	}
+	selectsNodes := c.Datacenters != "" || c.AllDCs || c.Racks != "" || c.ListDCs || c.ListNodes || c.LimitDCs > 0 || c.NodesFrom != ""
+	return c.NodetoolFile != "" && selectsNodes && len(c.Args) == c.WantArgs
+}
+
+// stdinPath is the -file value reading the nodetool status output from stdin, e.g. nodetool status | wetlog -file -.
+const stdinPath = "-"
+
+// openNodetoolStatus opens the nodetool status output at path, or stdin if path is stdinPath. Closing stdin is left to
+// the process.
+func openNodetoolStatus(path string) (io.ReadCloser, error) {
+	if path == stdinPath {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path) //nosec G304
+}
 
-	textToSearch := entry.Message
+// openOutput creates the file at path receiving the results, or returns stdout if path is empty.
+func openOutput(path string) (*os.File, error) {
+	if path == "" {
+		return os.Stdout, nil
+	}
+	return os.Create(path) //nosec G304
+}
 
-	for _, query := range queries {
-		if strings.Contains(textToSearch, query) {
-			textToSearch = strings.SplitN(textToSearch, query, 2)[1]
-		} else {
-			return false
+// PrintDatacenters writes the datacenters in the nodetool status output to w.
+func PrintDatacenters(w io.Writer, nodes []Node) error {
+	if _, err := fmt.Fprintln(w, "Datacenters:"); err != nil {
+		return err
+	}
+	for _, dc := range pipeline.Datacenters(nodes) {
+		if _, err := fmt.Fprintln(w, dc); err != nil {
+			return err
 		}
 	}
-	return true
+	return nil
 }
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"io"
 	"os"
 	"path/filepath"
@@ -9,6 +12,9 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github/kenjords/wetlog/internal/pipeline"
+	"github/kenjords/wetlog/pkg/wetlog"
 )
 
 func TestPrintVersion(t *testing.T) {
@@ -21,256 +27,133 @@ func TestPrintVersion(t *testing.T) {
 	}
 }
 
-func TestParseNodetoolStatus(t *testing.T) {
-	testCases := []struct {
-		name      string
-		input     string
-		wantNodes []Node
-		wantError bool
-	}{
-		{
-			name:  "single node up",
-			input: "Datacenter: DC1\nUN 127.0.0.1\n",
-			wantNodes: []Node{
-				{Address: "127.0.0.1", Datacenter: "DC1"},
-			},
-			wantError: false,
-		},
-		{
-			name:  "multiple nodes",
-			input: "Datacenter: DC1\nUN 127.0.0.1\nDN 127.0.0.2\n",
-			wantNodes: []Node{
-				{Address: "127.0.0.1", Datacenter: "DC1"},
-				{Address: "127.0.0.2", Datacenter: "DC1"},
-			},
-			wantError: false,
-		},
-		{
-			name:  "multiple datacenters",
-			input: "Datacenter: DC1\nUN 127.0.0.1\nUN 127.0.0.2\nDatacenter: DC2\nUN 127.0.1.1\n",
-			wantNodes: []Node{
-				{Address: "127.0.0.1", Datacenter: "DC1"},
-				{Address: "127.0.0.2", Datacenter: "DC1"},
-				{Address: "127.0.1.1", Datacenter: "DC2"},
-			},
-			wantError: false,
-		},
-		{
-			name:  "node down, node up, node joining, node moving, node leaving",
-			input: "Datacenter: DC1\nDN 127.0.0.1\nUN 127.0.0.2\nUJ 127.0.0.3\nUM 127.0.0.4\nUL 127.0.0.5\n",
-			wantNodes: []Node{
-				{Address: "127.0.0.1", Datacenter: "DC1"},
-				{Address: "127.0.0.2", Datacenter: "DC1"},
-				{Address: "127.0.0.3", Datacenter: "DC1"},
-				{Address: "127.0.0.4", Datacenter: "DC1"},
-				{Address: "127.0.0.5", Datacenter: "DC1"},
-			},
-			wantError: false,
-		},
-		{
-			name:      "bad format",
-			input:     "bad input format\n",
-			wantNodes: nil,
-			wantError: true,
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			r := strings.NewReader(tc.input)
-			nodes, err := ParseNodetoolStatus(r)
-
-			if (err != nil) != tc.wantError {
-				t.Fatalf("parseNodetoolStatus() error = %v, wantErr %v", err, tc.wantError)
-			}
+func TestOpenNodetoolStatus(t *testing.T) {
+	status := "Datacenter: DC1\nUN  127.0.0.1  1.2 GiB  256  ?  rack1\nDN  127.0.0.2  1.1 GiB  256  ?  rack1\n"
+	path := filepath.Join(t.TempDir(), "status.txt")
+	if err := os.WriteFile(path, []byte(status), 0o600); err != nil {
+		t.Fatal(err)
+	}
 
-			if !reflect.DeepEqual(nodes, tc.wantNodes) {
-				t.Errorf("parseNodetoolStatus() = %v, want %v", nodes, tc.wantNodes)
-			}
-		})
+	// "-" reads the status piped to stdin
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
 	}
-}
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() { os.Stdin = stdin })
+	go func() {
+		_, _ = io.WriteString(w, status)
+		w.Close()
+	}()
 
-func TestParseDate(t *testing.T) {
-	testCases := []struct {
-		name    string
-		input   string
-		want    time.Time
-		wantErr bool
-	}{
-		{
-			name:    "valid date",
-			input:   "2023-07-13 12:01:01,000",
-			want:    time.Date(2023, 7, 13, 12, 0o1, 0o1, 0, time.UTC),
-			wantErr: false,
-		},
-		{
-			name:    "invalid date",
-			input:   "2023-13-07 12:01:01,000",
-			want:    time.Time{},
-			wantErr: true,
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			got, err := ParseDate(tc.input)
-
-			if (err != nil) != tc.wantErr {
-				t.Fatalf("parseDate() error = %v, wantErr %v", err, tc.wantErr)
-			}
+	for _, source := range []string{stdinPath, path} {
+		file, err := openNodetoolStatus(source)
+		if err != nil {
+			t.Fatalf("openNodetoolStatus(%q) error = %v", source, err)
+		}
+		nodes, err := wetlog.ParseNodetoolStatus(file)
+		if err != nil {
+			t.Fatalf("ParseNodetoolStatus() error = %v", err)
+		}
+		if err := file.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if len(nodes) != 2 || nodes[0].Address != "127.0.0.1" || nodes[1].Status != "DN" {
+			t.Errorf("Unexpected nodes read from %q: %+v", source, nodes)
+		}
+	}
+	if _, err := r.Stat(); err != nil {
+		t.Errorf("Expected stdin to be left open, got %v", err)
+	}
 
-			if !got.Equal(tc.want) {
-				t.Errorf("parseDate() = %v, want %v", got, tc.want)
-			}
-		})
+	if _, err := openNodetoolStatus(filepath.Join(t.TempDir(), "missing.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected a missing file error, got %v", err)
 	}
 }
 
-func TestParseLogLevel(t *testing.T) {
-	testCases := []struct {
-		name    string
-		input   string
-		want    LogLevel
-		wantErr bool
+func TestValidCommandLine(t *testing.T) {
+	tests := []struct {
+		name string
+		c    commandLine
+		want bool
 	}{
-		{
-			name:    "DEBUG log level",
-			input:   "DEBUG",
-			want:    DEBUG,
-			wantErr: false,
-		},
-		{
-			name:    "INFO log level",
-			input:   "INFO",
-			want:    INFO,
-			wantErr: false,
-		},
-		{
-			name:    "WARN log level",
-			input:   "WARN",
-			want:    WARN,
-			wantErr: false,
-		},
-		{
-			name:    "ERROR log level",
-			input:   "ERROR",
-			want:    ERROR,
-			wantErr: false,
-		},
-		{
-			name:    "Invalid log level",
-			input:   "INVALID",
-			want:    0,
-			wantErr: true,
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			got, err := ParseLogLevel(tc.input)
-
-			if (err != nil) != tc.wantErr {
-				t.Fatalf("parseLogLevel() error = %v, wantErr %v", err, tc.wantErr)
-			}
-
-			if got != tc.want {
-				t.Errorf("parseLogLevel() = %v, want %v", got, tc.want)
+		{name: "datacenters", c: commandLine{NodetoolFile: "status.txt", Datacenters: "DC1", Args: []string{"bundle"}, WantArgs: 1}, want: true},
+		{name: "all datacenters", c: commandLine{NodetoolFile: "status.txt", AllDCs: true, Args: []string{"bundle"}, WantArgs: 1}, want: true},
+		{name: "racks", c: commandLine{NodetoolFile: "status.txt", Racks: "rack1", Args: []string{"bundle"}, WantArgs: 1}, want: true},
+		{name: "list nodes", c: commandLine{NodetoolFile: "status.txt", ListNodes: true, Args: []string{"bundle"}, WantArgs: 1}, want: true},
+		{name: "list datacenters", c: commandLine{NodetoolFile: "status.txt", ListDCs: true, Args: []string{"bundle"}, WantArgs: 1}, want: true},
+		{name: "all datacenters diff", c: commandLine{NodetoolFile: "status.txt", AllDCs: true, Args: []string{"before", "after"}, WantArgs: 2}, want: true},
+		{name: "input json", c: commandLine{InputJSON: "entries.json", WantArgs: 1}, want: true},
+		{name: "no node selection", c: commandLine{NodetoolFile: "status.txt", Args: []string{"bundle"}, WantArgs: 1}, want: false},
+		{name: "all datacenters without file", c: commandLine{AllDCs: true, Args: []string{"bundle"}, WantArgs: 1}, want: false},
+		{name: "all datacenters without directory", c: commandLine{NodetoolFile: "status.txt", AllDCs: true, WantArgs: 1}, want: false},
+		{name: "all datacenters without file or directory", c: commandLine{AllDCs: true, WantArgs: 1}, want: false},
+		{name: "too many directories", c: commandLine{NodetoolFile: "status.txt", AllDCs: true, Args: []string{"before", "after"}, WantArgs: 1}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validCommandLine(tt.c); got != tt.want {
+				t.Errorf("validCommandLine(%+v) = %v, want %v", tt.c, got, tt.want)
 			}
 		})
 	}
 }
 
-func TestProcessFile(t *testing.T) {
-	t.Helper()
-	node := Node{Address: "192.0.2.0"} // This IP address is a placeholder. It should be replaced with a valid IP.
-	topLevelDir := "test"
-	queries := []string{"INFO"}
+func TestOpenOutput(t *testing.T) {
+	entries := LogEntries{
+		{LogLevel: WARN, Date: time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC), LineNumber: 1, NodeIP: "10.0.0.1", FilePath: "system.log", Message: "WARN  [main] 2023-07-14 16:00:00,000 Server.java:10 - Slow query"},
+		{LogLevel: ERROR, Date: time.Date(2023, 7, 14, 16, 0, 1, 0, time.UTC), LineNumber: 2, NodeIP: "10.0.0.2", FilePath: "system.log", Message: "ERROR [main] 2023-07-14 16:00:01,000 Server.java:20 - Failed"},
+	}
+	write := func(f *os.File) {
+		t.Helper()
+		if _, err := writeEntries(context.Background(), bufio.NewWriter(f), entries, FormatOptions{}, nil); err != nil {
+			t.Fatalf("writeEntries() error = %v", err)
+		}
+	}
 
-	// Create the necessary path and file for the test
-	testPath := filepath.Join(topLevelDir, "nodes", node.Address, "logs", "cassandra")
-	err := os.MkdirAll(testPath, os.ModePerm)
+	// no path writes to stdout
+	r, w, err := os.Pipe()
 	if err != nil {
-		t.Fatalf("Couldn't create path: %v", err)
+		t.Fatal(err)
 	}
-
-	// Write sample data to the system.log file
-	testFilePath := filepath.Join(testPath, "system.log")
-	err = os.WriteFile(testFilePath, []byte("Sample log data"), 0o644)
+	stdout := os.Stdout
+	os.Stdout = w
+	t.Cleanup(func() { os.Stdout = stdout })
+	out, err := openOutput("")
 	if err != nil {
-		t.Fatalf("Couldn't write to file: %v", err)
+		t.Fatalf("openOutput() error = %v", err)
 	}
-
-	// Make sure to clean up after test
-	defer func() {
-		err := os.RemoveAll(filepath.Join(topLevelDir, "nodes"))
-		if err != nil {
-			t.Errorf("Couldn't clean up test files: %v", err)
-		}
-	}()
-
-	logEntryChan := make(chan *LogEntry)
-	errChan := make(chan error)
-
-	go func() {
-		err := ProcessFile(node, topLevelDir, queries, logEntryChan)
-		if err != nil {
-			errChan <- err
-		}
-		close(logEntryChan)
-	}()
-
-	// Check whether it sends LogEntry to the channel correctly.
-	for logEntry := range logEntryChan {
-		if logEntry.Message == "" {
-			t.Fatalf("Expected message in log entry, got empty")
-		}
+	if out != w {
+		t.Fatalf("Expected stdout without a path")
+	}
+	write(out)
+	w.Close()
+	want, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	// Check for errors
-	select {
-	case err := <-errChan:
-		t.Fatalf("ProcessFile() error = %v", err)
-	default:
+	path := filepath.Join(t.TempDir(), "results.log")
+	out, err = openOutput(path)
+	if err != nil {
+		t.Fatalf("openOutput(%q) error = %v", path, err)
+	}
+	write(out)
+	if err := out.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) == 0 || !bytes.Equal(got, want) {
+		t.Errorf("Expected the file to hold the stdout output:\n%s\nGot:\n%s", want, got)
 	}
-}
 
-func TestProcessLine(t *testing.T) {
-	// Define test cases
-	testCases := []struct {
-		line      string
-		lineNum   int
-		filePath  string
-		expectErr bool
-	}{
-		{
-			line:      "INFO  [Solr TTL scheduler-0] 2023-07-05 13:03:37,128  AbstractSolrSecondaryIndex.java:1964 - Expired 3 documents in 18 milliseconds for core poms_om_search.om_mail_order_by_customer",
-			lineNum:   1,
-			filePath:  "testFilePath",
-			expectErr: false,
-		},
-		{
-			line:      "WARN  2023-04-24 12:12:32,430 org.apache.hadoop.hive.conf.HiveConf: HiveConf hive.server2.thrift.http.port expects INT type value",
-			lineNum:   2,
-			filePath:  "testFilePath",
-			expectErr: false,
-		},
-		{
-			line:      "INVALID  [Solr TTL scheduler-0] 2023-07-05 13:03:37,128  AbstractSolrSecondaryIndex.java:1964 - Expired 3 documents in 18 milliseconds for core poms_om_search.om_mail_order_by_customer",
-			lineNum:   3,
-			filePath:  "testFilePath",
-			expectErr: true, // expect error due to invalid log level
-		},
-	}
-
-	for i, testCase := range testCases {
-		_, err := ProcessLine(testCase.line, testCase.lineNum, testCase.filePath)
-
-		if err != nil && !testCase.expectErr {
-			t.Errorf("Test case %d: unexpected error: %v", i+1, err)
-		} else if err == nil && testCase.expectErr {
-			t.Errorf("Test case %d: expected error but got none", i+1)
-		}
+	if _, err := openOutput(filepath.Join(t.TempDir(), "missing", "results.log")); !os.IsNotExist(err) {
+		t.Errorf("Expected a missing directory error, got %v", err)
 	}
 }
 
@@ -285,151 +168,32 @@ func TestPrintDatacenters(t *testing.T) {
 
 	expectedOutput := "Datacenters:\nDC1\nDC2\nDC3\n"
 
-	// Create a pipe for capturing the output
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	// Call the function
-	PrintDatacenters(nodes)
-
-	// Reset the standard output
-	err := w.Close()
-	if err != nil {
-		t.Fatalf("Couldn't close pipe: %v", err)
+	var buf bytes.Buffer
+	if err := PrintDatacenters(&buf, nodes); err != nil {
+		t.Fatalf("PrintDatacenters() error = %v", err)
 	}
-	os.Stdout = os.NewFile(1, "")
-
-	// Read the captured output from the pipe
-	capturedOutput, _ := io.ReadAll(r)
-	output := string(capturedOutput)
-
-	if output != expectedOutput {
-		t.Errorf("Expected output:\n%s\nGot:\n%s", expectedOutput, output)
+	if buf.String() != expectedOutput {
+		t.Errorf("Expected output:\n%s\nGot:\n%s", expectedOutput, buf.String())
 	}
-}
 
-func TestFilterNodesByDatacenters(t *testing.T) {
-	// Define test nodes and datacenters
-	nodes := []Node{
-		{"192.168.1.1", "dc1"},
-		{"192.168.1.2", "dc1"},
-		{"192.168.1.3", "dc2"},
-		{"192.168.1.4", "dc3"},
-		{"192.168.1.5", "dc4"},
+	// -list-dcs -out writes them to the file
+	path := filepath.Join(t.TempDir(), "dcs.txt")
+	out, err := openOutput(path)
+	if err != nil {
+		t.Fatalf("openOutput() error = %v", err)
 	}
-	datacenters := []string{"dc1", "dc3"}
-
-	// Run the filter function
-	result := filterNodesByDatacenters(nodes, datacenters)
-
-	// Expected result
-	expected := []Node{
-		{"192.168.1.1", "dc1"},
-		{"192.168.1.2", "dc1"},
-		{"192.168.1.4", "dc3"},
+	if err := PrintDatacenters(out, nodes); err != nil {
+		t.Fatalf("PrintDatacenters() error = %v", err)
 	}
-
-	// Check if result matches expected
-	if !reflect.DeepEqual(result, expected) {
-		t.Errorf("filterNodesByDatacenters() = %v, want %v", result, expected)
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
 	}
-}
-
-func TestStartsWithLogLevel(t *testing.T) {
-	tests := []struct {
-		name     string
-		line     string
-		expected bool
-	}{
-		{
-			name:     "Line starts with log level",
-			line:     "INFO  [main] 2023-07-14 16:00:00,658 YamlConfigurationLoader.java:89 - Configuration location: file:/etc/cassandra/cassandra.yaml",
-			expected: true,
-		},
-		{
-			name:     "Line does not start with log level",
-			line:     "[main] 2023-07-14 16:00:00,658 YamlConfigurationLoader.java:89 - Configuration location: file:/etc/cassandra/cassandra.yaml",
-			expected: false,
-		},
-		{
-			name:     "Empty line",
-			line:     "",
-			expected: false,
-		},
-		{
-			name:     "Line starts with non-word characters",
-			line:     "# INFO 2023-07-14 16:00:00,658 YamlConfigurationLoader.java:89 - Configuration location: file:/etc/cassandra/cassandra.yaml",
-			expected: false,
-		},
-	}
-
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			if got := startsWithLogLevel(test.line); got != test.expected {
-				t.Errorf("startsWithLogLevel() = %v, want %v", got, test.expected)
-			}
-		})
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
 	}
-}
-
-func TestMatchQuery(t *testing.T) {
-	t.Parallel()
-
-	testCases := []struct {
-		name     string
-		entry    *LogEntry
-		queries  []string
-		expected bool
-	}{
-		{
-			name: "No Queries",
-			entry: &LogEntry{
-				Message: "No queries to match",
-			},
-			queries:  []string{},
-			expected: true,
-		},
-		{
-			name: "Single Match",
-			entry: &LogEntry{
-				Message: "This is a test message",
-			},
-			queries:  []string{"test"},
-			expected: true,
-		},
-		{
-			name: "Single Non-Match",
-			entry: &LogEntry{
-				Message: "This is a test message",
-			},
-			queries:  []string{"non-match"},
-			expected: false,
-		},
-		{
-			name: "Multiple Matches",
-			entry: &LogEntry{
-				Message: "This is a test message with multiple queries to match",
-			},
-			queries:  []string{"test", "message", "multiple", "queries"},
-			expected: true,
-		},
-		{
-			name: "Multiple Queries with Non-Match",
-			entry: &LogEntry{
-				Message: "This is a test message with multiple queries to match",
-			},
-			queries:  []string{"test", "non-match"},
-			expected: false,
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			actual := matchQuery(tc.entry, tc.queries)
-			if actual != tc.expected {
-				t.Errorf("expected %v, got %v", tc.expected, actual)
-			}
-		})
+	if string(content) != expectedOutput {
+		t.Errorf("Expected the file to hold:\n%s\nGot:\n%s", expectedOutput, content)
 	}
 }
 
@@ -464,7 +228,7 @@ func TestLogEntriesSorting(t *testing.T) {
 	entries := LogEntries{entry1, entry2, entry3}
 
 	// Sort by date
-	sort.Sort(ByDate{entries})
+	sort.Sort(ByDate{LogEntries: entries})
 	expectedDates := []time.Time{entry3.Date, entry1.Date, entry2.Date}
 	for i, entry := range entries {
 		if !reflect.DeepEqual(entry.Date, expectedDates[i]) {
@@ -473,7 +237,7 @@ func TestLogEntriesSorting(t *testing.T) {
 	}
 
 	// Sort by log level
-	sort.Sort(ByLogLevel{entries})
+	sort.Sort(ByLogLevel{LogEntries: entries})
 	expectedLevels := []LogLevel{1, 2, 3}
 	for i, entry := range entries {
 		if entry.LogLevel != expectedLevels[i] {
@@ -482,7 +246,7 @@ func TestLogEntriesSorting(t *testing.T) {
 	}
 
 	// Sort by line number
-	sort.Sort(ByLineNumber{entries})
+	sort.Sort(ByLineNumber{LogEntries: entries})
 	expectedLineNumbers := []int{5, 10, 15}
 	for i, entry := range entries {
 		if entry.LineNumber != expectedLineNumbers[i] {
@@ -491,7 +255,7 @@ func TestLogEntriesSorting(t *testing.T) {
 	}
 
 	// Sort by node IP
-	sort.Sort(ByNodeIP{entries})
+	sort.Sort(ByNodeIP{LogEntries: entries})
 	expectedIPs := []string{"192.168.1.3", "192.168.1.5", "192.168.1.10"}
 	for i, entry := range entries {
 		if entry.NodeIP != expectedIPs[i] {
@@ -500,162 +264,92 @@ func TestLogEntriesSorting(t *testing.T) {
 	}
 }
 
-// TestLen tests the Len() method of the LogEntries.
-func TestLen(t *testing.T) {
-	entry1 := &LogEntry{
-		LogLevel:   DEBUG,
-		Date:       time.Date(2023, 7, 14, 0, 0, 0, 0, time.UTC),
-		LineNumber: 1,
-		NodeIP:     "192.168.1.1",
-		FilePath:   "/var/log/test.log",
-		Message:    "Debug message 1",
-	}
-	entry2 := &LogEntry{
-		LogLevel:   INFO,
-		Date:       time.Date(2023, 7, 14, 1, 0, 0, 0, time.UTC),
-		LineNumber: 2,
-		NodeIP:     "192.168.1.2",
-		FilePath:   "/var/log/test.log",
-		Message:    "Info message 2",
-	}
-	logEntries := LogEntries{entry2, entry1}
-
-	if logEntries.Len() != 2 {
-		t.Fatalf("Expected Len() to return 2, but got %v", logEntries.Len())
-	}
-}
-
-// TestSwap tests the Swap() method of the LogEntries.
-func TestSwap(t *testing.T) {
-
-	entry1 := &LogEntry{
-		LogLevel:   DEBUG,
-		Date:       time.Date(2023, 7, 14, 0, 0, 0, 0, time.UTC),
-		LineNumber: 1,
-		NodeIP:     "192.168.1.1",
-		FilePath:   "/var/log/test.log",
-		Message:    "Debug message 1",
-	}
-	entry2 := &LogEntry{
-		LogLevel:   INFO,
-		Date:       time.Date(2023, 7, 14, 1, 0, 0, 0, time.UTC),
-		LineNumber: 2,
-		NodeIP:     "192.168.1.2",
-		FilePath:   "/var/log/test.log",
-		Message:    "Info message 2",
+// writeSystemLog writes content to the system.log of the node with the given address under topLevelDir.
+func writeSystemLog(t *testing.T, topLevelDir, address, content string) string {
+	t.Helper()
+	logDir := filepath.Join(topLevelDir, "nodes", address, "logs", "cassandra")
+	if err := os.MkdirAll(logDir, os.ModePerm); err != nil {
+		t.Fatalf("Couldn't create path: %v", err)
 	}
-
-	logEntries := LogEntries{entry2, entry1}
-	logEntries.Swap(0, 1)
-	if logEntries[0].Message != "Debug message 1" || logEntries[1].Message != "Info message 2" {
-		t.Fatalf("Swap() did not swap the entries correctly")
+	logFile := filepath.Join(logDir, "system.log")
+	if err := os.WriteFile(logFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("Couldn't write to file: %v", err)
 	}
+	return logFile
 }
 
-// TestByDate tests the sorting of LogEntries by date.
-func TestByDate(t *testing.T) {
-
-	entry1 := &LogEntry{
-		LogLevel:   DEBUG,
-		Date:       time.Date(2023, 7, 14, 0, 0, 0, 0, time.UTC),
-		LineNumber: 1,
-		NodeIP:     "192.168.1.1",
-		FilePath:   "/var/log/test.log",
-		Message:    "Debug message 1",
-	}
-	entry2 := &LogEntry{
-		LogLevel:   INFO,
-		Date:       time.Date(2023, 7, 14, 1, 0, 0, 0, time.UTC),
-		LineNumber: 2,
-		NodeIP:     "192.168.1.2",
-		FilePath:   "/var/log/test.log",
-		Message:    "Info message 2",
-	}
+// TestByNodeIPMixedAddresses tests that hostnames consistently sort after valid IPs.
+func TestByNodeIPMixedAddresses(t *testing.T) {
+	addrs := []string{"node-b.example.com", "10.0.0.10", "::1", "node-a.example.com", "10.0.0.2", "192.168.1.1"}
+	want := []string{"::1", "10.0.0.2", "10.0.0.10", "192.168.1.1", "node-a.example.com", "node-b.example.com"}
 
-	logEntries := LogEntries{entry2, entry1}
-	sort.Sort(ByDate{logEntries})
-	if !logEntries[0].Date.Before(logEntries[1].Date) {
-		t.Fatalf("ByDate sort failed")
+	newEntries := func() LogEntries {
+		var entries LogEntries
+		for _, addr := range addrs {
+			entries = append(entries, &LogEntry{NodeIP: addr})
+		}
+		return entries
 	}
-}
 
-// TestByLogLevel tests the sorting of LogEntries by log level.
-func TestByLogLevel(t *testing.T) {
+	byNodeIP := newEntries()
+	sort.Sort(ByNodeIP{LogEntries: byNodeIP})
+	parsedOnce := newEntries()
+	sortWith(newNodeIPSorter, false)(parsedOnce)
 
-	entry1 := &LogEntry{
-		LogLevel:   DEBUG,
-		Date:       time.Date(2023, 7, 14, 0, 0, 0, 0, time.UTC),
-		LineNumber: 1,
-		NodeIP:     "192.168.1.1",
-		FilePath:   "/var/log/test.log",
-		Message:    "Debug message 1",
-	}
-	entry2 := &LogEntry{
-		LogLevel:   INFO,
-		Date:       time.Date(2023, 7, 14, 1, 0, 0, 0, time.UTC),
-		LineNumber: 2,
-		NodeIP:     "192.168.1.2",
-		FilePath:   "/var/log/test.log",
-		Message:    "Info message 2",
-	}
-
-	logEntries := LogEntries{entry2, entry1}
-	sort.Sort(ByLogLevel{logEntries})
-	if logEntries[0].LogLevel > logEntries[1].LogLevel {
-		t.Fatalf("ByLogLevel sort failed")
+	for i := range want {
+		if byNodeIP[i].NodeIP != want[i] {
+			t.Errorf("ByNodeIP: expected %s at index %d, got %s", want[i], i, byNodeIP[i].NodeIP)
+		}
+		if parsedOnce[i].NodeIP != want[i] {
+			t.Errorf("newNodeIPSorter: expected %s at index %d, got %s", want[i], i, parsedOnce[i].NodeIP)
+		}
 	}
 }
 
-// TestByLineNumber tests the sorting of LogEntries by line number.
-func TestByLineNumber(t *testing.T) {
-
-	entry1 := &LogEntry{
-		LogLevel:   DEBUG,
-		Date:       time.Date(2023, 7, 14, 0, 0, 0, 0, time.UTC),
-		LineNumber: 1,
-		NodeIP:     "192.168.1.1",
-		FilePath:   "/var/log/test.log",
-		Message:    "Debug message 1",
-	}
-	entry2 := &LogEntry{
-		LogLevel:   INFO,
-		Date:       time.Date(2023, 7, 14, 1, 0, 0, 0, time.UTC),
-		LineNumber: 2,
-		NodeIP:     "192.168.1.2",
-		FilePath:   "/var/log/test.log",
-		Message:    "Info message 2",
-	}
-
-	logEntries := LogEntries{entry2, entry1}
-	sort.Sort(ByLineNumber{logEntries})
-	if logEntries[0].LineNumber > logEntries[1].LineNumber {
-		t.Fatalf("ByLineNumber sort failed")
+func TestSortWithReverse(t *testing.T) {
+	base := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	nodes := []Node{{Address: "10.0.0.1", LoadBytes: 1 << 30}, {Address: "10.0.0.2", LoadBytes: 2 << 30}, {Address: "10.0.0.10", LoadBytes: 3 << 30}}
+	newEntries := func() LogEntries {
+		return LogEntries{
+			{Date: base.Add(time.Minute), LogLevel: WARN, LineNumber: 2, NodeIP: "10.0.0.2", LineCount: 1, Message: "timeout", Metrics: map[string]float64{"gc_pause_ms": 300}},
+			{Date: base, LogLevel: DEBUG, LineNumber: 1, NodeIP: "10.0.0.10", LineCount: 3, Message: "timeout timeout", Metrics: map[string]float64{"gc_pause_ms": 500}},
+			{Date: base.Add(2 * time.Minute), LogLevel: ERROR, LineNumber: 3, NodeIP: "10.0.0.1", LineCount: 2, Message: "ok", Metrics: map[string]float64{"gc_pause_ms": 100}},
+		}
 	}
-}
 
-// TestByNodeIP tests the sorting of LogEntries by node IP.
-func TestByNodeIP(t *testing.T) {
+	timeoutScore := func(message string) int { return relevanceScore(message, []string{"timeout"}, false) }
 
-	entry1 := &LogEntry{
-		LogLevel:   DEBUG,
-		Date:       time.Date(2023, 7, 14, 0, 0, 0, 0, time.UTC),
-		LineNumber: 1,
-		NodeIP:     "192.168.1.1",
-		FilePath:   "/var/log/test.log",
-		Message:    "Debug message 1",
-	}
-	entry2 := &LogEntry{
-		LogLevel:   INFO,
-		Date:       time.Date(2023, 7, 14, 1, 0, 0, 0, time.UTC),
-		LineNumber: 2,
-		NodeIP:     "192.168.1.2",
-		FilePath:   "/var/log/test.log",
-		Message:    "Info message 2",
-	}
-
-	logEntries := LogEntries{entry2, entry1}
-	sort.Sort(ByNodeIP{logEntries})
-	if logEntries[0].NodeIP > logEntries[1].NodeIP {
-		t.Fatalf("ByNodeIP sort failed")
+	tests := []struct {
+		name      string
+		newSorter func(LogEntries) sort.Interface
+		key       func(*LogEntry) float64
+	}{
+		{name: "date", newSorter: func(e LogEntries) sort.Interface { return ByDate{LogEntries: e} }, key: func(e *LogEntry) float64 { return float64(e.Date.Unix()) }},
+		{name: "loglevel", newSorter: func(e LogEntries) sort.Interface { return ByLogLevel{LogEntries: e} }, key: func(e *LogEntry) float64 { return float64(e.LogLevel) }},
+		{name: "linenumber", newSorter: func(e LogEntries) sort.Interface { return ByLineNumber{LogEntries: e} }, key: func(e *LogEntry) float64 { return float64(e.LineNumber) }},
+		// the entries of the lowest IPs and least loaded nodes have the highest line numbers
+		{name: "nodeip", newSorter: newNodeIPSorter, key: func(e *LogEntry) float64 { return -float64(e.LineNumber) }},
+		{name: "linecount", newSorter: func(e LogEntries) sort.Interface { return ByLineCount{LogEntries: e} }, key: func(e *LogEntry) float64 { return float64(e.LineCount) }},
+		{name: "load", newSorter: func(e LogEntries) sort.Interface { return newLoadSorter(e, nodes) }, key: func(e *LogEntry) float64 { return -float64(e.LineNumber) }},
+		// relevance is already descending, reversing it puts the least relevant first
+		{name: "relevance", newSorter: func(e LogEntries) sort.Interface { return newRelevanceSorter(e, timeoutScore) }, key: func(e *LogEntry) float64 { return -float64(strings.Count(e.Message, "timeout")) }},
+		{name: "metric", newSorter: func(e LogEntries) sort.Interface { return pipeline.ByMetric{LogEntries: e, Name: "gc_pause_ms"} }, key: func(e *LogEntry) float64 { return e.Metrics["gc_pause_ms"] }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ascending := newEntries()
+			sortWith(tt.newSorter, false)(ascending)
+			descending := newEntries()
+			sortWith(tt.newSorter, true)(descending)
+			for i := range descending {
+				if i > 0 && tt.key(descending[i-1]) < tt.key(descending[i]) {
+					t.Errorf("Expected descending order, got %v before %v", tt.key(descending[i-1]), tt.key(descending[i]))
+				}
+				if got, want := descending[i].LineNumber, ascending[len(ascending)-1-i].LineNumber; got != want {
+					t.Errorf("Expected line %d at index %d of the reversed sort, got %d", want, i, got)
+				}
+			}
+		})
 	}
 }
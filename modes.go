@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"sync"
+	"syscall"
+	"time"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+// scanSetup is the scan the modes of main share: the selected nodes, how their logs are read and matched, and the
+// filters their entries go through once collected.
+type scanSetup struct {
+	ctx         context.Context             // ctx is cancelled when the user interrupts.
+	nodes       []Node                      // nodes are the selected nodes.
+	topLevelDir string                      // topLevelDir is the directory holding the logs of the nodes.
+	queries     []string                    // queries are the -query terms searched as substrings.
+	opts        pipeline.ScanOptions        // opts controls how the logs are read and matched.
+	timeout     time.Duration               // timeout is the -timeout after which the scan stops, 0 for none.
+	filter      func(LogEntries) LogEntries // filter applies the filters that work on collected entries.
+	progress    *progressBar                // progress is the -progress-bar, nil if none is drawn.
+	tail        int                         // tail is the -tail number of most recent entries kept, 0 for all.
+}
+
+// checkScan exits with status 130 if the user interrupted the scan, since the modes print results computed from every
+// node, which an interrupted scan leaves incomplete. If ctx hit -timeout, it notes that only the collected entries are
+// reported.
+func (s scanSetup) checkScan(ctx context.Context, collected int) {
+	s.progress.Finish()
+	if s.ctx.Err() != nil {
+		log.Printf("Interrupted while scanning logs, no results were printed")
+		syscall.Exit(130)
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		log.Print(timeoutNotice(s.timeout, collected))
+	}
+}
+
+// collect collects the filtered entries of each node under dir read with opts, within -timeout.
+func (s scanSetup) collect(dir string, queries []string, opts pipeline.ScanOptions) map[string]LogEntries {
+	ctx, cancel := pipeline.WithScanTimeout(s.ctx, s.timeout)
+	defer cancel()
+	nodeEntries := pipeline.CollectNodeEntries(ctx, s.nodes, dir, queries, opts)
+	s.checkScan(ctx, pipeline.CountNodeEntries(nodeEntries))
+	return pipeline.FilterNodeEntries(nodeEntries, s.filter)
+}
+
+// runDiff writes to w the messages that only appear in the logs under the top-level directory or under afterDir,
+// whose node directories are given by afterNodeDirs, see -diff.
+func runDiff(s scanSetup, w io.Writer, afterDir string, afterNodeDirs map[string]string) error {
+	ctx, cancel := pipeline.WithScanTimeout(s.ctx, s.timeout)
+	defer cancel()
+	afterOpts := s.opts
+	afterOpts.NodeDirs = afterNodeDirs
+	before := pipeline.CollectNodeEntries(ctx, s.nodes, s.topLevelDir, s.queries, s.opts)
+	after := pipeline.CollectNodeEntries(ctx, s.nodes, afterDir, s.queries, afterOpts)
+	s.checkScan(ctx, pipeline.CountNodeEntries(before)+pipeline.CountNodeEntries(after))
+	return PrintDiff(w, DiffBundles(pipeline.FilterNodeEntries(before, s.filter), pipeline.FilterNodeEntries(after, s.filter)))
+}
+
+// runGaps writes to w the periods longer than threshold in which a node logged nothing, see -gaps.
+func runGaps(s scanSetup, w io.Writer, threshold time.Duration) error {
+	return PrintGaps(w, s.collect(s.topLevelDir, s.queries, s.opts), threshold)
+}
+
+// runRestartLoops writes to w the nodes that started at least threshold times within window, see -restart-loops.
+func runRestartLoops(s scanSetup, w io.Writer, window time.Duration, threshold int) error {
+	// the startup banners are looked for in every entry, whatever the query
+	opts := s.opts
+	opts.Matchers = nil
+	return PrintRestartLoops(w, s.collect(s.topLevelDir, nil, opts), window, threshold)
+}
+
+// runGroupByNode writes the entries of each node to w under a header, see -group-by-node.
+func runGroupByNode(s scanSetup, w io.Writer, formatOpts FormatOptions) error {
+	return PrintNodeGroups(w, s.collect(s.topLevelDir, s.queries, s.opts), formatOpts)
+}
+
+// runMatchPreview writes the number of entries of each node that would be printed to w, see -match-preview.
+func runMatchPreview(s scanSetup, w io.Writer) error {
+	ctx, cancel := pipeline.WithScanTimeout(s.ctx, s.timeout)
+	defer cancel()
+	filter := func(entries LogEntries) LogEntries { return pipeline.TailEntries(s.filter(entries), s.tail) }
+	counts := previewMatches(ctx, s.nodes, s.topLevelDir, s.queries, s.opts, filter)
+	total := 0
+	for _, count := range counts {
+		total += count.Count
+	}
+	s.checkScan(ctx, total)
+	return PrintMatchPreview(w, counts)
+}
+
+// runZeroNodes writes to w the nodes left without entries once filtered, see -zero-nodes, followed by the nodes whose
+// logs weren't read whole before -timeout.
+func runZeroNodes(s scanSetup, w io.Writer) error {
+	ctx, cancel := pipeline.WithScanTimeout(s.ctx, s.timeout)
+	defer cancel()
+	var mu sync.Mutex
+	scanned := make(map[string]bool, len(s.nodes))
+	opts := s.opts
+	opts.NodeDone = func(node Node) {
+		if s.opts.NodeDone != nil {
+			s.opts.NodeDone(node)
+		}
+		if ctx.Err() == nil {
+			mu.Lock()
+			scanned[node.Address] = true
+			mu.Unlock()
+		}
+	}
+	nodeEntries := pipeline.CollectNodeEntries(ctx, s.nodes, s.topLevelDir, s.queries, opts)
+	s.checkScan(ctx, pipeline.CountNodeEntries(nodeEntries))
+
+	unscanned := make(map[string]bool)
+	for _, node := range s.nodes {
+		if !scanned[node.Address] {
+			unscanned[node.Address] = true
+		}
+	}
+	nodeEntries = pipeline.FilterNodeEntries(nodeEntries, s.filter)
+	return PrintZeroNodes(w, findZeroNodes(s.nodes, s.topLevelDir, s.opts, nodeEntries, unscanned))
+}
+
+// runMergeSort writes the filtered entries to w sorted by date with an external merge sort keeping about budget bytes
+// of entries in memory, see -merge-sort-buffer, and flushes w. It returns the number of entries written and
+// collected, along with context.DeadlineExceeded after -timeout.
+func runMergeSort(s scanSetup, w *bufio.Writer, budget int, formatOpts FormatOptions, limit *throttle) (written, collected int, err error) {
+	if err := writeHeader(w, formatOpts); err != nil {
+		return 0, 0, err
+	}
+	err = externalSortEntries(s.ctx, s.nodes, s.topLevelDir, s.queries, s.opts, s.timeout, "", budget, s.filter, func(entry *LogEntry) error {
+		collected++
+		if s.ctx.Err() != nil {
+			return s.ctx.Err()
+		}
+		ok, err := writeEntry(s.ctx, w, entry, formatOpts, limit)
+		if ok {
+			written++
+		}
+		return err
+	})
+	if flushErr := w.Flush(); flushErr != nil {
+		return written, collected, flushErr
+	}
+	return written, collected, err
+}
+
+// reportModes holds the modes that print a report of the collected entries instead of the entries.
+type reportModes struct {
+	Count         bool           // Count prints the counts of the entries per level and node, see -count.
+	ListSources   bool           // ListSources prints the distinct source files of the entries, see -list-sources.
+	IgnoreCaseIDs bool           // IgnoreCaseIDs matches the source files ignoring case, see -ignore-case-dc-and-node.
+	SummaryOnly   bool           // SummaryOnly prints the JSON summary of the entries, see -summary-json-only.
+	Correlate     *regexp.Regexp // Correlate groups the entries into traces by correlation ID, see -correlate.
+	SlowTraces    time.Duration  // SlowTraces prints the traces lasting at least this long instead, see -slow-traces.
+	BucketDetail  time.Duration  // BucketDetail prints the entries per time bucket of this width, see -bucket-detail.
+	BucketSamples int            // BucketSamples is the number of sample entries per bucket, see -bucket-samples.
+}
+
+// runReport writes to w the report of the entries the first selected mode of m prints, buffering the longer ones
+// through bufferSize bytes. It returns false without writing anything if m selects none.
+func runReport(w io.Writer, entries LogEntries, m reportModes, formatOpts FormatOptions, bufferSize int) (bool, error) {
+	switch {
+	case m.Count:
+		return true, summarize(entries).Write(w)
+	case m.ListSources:
+		return true, PrintSources(w, countSources(entries, m.IgnoreCaseIDs))
+	case m.SummaryOnly:
+		return true, writeSummaryOnly(w, entries)
+	case m.Correlate != nil:
+		return true, writeBuffered(w, bufferSize, func(w io.Writer) error {
+			traces := correlateEntries(entries, m.Correlate)
+			if m.SlowTraces > 0 {
+				return PrintLatencies(w, slowTraces(traces, m.SlowTraces))
+			}
+			return PrintTraces(w, traces, formatOpts)
+		})
+	case m.BucketDetail > 0:
+		return true, writeBuffered(w, bufferSize, func(w io.Writer) error {
+			return PrintBucketDetail(w, bucketEntries(entries, m.BucketDetail), m.BucketSamples, formatOpts)
+		})
+	default:
+		return false, nil
+	}
+}
+
+// timeoutNotice describes the partial results of a scan stopped by -timeout.
+func timeoutNotice(timeout time.Duration, collected int) string {
+	return fmt.Sprintf("Scan timed out after %s, partial results: %d entries collected", timeout, collected)
+}
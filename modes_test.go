@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+// newModeSetup returns the scan setup of two nodes whose logs hold an INFO and a WARN entry a minute apart, and a
+// third node without a log, filtered by filter.
+func newModeSetup(t *testing.T, filter func(LogEntries) LogEntries) scanSetup {
+	t.Helper()
+	topLevelDir := t.TempDir()
+	content := "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n" +
+		"WARN  [main] 2023-07-14 16:01:00,000 Server.java:10 - Slow\n"
+	writeSystemLog(t, topLevelDir, "10.0.0.1", content)
+	writeSystemLog(t, topLevelDir, "10.0.0.2", "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n")
+	return scanSetup{
+		ctx:         context.Background(),
+		nodes:       []Node{{Address: "10.0.0.1"}, {Address: "10.0.0.2"}, {Address: "10.0.0.3"}},
+		topLevelDir: topLevelDir,
+		filter:      filter,
+	}
+}
+
+var warnOnly = pipeline.EntryFilters{MinLevel: WARN}.Apply
+
+func TestRunZeroNodesFiltered(t *testing.T) {
+	var buf bytes.Buffer
+	if err := runZeroNodes(newModeSetup(t, warnOnly), &buf); err != nil {
+		t.Fatalf("runZeroNodes() error = %v", err)
+	}
+	want := "10.0.0.2: no matching entries\n10.0.0.3: log file missing\n"
+	if buf.String() != want {
+		t.Errorf("runZeroNodes() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRunGroupByNodeFiltered(t *testing.T) {
+	var buf bytes.Buffer
+	if err := runGroupByNode(newModeSetup(t, warnOnly), &buf, FormatOptions{Format: FormatText}); err != nil {
+		t.Fatalf("runGroupByNode() error = %v", err)
+	}
+	if got := buf.String(); !strings.Contains(got, "Slow") || strings.Contains(got, "Starting") {
+		t.Errorf("Expected only the WARN entry, got:\n%s", got)
+	}
+}
+
+func TestRunGroupByNodeConcurrency(t *testing.T) {
+	topLevelDir := t.TempDir()
+	var nodes []Node
+	for i := 0; i < 18; i++ {
+		node := Node{Address: fmt.Sprintf("10.0.%d.%d", i%3, i), Datacenter: fmt.Sprintf("DC%d", i%3+1)}
+		nodes = append(nodes, node)
+		writeSystemLog(t, topLevelDir, node.Address, "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n")
+	}
+
+	const concurrency, perDC = 3, 2
+	var mu sync.Mutex
+	active := make(map[string]int)
+	total, maxTotal, maxPerDC := 0, 0, 0
+	opts := pipeline.ScanOptions{
+		Concurrency:      concurrency,
+		PerDCConcurrency: perDC,
+		// a node starts being processed at its entry and is done once NodeDone is called
+		Matchers: []pipeline.Matcher{{
+			Name: "track",
+			Match: func(entry *LogEntry) bool {
+				mu.Lock()
+				active[entry.Datacenter]++
+				total++
+				if active[entry.Datacenter] > maxPerDC {
+					maxPerDC = active[entry.Datacenter]
+				}
+				if total > maxTotal {
+					maxTotal = total
+				}
+				mu.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				return true
+			},
+		}},
+		NodeDone: func(node Node) {
+			mu.Lock()
+			active[node.Datacenter]--
+			total--
+			mu.Unlock()
+		},
+	}
+	s := scanSetup{ctx: context.Background(), nodes: nodes, topLevelDir: topLevelDir, opts: opts, filter: func(entries LogEntries) LogEntries { return entries }}
+
+	var buf bytes.Buffer
+	if err := runGroupByNode(s, &buf, FormatOptions{Format: FormatText}); err != nil {
+		t.Fatalf("runGroupByNode() error = %v", err)
+	}
+	if got := strings.Count(buf.String(), "Starting"); got != len(nodes) {
+		t.Errorf("Expected the entry of each of the %d nodes, got %d", len(nodes), got)
+	}
+	if maxTotal > concurrency {
+		t.Errorf("Expected at most %d nodes processed at once, got %d", concurrency, maxTotal)
+	}
+	if maxPerDC > perDC {
+		t.Errorf("Expected at most %d nodes of a datacenter processed at once, got %d", perDC, maxPerDC)
+	}
+}
+
+func TestRunGapsFiltered(t *testing.T) {
+	var buf bytes.Buffer
+	if err := runGaps(newModeSetup(t, func(entries LogEntries) LogEntries { return entries }), &buf, 30*time.Second); err != nil {
+		t.Fatalf("runGaps() error = %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Fatal("Expected the minute without entries of 10.0.0.1 to be reported")
+	}
+
+	buf.Reset()
+	if err := runGaps(newModeSetup(t, warnOnly), &buf, 30*time.Second); err != nil {
+		t.Fatalf("runGaps() error = %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no gap between the entries left by the filter, got %q", buf.String())
+	}
+}
+
+func TestRunReport(t *testing.T) {
+	entries := LogEntries{
+		{LogLevel: WARN, NodeIP: "10.0.0.1", Message: "WARN  [main] 2023-07-14 16:00:00,000 GCInspector.java:10 - Slow"},
+	}
+
+	var buf bytes.Buffer
+	reported, err := runReport(&buf, entries, reportModes{}, FormatOptions{}, defaultOutputBufferSize)
+	if err != nil || reported || buf.Len() != 0 {
+		t.Errorf("Expected no report without a mode, got %v, %v and %q", reported, err, buf.String())
+	}
+
+	reported, err = runReport(&buf, entries, reportModes{ListSources: true, Count: true}, FormatOptions{}, defaultOutputBufferSize)
+	if err != nil || !reported {
+		t.Fatalf("Expected a report, got %v and %v", reported, err)
+	}
+	// -count comes first
+	if strings.Contains(buf.String(), "GCInspector.java") {
+		t.Errorf("Expected the counts rather than the sources, got %q", buf.String())
+	}
+}
+
+// newSlowSetup returns the scan setup of nodes processed one at a time whose single entry takes delay to match, with
+// a -timeout of timeout.
+func newSlowSetup(t *testing.T, nodeCount int, delay, timeout time.Duration) scanSetup {
+	t.Helper()
+	topLevelDir := t.TempDir()
+	var nodes []Node
+	for i := 0; i < nodeCount; i++ {
+		node := Node{Address: fmt.Sprintf("10.0.0.%d", i+1)}
+		nodes = append(nodes, node)
+		writeSystemLog(t, topLevelDir, node.Address, "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n")
+	}
+	slow := pipeline.Matcher{Name: "slow", Match: func(*LogEntry) bool {
+		time.Sleep(delay)
+		return false
+	}}
+	return scanSetup{
+		ctx:         context.Background(),
+		nodes:       nodes,
+		topLevelDir: topLevelDir,
+		opts:        pipeline.ScanOptions{Deterministic: true, Matchers: []pipeline.Matcher{slow}},
+		timeout:     timeout,
+		filter:      func(entries LogEntries) LogEntries { return entries },
+	}
+}
+
+func TestModesTimeout(t *testing.T) {
+	const nodeCount, delay = 5, 200 * time.Millisecond
+	tests := []struct {
+		name string
+		run  func(s scanSetup, w *bytes.Buffer) error
+	}{
+		{name: "match preview", run: func(s scanSetup, w *bytes.Buffer) error { return runMatchPreview(s, w) }},
+		{name: "zero nodes", run: func(s scanSetup, w *bytes.Buffer) error { return runZeroNodes(s, w) }},
+		{name: "benchmark", run: func(s scanSetup, w *bytes.Buffer) error {
+			report, err := runBenchmark(s, func(LogEntries) {})
+			if err != nil {
+				return err
+			}
+			return report.Write(w)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSlowSetup(t, nodeCount, delay, 10*time.Millisecond)
+			var buf bytes.Buffer
+			start := time.Now()
+			if err := tt.run(s, &buf); err != nil {
+				t.Fatalf("Expected the partial results after the timeout, got %v", err)
+			}
+			// every node would take delay without the timeout
+			if elapsed := time.Since(start); elapsed >= nodeCount*delay/2 {
+				t.Errorf("Expected the scan to stop at the timeout, took %s", elapsed)
+			}
+			if buf.Len() == 0 {
+				t.Error("Expected a report of the partial results")
+			}
+		})
+	}
+}
+
+func TestRunZeroNodesTimeout(t *testing.T) {
+	var buf bytes.Buffer
+	if err := runZeroNodes(newSlowSetup(t, 2, 100*time.Millisecond, 10*time.Millisecond), &buf); err != nil {
+		t.Fatalf("runZeroNodes() error = %v", err)
+	}
+	want := "10.0.0.1: not scanned before -timeout\n10.0.0.2: not scanned before -timeout\n"
+	if buf.String() != want {
+		t.Errorf("runZeroNodes() = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// defaultOutputBufferSize is the default size of the buffer entries are written through, large enough to keep the number
+// of write syscalls low for big result sets.
+const defaultOutputBufferSize = 64 * 1024
+
+// syncWriter serializes writes to w so it can be shared by the node goroutines.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// writeEntries writes the header row of the format and entries formatted with opts to w, at the rate of limit if not
+// nil, until ctx is cancelled. It flushes w and returns the number of entries written.
+func writeEntries(ctx context.Context, w *bufio.Writer, entries LogEntries, opts FormatOptions, limit *throttle) (int, error) {
+	if err := writeHeader(w, opts); err != nil {
+		return 0, err
+	}
+	written := 0
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			break
+		}
+		ok, err := writeEntry(ctx, w, entry, opts, limit)
+		if err != nil {
+			return written, err
+		}
+		if ok {
+			written++
+		}
+	}
+	return written, w.Flush()
+}
+
+// writeBuffered calls write with w buffered through size bytes, then flushes the buffer unless write failed.
+func writeBuffered(w io.Writer, size int, write func(io.Writer) error) error {
+	bw := bufio.NewWriterSize(w, size)
+	if err := write(bw); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeEntry writes a single entry like writeEntries, without flushing w unless limit is non-nil. It returns false if
+// limit dropped the entry.
+func writeEntry(ctx context.Context, w *bufio.Writer, entry *LogEntry, opts FormatOptions, limit *throttle) (bool, error) {
+	if !limit.Allow(ctx) {
+		return false, nil
+	}
+	if err := FormatEntry(w, entry, opts); err != nil {
+		return false, err
+	}
+	if limit != nil {
+		return true, w.Flush()
+	}
+	return true, nil
+}
+
+// jsonEntries returns the entries as they should be encoded in JSON output formatted with opts.
+func jsonEntries(entries LogEntries, opts FormatOptions) LogEntries {
+	display := make(LogEntries, 0, len(entries))
+	for _, entry := range entries {
+		display = append(display, opts.jsonEntry(opts.displayEntry(entry)))
+	}
+	return display
+}
+
+// writeJSONArray writes the entries formatted with opts to w as a single JSON array.
+func writeJSONArray(w io.Writer, entries LogEntries, opts FormatOptions) error {
+	return json.NewEncoder(w).Encode(jsonEntries(entries, opts))
+}
+
+// writeHeader writes the header row of the format of opts to w before the first entry: the csvHeader of FormatCSV.
+// Other formats have none.
+func writeHeader(w io.Writer, opts FormatOptions) error {
+	if opts.Format != FormatCSV {
+		return nil
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// interruptingWriter sends SIGINT to the current process on its first write and waits for the signal to be delivered.
+type interruptingWriter struct {
+	buf         bytes.Buffer
+	ctx         context.Context
+	interrupted bool
+}
+
+func (w *interruptingWriter) Write(p []byte) (int, error) {
+	if !w.interrupted {
+		w.interrupted = true
+		if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+			return 0, err
+		}
+		<-w.ctx.Done()
+	}
+	return w.buf.Write(p)
+}
+
+func TestWriteEntriesFlushesOnInterrupt(t *testing.T) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var entries LogEntries
+	for i := 1; i <= 100; i++ {
+		entries = append(entries, &LogEntry{
+			LogLevel:   INFO,
+			Date:       time.Date(2023, 7, 14, 0, 0, i, 0, time.UTC),
+			LineNumber: i,
+			NodeIP:     "192.168.1.1",
+			FilePath:   "system.log",
+			Message:    fmt.Sprintf("message %d", i),
+		})
+	}
+
+	w := &interruptingWriter{ctx: ctx}
+	// A small buffer forces a write to the underlying writer, and so the interrupt, part way through the entries.
+	written, err := writeEntries(ctx, bufio.NewWriterSize(w, 256), entries, FormatOptions{Format: FormatText}, nil)
+	if err != nil {
+		t.Fatalf("writeEntries() error = %v", err)
+	}
+
+	if written == 0 || written == len(entries) {
+		t.Fatalf("Expected a partial write after the interrupt, wrote %d of %d entries", written, len(entries))
+	}
+
+	lines := strings.Split(strings.TrimSuffix(w.buf.String(), "\n"), "\n")
+	if len(lines) != written {
+		t.Fatalf("Expected %d flushed lines, got %d", written, len(lines))
+	}
+	if !strings.HasSuffix(lines[written-1], fmt.Sprintf("message %d", written)) {
+		t.Errorf("Expected the last flushed line to be entry %d, got %q", written, lines[written-1])
+	}
+}
+
+func BenchmarkWriteEntries(b *testing.B) {
+	var entries LogEntries
+	for i := 0; i < 10000; i++ {
+		entries = append(entries, &LogEntry{LogLevel: INFO, Date: time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC), LineNumber: i,
+			NodeIP: "10.0.0.1", FilePath: "system.log", Message: fmt.Sprintf("Compacted %d sstables", i)})
+	}
+
+	benchmarks := []struct {
+		name     string
+		buffered bool
+	}{
+		{name: "unbuffered", buffered: false},
+		{name: "buffered", buffered: true},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			file, err := os.Create(filepath.Join(b.TempDir(), "out.log"))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer file.Close()
+
+			for i := 0; i < b.N; i++ {
+				if bm.buffered {
+					if _, err := writeEntries(context.Background(), bufio.NewWriterSize(file, defaultOutputBufferSize), entries, FormatOptions{}, nil); err != nil {
+						b.Fatal(err)
+					}
+					continue
+				}
+				for _, entry := range entries {
+					if err := FormatEntry(file, entry, FormatOptions{}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWriteJSONArray(t *testing.T) {
+	entries := LogEntries{
+		{LogLevel: WARN, Date: time.Date(2023, 7, 14, 16, 0, 0, 658000000, time.UTC), LineNumber: 42, NodeIP: "10.0.0.1", FilePath: "system.log", Message: "Slow", LineCount: 1},
+		{LogLevel: ERROR, Date: time.Date(2023, 7, 14, 16, 0, 1, 0, time.UTC), LineNumber: 43, NodeIP: "10.0.0.2", FilePath: "system.log", Message: "Failed", LineCount: 1},
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSONArray(&buf, entries, FormatOptions{Format: FormatJSON}); err != nil {
+		t.Fatalf("writeJSONArray() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "[{\"level\":\"WARN\",\"date\":\"2023-07-14T16:00:00.658Z\"") {
+		t.Errorf("Expected a JSON array with the level name and RFC 3339 date, got %q", buf.String())
+	}
+
+	var decoded []LogEntry
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Expected a JSON array, got %q: %v", buf.String(), err)
+	}
+	if len(decoded) != len(entries) {
+		t.Fatalf("Expected %d entries, got %d", len(entries), len(decoded))
+	}
+	for i, entry := range entries {
+		got := decoded[i]
+		if got.LogLevel != entry.LogLevel || !got.Date.Equal(entry.Date) || got.LineNumber != entry.LineNumber ||
+			got.NodeIP != entry.NodeIP || got.FilePath != entry.FilePath || got.Message != entry.Message {
+			t.Errorf("Expected entry %d to decode to %+v, got %+v", i, *entry, got)
+		}
+	}
+}
+
+func TestWriteEntriesCSVHeader(t *testing.T) {
+	entries := LogEntries{
+		{LogLevel: WARN, Date: time.Date(2023, 7, 14, 16, 0, 0, 658000000, time.UTC), LineNumber: 42, NodeIP: "10.0.0.1", FilePath: "/bundle/system.log", Message: "Slow, \"very\" slow", LineCount: 1},
+		{LogLevel: ERROR, Date: time.Date(2023, 7, 14, 16, 0, 1, 0, time.UTC), LineNumber: 43, NodeIP: "10.0.0.2", FilePath: "/bundle/system.log", Message: "Failed\n\tat Server.run(Server.java:10)", LineCount: 2},
+	}
+
+	var buf bytes.Buffer
+	if _, err := writeEntries(context.Background(), bufio.NewWriter(&buf), entries, FormatOptions{Format: FormatCSV, StripPrefix: "/bundle"}, nil); err != nil {
+		t.Fatalf("writeEntries() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV, got %v", err)
+	}
+	want := [][]string{
+		{"level", "date", "node_ip", "file_path", "line_number", "message"},
+		{"WARN", "2023-07-14T16:00:00.658Z", "10.0.0.1", "system.log", "42", "Slow, \"very\" slow"},
+		{"ERROR", "2023-07-14T16:00:01Z", "10.0.0.2", "system.log", "43", "Failed\n\tat Server.run(Server.java:10)"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("Expected %d records, got %d: %q", len(want), len(records), records)
+	}
+	for i := range want {
+		if len(records[i]) != len(csvHeader) {
+			t.Errorf("Expected %d fields in record %d, got %d", len(csvHeader), i, len(records[i]))
+			continue
+		}
+		for j := range want[i] {
+			if records[i][j] != want[i][j] {
+				t.Errorf("Record %d field %s: expected %q, got %q", i, csvHeader[j], want[i][j], records[i][j])
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package wetlog_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github/kenjords/wetlog/pkg/wetlog"
+)
+
+func ExampleParseNodetoolStatus() {
+	status := `Datacenter: DC1
+===============
+--  Address    Load        Tokens  Owns (effective)  Host ID                               Rack
+UN  10.0.0.1   1.2 GiB     256     100.0%            f47ac10b-58cc-4372-a567-0e02b2c3d479  rack1
+DN  10.0.0.2   1.1 GiB     256     100.0%            0e02b2c3-58cc-4372-a567-f47ac10bd479  rack2
+`
+	nodes, err := wetlog.ParseNodetoolStatus(strings.NewReader(status))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, node := range nodes {
+		fmt.Println(node.Address, node.Datacenter, node.Rack, node.IsUp())
+	}
+	// Output:
+	// 10.0.0.1 DC1 rack1 true
+	// 10.0.0.2 DC1 rack2 false
+}
+
+func ExampleProcessLine() {
+	entry, err := wetlog.ProcessLine("WARN  [Service Thread] 2023-07-14 16:00:00,658 GCInspector.java:282 - G1 Young Generation GC in 523ms", 1, "system.log")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(entry.LogLevel, entry.Date.Format("2006-01-02 15:04:05"), entry.SourceFile(), entry.Body())
+	// Output:
+	// WARN 2023-07-14 16:00:00 GCInspector.java G1 Young Generation GC in 523ms
+}
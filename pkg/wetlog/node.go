@@ -0,0 +1,146 @@
+package wetlog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Node represents a node in the cluster.
+type Node struct {
+	Address    string
+	Datacenter string
+	Status     string // Status is the two-letter state of the node in nodetool status, e.g. UN or DN.
+	Load       string // Load is the data size of the node in nodetool status, e.g. "1.2 GiB", if the column is present.
+	LoadBytes  int64  // LoadBytes is Load in bytes, or -1 if it is unknown, e.g. "?" for a down node or no Load column.
+	HostID     string // HostID is the host ID of the node in nodetool status, if the column is present.
+	Rack       string // Rack is the rack of the node in nodetool status, if the column is present.
+}
+
+// nodeStatuses are the status tokens nodetool status starts node rows with: U(p) or D(own) followed by the state,
+// N(ormal), L(eaving), J(oining), M(oving) or U(nknown).
+var nodeStatuses = map[string]struct{}{
+	"UN": {}, "UL": {}, "UJ": {}, "UM": {}, "UU": {},
+	"DN": {}, "DL": {}, "DJ": {}, "DM": {}, "DU": {},
+}
+
+// IsNodeStatus returns true if token is a node status of nodetool status, e.g. UN or DN.
+func IsNodeStatus(token string) bool {
+	_, ok := nodeStatuses[token]
+	return ok
+}
+
+// IsUp returns true if nodetool status reported the node as up.
+func (n Node) IsUp() bool {
+	return strings.HasPrefix(n.Status, "U")
+}
+
+// loadUnits maps the size units nodetool status uses in the Load column to their number of bytes.
+var loadUnits = map[string]float64{
+	"bytes": 1,
+	"B":     1,
+	"KiB":   1 << 10,
+	"KB":    1 << 10,
+	"MiB":   1 << 20,
+	"MB":    1 << 20,
+	"GiB":   1 << 30,
+	"GB":    1 << 30,
+	"TiB":   1 << 40,
+	"TB":    1 << 40,
+}
+
+// ParseLoad converts a human-readable load from nodetool status, e.g. "1.2 GiB", to bytes.
+func ParseLoad(s string) (int64, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("Invalid load: %s", s)
+	}
+	unit, ok := loadUnits[fields[1]]
+	if !ok {
+		return 0, fmt.Errorf("Invalid load unit: %s", fields[1])
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid load: %s", s)
+	}
+	return int64(value * unit), nil
+}
+
+// loadColumnValue returns the load at the start of s, which begins at the Load column of a nodetool status row: a
+// number followed by its unit, or a single token such as "?" for nodes with an unknown load.
+func loadColumnValue(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ""
+	}
+	if len(fields) > 1 {
+		if _, ok := loadUnits[fields[1]]; ok {
+			return fields[0] + " " + fields[1]
+		}
+	}
+	return fields[0]
+}
+
+// minRackRowFields is the number of fields of the shortest nodetool status row holding a rack: status, address, load
+// and its unit, tokens, owns and rack, without a host ID.
+const minRackRowFields = 7
+
+// ParseNodetoolStatus parses the output of nodetool status.
+func ParseNodetoolStatus(r io.Reader) ([]Node, error) {
+	scanner := bufio.NewScanner(r)
+	var nodes []Node
+	var datacenter string
+	var foundNodeStatus bool
+	// positions of the optional columns in the header of the current datacenter, -1 if absent
+	loadColumn, hostIDColumn := -1, -1
+	// hasRack is false once a header without a Rack column is seen
+	hasRack := true
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+
+		switch {
+		case strings.HasPrefix(line, "Datacenter:"):
+			if len(fields) > 1 {
+				datacenter = fields[1]
+			}
+			loadColumn, hostIDColumn, hasRack = -1, -1, true
+		case strings.HasPrefix(line, "--"):
+			loadColumn = strings.Index(line, "Load")
+			hostIDColumn = strings.Index(line, "Host ID")
+			hasRack = strings.Contains(line, "Rack")
+		case len(fields) > 1 && IsNodeStatus(fields[0]):
+			node := Node{Address: fields[1], Datacenter: datacenter, Status: fields[0], LoadBytes: -1}
+			if loadColumn >= 0 && loadColumn < len(line) {
+				node.Load = loadColumnValue(line[loadColumn:])
+				if loadBytes, err := ParseLoad(node.Load); err == nil {
+					node.LoadBytes = loadBytes
+				}
+			}
+			if hostIDColumn >= 0 && hostIDColumn < len(line) {
+				if hostID := strings.Fields(line[hostIDColumn:]); len(hostID) > 0 && signatureUUIDRegex.MatchString(hostID[0]) {
+					node.HostID = hostID[0]
+				}
+			}
+			// the rack is the last column, missing from lines stopping before it such as "UN 10.0.0.1 1.2 GiB"
+			if hasRack && len(fields) >= minRackRowFields {
+				node.Rack = fields[len(fields)-1]
+			}
+			nodes = append(nodes, node)
+			foundNodeStatus = true
+		}
+	}
+
+	if !foundNodeStatus {
+		return nil, fmt.Errorf("No nodes found in nodetool status output")
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
@@ -0,0 +1,198 @@
+package wetlog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNodetoolStatus(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     string
+		wantNodes []Node
+		wantError bool
+	}{
+		{
+			name:  "single node up",
+			input: "Datacenter: DC1\nUN 127.0.0.1\n",
+			wantNodes: []Node{
+				{Address: "127.0.0.1", Datacenter: "DC1", Status: "UN", LoadBytes: -1},
+			},
+			wantError: false,
+		},
+		{
+			name:  "multiple nodes",
+			input: "Datacenter: DC1\nUN 127.0.0.1\nDN 127.0.0.2\n",
+			wantNodes: []Node{
+				{Address: "127.0.0.1", Datacenter: "DC1", Status: "UN", LoadBytes: -1},
+				{Address: "127.0.0.2", Datacenter: "DC1", Status: "DN", LoadBytes: -1},
+			},
+			wantError: false,
+		},
+		{
+			name:  "multiple datacenters",
+			input: "Datacenter: DC1\nUN 127.0.0.1\nUN 127.0.0.2\nDatacenter: DC2\nUN 127.0.1.1\n",
+			wantNodes: []Node{
+				{Address: "127.0.0.1", Datacenter: "DC1", Status: "UN", LoadBytes: -1},
+				{Address: "127.0.0.2", Datacenter: "DC1", Status: "UN", LoadBytes: -1},
+				{Address: "127.0.1.1", Datacenter: "DC2", Status: "UN", LoadBytes: -1},
+			},
+			wantError: false,
+		},
+		{
+			name:  "node down, node up, node joining, node moving, node leaving",
+			input: "Datacenter: DC1\nDN 127.0.0.1\nUN 127.0.0.2\nUJ 127.0.0.3\nUM 127.0.0.4\nUL 127.0.0.5\n",
+			wantNodes: []Node{
+				{Address: "127.0.0.1", Datacenter: "DC1", Status: "DN", LoadBytes: -1},
+				{Address: "127.0.0.2", Datacenter: "DC1", Status: "UN", LoadBytes: -1},
+				{Address: "127.0.0.3", Datacenter: "DC1", Status: "UJ", LoadBytes: -1},
+				{Address: "127.0.0.4", Datacenter: "DC1", Status: "UM", LoadBytes: -1},
+				{Address: "127.0.0.5", Datacenter: "DC1", Status: "UL", LoadBytes: -1},
+			},
+			wantError: false,
+		},
+		{
+			name:      "bad format",
+			input:     "bad input format\n",
+			wantNodes: nil,
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := strings.NewReader(tc.input)
+			nodes, err := ParseNodetoolStatus(r)
+
+			if (err != nil) != tc.wantError {
+				t.Fatalf("parseNodetoolStatus() error = %v, wantErr %v", err, tc.wantError)
+			}
+
+			if !reflect.DeepEqual(nodes, tc.wantNodes) {
+				t.Errorf("parseNodetoolStatus() = %v, want %v", nodes, tc.wantNodes)
+			}
+		})
+	}
+}
+
+func TestParseNodetoolStatusStatuses(t *testing.T) {
+	statuses := []string{"UN", "UL", "UJ", "UM", "UU", "DN", "DL", "DJ", "DM", "DU"}
+	for _, status := range statuses {
+		t.Run(status, func(t *testing.T) {
+			nodes, err := ParseNodetoolStatus(strings.NewReader("Datacenter: DC1\n" + status + "  127.0.0.1  1.2 GiB  256  ?  rack1\n"))
+			if err != nil {
+				t.Fatalf("ParseNodetoolStatus() error = %v", err)
+			}
+			if len(nodes) != 1 || nodes[0].Status != status || nodes[0].Address != "127.0.0.1" {
+				t.Errorf("Expected node 127.0.0.1 with status %s, got %+v", status, nodes)
+			}
+		})
+	}
+
+	// tokens that merely start like a status are not node rows
+	for _, line := range []string{"UNKNOWN 127.0.0.1", "DNS 127.0.0.1", "XN 127.0.0.1", "UN"} {
+		t.Run(line, func(t *testing.T) {
+			if nodes, err := ParseNodetoolStatus(strings.NewReader("Datacenter: DC1\n" + line + "\n")); err == nil {
+				t.Errorf("Expected no node from %q, got %+v", line, nodes)
+			}
+		})
+	}
+}
+
+func TestParseNodetoolStatusRacks(t *testing.T) {
+	input := `Datacenter: DC1
+===============
+--  Address    Load        Tokens  Owns (effective)  Host ID                               Rack
+UN  10.0.0.1   1.2 GiB     256     100.0%            f47ac10b-58cc-4372-a567-0e02b2c3d479  rack1
+UN  10.0.0.2   1.1 GiB     256     100.0%            0e02b2c3-58cc-4372-a567-f47ac10bd479  rack2
+UN  10.0.0.3   1.3 GiB     256     100.0%            6ba7b810-9dad-11d1-80b4-00c04fd430c8  rack3
+Datacenter: DC2
+===============
+--  Address    Load        Tokens  Owns (effective)  Host ID
+UN  10.0.1.1   1.2 GiB     256     100.0%            7c9e6679-7425-40de-944b-e07fc1f90ae7
+Datacenter: DC3
+===============
+UN  10.0.2.1   1.2 GiB     256     100.0%  rack1
+UN  10.0.2.2   1.2 GiB
+DN  10.0.2.3
+`
+	nodes, err := ParseNodetoolStatus(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseNodetoolStatus() error = %v", err)
+	}
+	racks := make(map[string]string)
+	for _, node := range nodes {
+		racks[node.Address] = node.Rack
+	}
+	want := map[string]string{
+		"10.0.0.1": "rack1", "10.0.0.2": "rack2", "10.0.0.3": "rack3",
+		// DC2 has no Rack column, and short lines stop before the rack
+		"10.0.1.1": "",
+		"10.0.2.1": "rack1", "10.0.2.2": "", "10.0.2.3": "",
+	}
+	if !reflect.DeepEqual(racks, want) {
+		t.Errorf("Expected racks %v, got %v", want, racks)
+	}
+
+}
+
+func TestParseNodetoolStatusLoadAndHostID(t *testing.T) {
+	input := `Datacenter: DC1
+===============
+Status=Up/Down
+|/ State=Normal/Leaving/Joining/Moving
+--  Address    Load        Tokens  Owns (effective)  Host ID                               Rack
+UN  10.0.0.1   1.2 GiB     256     100.0%            f47ac10b-58cc-4372-a567-0e02b2c3d479  rack1
+DN  10.0.0.2   ?           256     100.0%            0e02b2c3-58cc-4372-a567-f47ac10bd479  rack1
+UN  10.0.0.3   512.5 KiB   256     100.0%            6ba7b810-9dad-11d1-80b4-00c04fd430c8  rack2
+
+Datacenter: DC2
+===============
+UN 10.0.1.1
+`
+	got, err := ParseNodetoolStatus(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseNodetoolStatus() error = %v", err)
+	}
+	want := []Node{
+		{Address: "10.0.0.1", Datacenter: "DC1", Status: "UN", Load: "1.2 GiB", LoadBytes: 1288490188, HostID: "f47ac10b-58cc-4372-a567-0e02b2c3d479", Rack: "rack1"},
+		{Address: "10.0.0.2", Datacenter: "DC1", Status: "DN", Load: "?", LoadBytes: -1, HostID: "0e02b2c3-58cc-4372-a567-f47ac10bd479", Rack: "rack1"},
+		{Address: "10.0.0.3", Datacenter: "DC1", Status: "UN", Load: "512.5 KiB", LoadBytes: 524800, HostID: "6ba7b810-9dad-11d1-80b4-00c04fd430c8", Rack: "rack2"},
+		{Address: "10.0.1.1", Datacenter: "DC2", Status: "UN", LoadBytes: -1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseNodetoolStatus() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseLoad(t *testing.T) {
+	tests := []struct {
+		load    string
+		want    int64
+		wantErr bool
+	}{
+		{load: "100 bytes", want: 100},
+		{load: "1.5 KiB", want: 1536},
+		{load: "2 MiB", want: 2 << 20},
+		{load: "1.25 GiB", want: 5 << 28},
+		{load: "1 TiB", want: 1 << 40},
+		{load: "256.43 GiB", want: 275339615928},
+		{load: "0 bytes", want: 0},
+		{load: "?", wantErr: true},
+		{load: "1.2 PiB", wantErr: true},
+		{load: "many GiB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.load, func(t *testing.T) {
+			got, err := ParseLoad(tt.load)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLoad(%q) error = %v, wantErr %v", tt.load, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLoad(%q) = %d, want %d", tt.load, got, tt.want)
+			}
+		})
+	}
+}
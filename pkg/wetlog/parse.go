@@ -0,0 +1,172 @@
+package wetlog
+
+import (
+	"regexp"
+	"time"
+)
+
+// dateLayouts are the layouts ParseDate tries, in order.
+var dateLayouts = []string{
+	"2006-01-02 15:04:05,000",
+	"06-01-02 15:04:05,000",
+	"2006-01-02T15:04:05.000",
+	"2006-01-02T15:04:05.000Z07:00",
+	"2006-01-02T15:04:05.000Z0700",
+}
+
+// yearlessDateLayout is the layout of dates that omit the year, which are only parsed when LineOptions.InferYear is set.
+const yearlessDateLayout = "01-02 15:04:05,000"
+
+// ParseDate parses a date string in the format "2006-01-02 15:04:05,000", falling back to a two-digit year and to
+// ISO-8601 dates such as "2006-01-02T15:04:05.000".
+func ParseDate(dateTimeStr string) (time.Time, error) {
+	var err error
+	for _, layout := range dateLayouts {
+		var date time.Time
+		date, err = time.Parse(layout, dateTimeStr)
+		if err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// parseYearlessDate parses a date without a year, placing it in the year of modTime. Dates that would fall after modTime
+// belong to the previous year, e.g. December entries in a log file last written in January.
+func parseYearlessDate(dateTimeStr string, modTime time.Time) (time.Time, error) {
+	date, err := time.Parse(yearlessDateLayout, dateTimeStr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return InferYear(date, modTime), nil
+}
+
+// InferYear moves a date parsed without a year to the year of modTime, or the year before if it would fall after modTime.
+func InferYear(date, modTime time.Time) time.Time {
+	date = date.AddDate(modTime.Year()-date.Year(), 0, 0)
+	if date.After(modTime) {
+		date = date.AddDate(-1, 0, 0)
+	}
+	return date
+}
+
+// LineOptions controls how ParseLine parses a log line.
+type LineOptions struct {
+	// DateLayout is a Go time layout tried before the built-in ones to parse the date of the line.
+	DateLayout string
+	// InferYear parses dates without a year, placing them in the year of ModTime, see InferYear.
+	InferYear bool
+	// ModTime is the modification time of the log file the line was read from, used with InferYear.
+	ModTime time.Time
+}
+
+// dateTimeRegex matches the level, optional thread and date starting a log line, capturing the date.
+var dateTimeRegex = regexp.MustCompile(`^(?:\[\w+\]|\w+)\s+(?:\[[^\]]*\]\s+)?((?:\d{4}-|\d{2}-)?\d{2}-\d{2}[\sT]\d{2}:\d{2}:\d{2}[,.]\d{3}(?:Z|[+-]\d{2}:?\d{2})?)`)
+
+// ProcessLine processes a line of a log file.
+func ProcessLine(line string, lineNumber int, filePath string) (*LogEntry, error) {
+	return ParseLine(line, lineNumber, filePath, LineOptions{})
+}
+
+// ParseLine parses the line starting an entry of a log file using the given options. It returns a nil entry for lines
+// that don't start with a level and a date, along with an error if the level or the date is invalid.
+func ParseLine(line string, lineNumber int, filePath string, opts LineOptions) (*LogEntry, error) {
+	levelName, ok := lineLogLevel(line)
+	if !ok {
+		return nil, nil
+	}
+
+	logLevel, err := ParseLogLevel(levelName)
+	if err != nil {
+		return nil, err
+	}
+
+	// the date must follow the level and optional thread, so a level word merely followed by a date somewhere in the
+	// message doesn't make an entry
+	if opts.DateLayout != "" {
+		if date, ok := parseHeaderDate(line, opts.DateLayout); ok {
+			return newLogEntry(logLevel, date, lineNumber, filePath, line), nil
+		}
+	}
+
+	dateTimeMatch := dateTimeRegex.FindStringSubmatch(line)
+	if dateTimeMatch == nil {
+		return nil, nil
+	}
+
+	var date time.Time
+	if opts.InferYear && len(dateTimeMatch[1]) == len(yearlessDateLayout) {
+		date, err = parseYearlessDate(dateTimeMatch[1], opts.ModTime)
+	} else {
+		date, err = ParseDate(dateTimeMatch[1])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newLogEntry(logLevel, date, lineNumber, filePath, line), nil
+}
+
+// newLogEntry returns the entry starting at a line of a log file.
+func newLogEntry(logLevel LogLevel, date time.Time, lineNumber int, filePath, line string) *LogEntry {
+	return &LogEntry{
+		LogLevel:   logLevel,
+		Date:       date,
+		LineNumber: lineNumber,
+		NodeIP:     "",
+		FilePath:   filePath,
+		Message:    line,
+		LineCount:  1,
+	}
+}
+
+// headerPrefixRegex matches the level and optional thread preceding the date of a log line.
+var headerPrefixRegex = regexp.MustCompile(`^(?:\[\w+\]|\w+)\s+(?:\[[^\]]*\]\s+)?`)
+
+// parseHeaderDate parses the date following the level and optional thread of line with a user supplied layout. The
+// date is expected to be as long as the layout, which holds for layouts made of fixed width numeric elements.
+func parseHeaderDate(line, layout string) (time.Time, bool) {
+	rest := line[len(headerPrefixRegex.FindString(line)):]
+	if len(rest) < len(layout) {
+		return time.Time{}, false
+	}
+	date, err := time.Parse(layout, rest[:len(layout)])
+	return date, err == nil
+}
+
+// logLevelPrefixRegex matches the level word starting a log line, bare as in "INFO  [main] ..." or in square brackets
+// as some logback patterns render it, e.g. "[INFO] 2023-07-14 ...".
+var logLevelPrefixRegex = regexp.MustCompile(`^(?:\[(\w+)\]|(\w+))\s`)
+
+// lineLogLevel returns the level word starting the line, without its brackets, and false if the line doesn't start
+// with a word. A bracketed word is only taken as a level if ParseLogLevel knows it, so that continuation lines starting
+// with e.g. "[main]" stay part of their entry.
+func lineLogLevel(line string) (string, bool) {
+	match := logLevelPrefixRegex.FindStringSubmatch(line)
+	switch {
+	case match == nil:
+		return "", false
+	case match[1] != "":
+		if _, err := ParseLogLevel(match[1]); err != nil {
+			return "", false
+		}
+		return match[1], true
+	default:
+		return match[2], true
+	}
+}
+
+// startsWithLogLevel returns true if the line starts with a log level.
+func startsWithLogLevel(line string) bool {
+	_, ok := lineLogLevel(line)
+	return ok
+}
+
+// stackTraceLineRegex matches the lines of a Java stack trace that start with a word followed by a space, which would
+// otherwise be taken for the level of a new entry.
+var stackTraceLineRegex = regexp.MustCompile(`^(?:Caused by: |Exception in thread ")`)
+
+// StartsEntry returns true if the line starts a new entry rather than continuing the message of the current one.
+func StartsEntry(line string) bool {
+	return startsWithLogLevel(line) && !stackTraceLineRegex.MatchString(line)
+}
@@ -0,0 +1,254 @@
+package wetlog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:    "valid date",
+			input:   "2023-07-13 12:01:01,000",
+			want:    time.Date(2023, 7, 13, 12, 0o1, 0o1, 0, time.UTC),
+			wantErr: false,
+		},
+		{
+			name:    "two-digit year",
+			input:   "23-07-13 12:01:01,000",
+			want:    time.Date(2023, 7, 13, 12, 0o1, 0o1, 0, time.UTC),
+			wantErr: false,
+		},
+		{
+			name:    "yearless date",
+			input:   "07-13 12:01:01,000",
+			want:    time.Time{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid date",
+			input:   "2023-13-07 12:01:01,000",
+			want:    time.Time{},
+			wantErr: true,
+		},
+		{
+			name:    "ISO date",
+			input:   "2023-07-13T12:01:01.250",
+			want:    time.Date(2023, 7, 13, 12, 0o1, 0o1, 250000000, time.UTC),
+			wantErr: false,
+		},
+		{
+			name:    "ISO date with offset",
+			input:   "2023-07-13T14:01:01.250+02:00",
+			want:    time.Date(2023, 7, 13, 12, 0o1, 0o1, 250000000, time.UTC),
+			wantErr: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseDate(tc.input)
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseDate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if !got.Equal(tc.want) {
+				t.Errorf("parseDate() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProcessLine(t *testing.T) {
+	// Define test cases
+	testCases := []struct {
+		line      string
+		lineNum   int
+		filePath  string
+		expectErr bool
+	}{
+		{
+			line:      "INFO  [Solr TTL scheduler-0] 2023-07-05 13:03:37,128  AbstractSolrSecondaryIndex.java:1964 - Expired 3 documents in 18 milliseconds for core poms_om_search.om_mail_order_by_customer",
+			lineNum:   1,
+			filePath:  "testFilePath",
+			expectErr: false,
+		},
+		{
+			line:      "WARN  2023-04-24 12:12:32,430 org.apache.hadoop.hive.conf.HiveConf: HiveConf hive.server2.thrift.http.port expects INT type value",
+			lineNum:   2,
+			filePath:  "testFilePath",
+			expectErr: false,
+		},
+		{
+			line:      "INVALID  [Solr TTL scheduler-0] 2023-07-05 13:03:37,128  AbstractSolrSecondaryIndex.java:1964 - Expired 3 documents in 18 milliseconds for core poms_om_search.om_mail_order_by_customer",
+			lineNum:   3,
+			filePath:  "testFilePath",
+			expectErr: true, // expect error due to invalid log level
+		},
+	}
+
+	for i, testCase := range testCases {
+		_, err := ProcessLine(testCase.line, testCase.lineNum, testCase.filePath)
+
+		if err != nil && !testCase.expectErr {
+			t.Errorf("Test case %d: unexpected error: %v", i+1, err)
+		} else if err == nil && testCase.expectErr {
+			t.Errorf("Test case %d: expected error but got none", i+1)
+		}
+	}
+}
+
+func TestProcessLineBracketedLevel(t *testing.T) {
+	date := time.Date(2023, 7, 14, 16, 0, 0, 658000000, time.UTC)
+	tests := []struct {
+		name      string
+		line      string
+		wantNil   bool
+		wantLevel LogLevel
+	}{
+		{name: "bracketed", line: "[WARN] 2023-07-14 16:00:00,658 Gossiper.java:1200 - Node /10.0.0.1 is down", wantLevel: WARN},
+		{name: "bracketed with thread", line: "[ERROR] [main] 2023-07-14 16:00:00,658 Server.java:10 - Failed", wantLevel: ERROR},
+		{name: "bare", line: "INFO  [main] 2023-07-14 16:00:00,658 Server.java:10 - Starting", wantLevel: INFO},
+		{name: "bracketed thread only", line: "[main] 2023-07-14 16:00:00,658 Server.java:10 - Starting", wantNil: true},
+		{name: "unbalanced bracket", line: "[WARN 2023-07-14 16:00:00,658 Server.java:10 - Starting", wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := ProcessLine(tt.line, 1, "system.log")
+			if err != nil {
+				t.Fatalf("ProcessLine() error = %v", err)
+			}
+			if tt.wantNil {
+				if entry != nil {
+					t.Errorf("Expected no entry, got %+v", entry)
+				}
+				return
+			}
+			if entry == nil || entry.LogLevel != tt.wantLevel || !entry.Date.Equal(date) {
+				t.Errorf("Expected a %v entry dated %v, got %+v", tt.wantLevel, date, entry)
+			}
+		})
+	}
+
+}
+
+func TestProcessLineRequiresLevelAndDate(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantEntry bool
+	}{
+		{name: "level and date", line: "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting", wantEntry: true},
+		{name: "level and date without thread", line: "WARN  2023-04-24 12:12:32,430 org.apache.hadoop.hive.conf.HiveConf: HiveConf", wantEntry: true},
+		{name: "level without timestamp", line: "INFO replaying commit log segments", wantEntry: false},
+		{name: "level with a date in the message", line: "INFO replaying segment written 2023-07-14 16:00:00,000 Server.java:10", wantEntry: false},
+		{name: "non-level word", line: "Starting 2023-07-14 16:00:00,000", wantEntry: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, _ := ProcessLine(tt.line, 1, "system.log")
+			if (entry != nil) != tt.wantEntry {
+				t.Errorf("ProcessLine(%q) = %+v, want an entry: %v", tt.line, entry, tt.wantEntry)
+			}
+		})
+	}
+
+}
+
+func TestProcessLineDateFormats(t *testing.T) {
+	want := time.Date(2023, 7, 14, 16, 0, 0, 658000000, time.UTC)
+	tests := []struct {
+		name   string
+		line   string
+		layout string
+	}{
+		{name: "comma format", line: "INFO  [main] 2023-07-14 16:00:00,658 Server.java:10 - Starting"},
+		{name: "ISO format", line: "INFO  [main] 2023-07-14T16:00:00.658 Server.java:10 - Starting"},
+		{name: "ISO format with zone", line: "INFO  [main] 2023-07-14T16:00:00.658Z Server.java:10 - Starting"},
+		{name: "user layout", line: "INFO  [main] 14/07/2023 16:00:00.658 Server.java:10 - Starting", layout: "02/01/2006 15:04:05.000"},
+		{name: "user layout falls back to built-in ones", line: "INFO  [main] 2023-07-14 16:00:00,658 Server.java:10 - Starting", layout: "02/01/2006 15:04:05.000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := ParseLine(tt.line, 1, "system.log", LineOptions{DateLayout: tt.layout})
+			if err != nil || entry == nil {
+				t.Fatalf("ParseLine() = %v, %v, want an entry", entry, err)
+			}
+			if !entry.Date.Equal(want) {
+				t.Errorf("Expected date %v, got %v", want, entry.Date)
+			}
+			if body := entry.Body(); body != "Starting" {
+				t.Errorf("Expected body %q, got %q", "Starting", body)
+			}
+		})
+	}
+
+	if entry, _ := ParseLine("INFO  [main] 14/07/2023 16:00:00.658 Server.java:10 - Starting", 1, "system.log", LineOptions{}); entry != nil {
+		t.Errorf("Expected no entry for a custom date without -date-format, got %+v", entry)
+	}
+}
+
+func TestStartsWithLogLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected bool
+	}{
+		{
+			name:     "Line starts with log level",
+			line:     "INFO  [main] 2023-07-14 16:00:00,658 YamlConfigurationLoader.java:89 - Configuration location: file:/etc/cassandra/cassandra.yaml",
+			expected: true,
+		},
+		{
+			name:     "Line does not start with log level",
+			line:     "[main] 2023-07-14 16:00:00,658 YamlConfigurationLoader.java:89 - Configuration location: file:/etc/cassandra/cassandra.yaml",
+			expected: false,
+		},
+		{
+			name:     "Empty line",
+			line:     "",
+			expected: false,
+		},
+		{
+			name:     "Line starts with non-word characters",
+			line:     "# INFO 2023-07-14 16:00:00,658 YamlConfigurationLoader.java:89 - Configuration location: file:/etc/cassandra/cassandra.yaml",
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := startsWithLogLevel(test.line); got != test.expected {
+				t.Errorf("startsWithLogLevel() = %v, want %v", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestStartsEntry(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"ERROR [main] 2023-07-14 16:00:00,658 CassandraDaemon.java:581 - Exception in thread Thread[main,5,main]", true},
+		{"Caused by: java.io.IOException: Corrupt sstable", false},
+		{"Exception in thread \"main\" java.lang.OutOfMemoryError: Java heap space", false},
+		{"\tat org.apache.cassandra.db.compaction.CompactionTask.runMayThrow(CompactionTask.java:241)", false},
+		{"TRACE [main] 2023-07-14 16:00:00,658 Server.java:10 - Unknown level", true},
+	}
+
+	for _, tt := range tests {
+		if got := StartsEntry(tt.line); got != tt.want {
+			t.Errorf("StartsEntry(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package wetlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Protobuf wire types, see https://protobuf.dev/programming-guides/encoding/.
+const (
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+	protoWireFixed32 = 5
+)
+
+// Field numbers of the LogEntry message in proto/wetlog.proto.
+const (
+	protoFieldLevel        = 1
+	protoFieldDateUnixNano = 2
+	protoFieldLineNumber   = 3
+	protoFieldNodeIP       = 4
+	protoFieldFilePath     = 5
+	protoFieldMessage      = 6
+	protoFieldMetrics      = 7
+	protoFieldLineCount    = 8
+	protoFieldCount        = 9
+	protoFieldDatacenter   = 10
+	protoFieldPrevLine     = 11
+	protoFieldNextLine     = 12
+	protoFieldFields       = 13
+)
+
+// Field numbers of the map entries of LogEntry.metrics.
+const (
+	protoFieldMetricKey   = 1
+	protoFieldMetricValue = 2
+)
+
+// Field numbers of the map entries of LogEntry.fields.
+const (
+	protoFieldFieldKey   = 1
+	protoFieldFieldValue = 2
+)
+
+func appendProtoTag(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendProtoTag(b, field, protoWireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendProtoBytes(b []byte, field int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = appendProtoTag(b, field, protoWireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendProtoDouble(b []byte, field int, v float64) []byte {
+	b = appendProtoTag(b, field, protoWireFixed64)
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+}
+
+// MarshalProto encodes the entry as a LogEntry protobuf message as defined in proto/wetlog.proto.
+func (e *LogEntry) MarshalProto() []byte {
+	var b []byte
+	b = appendProtoVarint(b, protoFieldLevel, uint64(e.LogLevel))
+	if !e.Date.IsZero() {
+		b = appendProtoVarint(b, protoFieldDateUnixNano, uint64(e.Date.UnixNano()))
+	}
+	b = appendProtoVarint(b, protoFieldLineNumber, uint64(e.LineNumber))
+	b = appendProtoBytes(b, protoFieldNodeIP, []byte(e.NodeIP))
+	b = appendProtoBytes(b, protoFieldFilePath, []byte(e.FilePath))
+	b = appendProtoBytes(b, protoFieldMessage, []byte(e.Message))
+
+	names := make([]string, 0, len(e.Metrics))
+	for name := range e.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var metric []byte
+		metric = appendProtoBytes(metric, protoFieldMetricKey, []byte(name))
+		metric = appendProtoDouble(metric, protoFieldMetricValue, e.Metrics[name])
+		b = appendProtoTag(b, protoFieldMetrics, protoWireBytes)
+		b = binary.AppendUvarint(b, uint64(len(metric)))
+		b = append(b, metric...)
+	}
+	b = appendProtoVarint(b, protoFieldLineCount, uint64(e.LineCount))
+	b = appendProtoVarint(b, protoFieldCount, uint64(e.Count))
+	b = appendProtoBytes(b, protoFieldDatacenter, []byte(e.Datacenter))
+	b = appendProtoVarint(b, protoFieldPrevLine, uint64(e.PrevLine))
+	b = appendProtoVarint(b, protoFieldNextLine, uint64(e.NextLine))
+
+	keys := make([]string, 0, len(e.Fields))
+	for key := range e.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var field []byte
+		field = appendProtoBytes(field, protoFieldFieldKey, []byte(key))
+		field = appendProtoBytes(field, protoFieldFieldValue, []byte(e.Fields[key]))
+		b = appendProtoTag(b, protoFieldFields, protoWireBytes)
+		b = binary.AppendUvarint(b, uint64(len(field)))
+		b = append(b, field...)
+	}
+	return b
+}
+
+// protoField is a single decoded field of a protobuf message.
+type protoField struct {
+	number   int
+	wireType int
+	varint   uint64
+	bytes    []byte
+}
+
+// readProtoFields decodes the fields of a protobuf message.
+func readProtoFields(b []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, fmt.Errorf("Invalid protobuf tag")
+		}
+		b = b[n:]
+		field := protoField{number: int(tag >> 3), wireType: int(tag & 7)}
+
+		switch field.wireType {
+		case protoWireVarint:
+			field.varint, n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, fmt.Errorf("Invalid protobuf varint in field %d", field.number)
+			}
+			b = b[n:]
+		case protoWireFixed64:
+			if len(b) < 8 {
+				return nil, fmt.Errorf("Truncated protobuf field %d", field.number)
+			}
+			field.varint = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case protoWireFixed32:
+			if len(b) < 4 {
+				return nil, fmt.Errorf("Truncated protobuf field %d", field.number)
+			}
+			field.varint = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		case protoWireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < length {
+				return nil, fmt.Errorf("Truncated protobuf field %d", field.number)
+			}
+			field.bytes = b[n : n+int(length)]
+			b = b[n+int(length):]
+		default:
+			return nil, fmt.Errorf("Unsupported protobuf wire type %d in field %d", field.wireType, field.number)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// UnmarshalProto decodes a LogEntry protobuf message into the entry. Unknown fields are ignored.
+func (e *LogEntry) UnmarshalProto(b []byte) error {
+	fields, err := readProtoFields(b)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		switch field.number {
+		case protoFieldLevel:
+			e.LogLevel = LogLevel(field.varint)
+		case protoFieldDateUnixNano:
+			e.Date = time.Unix(0, int64(field.varint)).UTC()
+		case protoFieldLineNumber:
+			e.LineNumber = int(field.varint)
+		case protoFieldNodeIP:
+			e.NodeIP = string(field.bytes)
+		case protoFieldFilePath:
+			e.FilePath = string(field.bytes)
+		case protoFieldMessage:
+			e.Message = string(field.bytes)
+		case protoFieldLineCount:
+			e.LineCount = int(field.varint)
+		case protoFieldCount:
+			e.Count = int(field.varint)
+		case protoFieldDatacenter:
+			e.Datacenter = string(field.bytes)
+		case protoFieldPrevLine:
+			e.PrevLine = int(field.varint)
+		case protoFieldNextLine:
+			e.NextLine = int(field.varint)
+		case protoFieldMetrics:
+			metricFields, err := readProtoFields(field.bytes)
+			if err != nil {
+				return err
+			}
+			var name string
+			var value float64
+			for _, metricField := range metricFields {
+				switch metricField.number {
+				case protoFieldMetricKey:
+					name = string(metricField.bytes)
+				case protoFieldMetricValue:
+					value = math.Float64frombits(metricField.varint)
+				}
+			}
+			if e.Metrics == nil {
+				e.Metrics = make(map[string]float64)
+			}
+			e.Metrics[name] = value
+		case protoFieldFields:
+			entryFields, err := readProtoFields(field.bytes)
+			if err != nil {
+				return err
+			}
+			var key, value string
+			for _, entryField := range entryFields {
+				switch entryField.number {
+				case protoFieldFieldKey:
+					key = string(entryField.bytes)
+				case protoFieldFieldValue:
+					value = string(entryField.bytes)
+				}
+			}
+			if e.Fields == nil {
+				e.Fields = make(map[string]string)
+			}
+			e.Fields[key] = value
+		}
+	}
+	return nil
+}
+
+// WriteProtoEntry writes the entry to w as a length-delimited LogEntry message.
+func WriteProtoEntry(w io.Writer, e *LogEntry) error {
+	msg := e.MarshalProto()
+	_, err := w.Write(append(binary.AppendUvarint(nil, uint64(len(msg))), msg...))
+	return err
+}
+
+// ReadProtoEntries decodes a stream of length-delimited LogEntry messages as written by -format proto.
+func ReadProtoEntries(r io.Reader) (LogEntries, error) {
+	br := bufio.NewReader(r)
+	var entries LogEntries
+	for {
+		length, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		msg := make([]byte, length)
+		if _, err := io.ReadFull(br, msg); err != nil {
+			return nil, err
+		}
+
+		entry := &LogEntry{}
+		if err := entry.UnmarshalProto(msg); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+}
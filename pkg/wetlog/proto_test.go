@@ -0,0 +1,64 @@
+package wetlog
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProtoRoundTrip(t *testing.T) {
+	entries := LogEntries{
+		{
+			LogLevel:   WARN,
+			Date:       time.Date(2023, 7, 14, 16, 0, 0, 658000000, time.UTC),
+			LineNumber: 42,
+			NodeIP:     "192.168.1.1",
+			Datacenter: "DC1",
+			FilePath:   "/var/log/cassandra/system.log",
+			Message:    "WARN  [Service Thread] 2023-07-14 16:00:00,658 GCInspector.java:282 - G1 Young Generation GC in 523ms.",
+			Metrics:    map[string]float64{"gc_pause_ms": 523, "pending_tasks": 1.5},
+			LineCount:  1,
+			PrevLine:   40,
+			NextLine:   45,
+			Fields:     map[string]string{"pool": "G1", "pause": "523ms"},
+		},
+		{
+			LogLevel:   DEBUG,
+			Date:       time.Date(2023, 7, 14, 16, 0, 1, 0, time.UTC),
+			LineNumber: 1,
+			NodeIP:     "192.168.1.2",
+			FilePath:   "/var/log/cassandra/system.log",
+			Message:    "DEBUG [main] 2023-07-14 16:00:01,000 Server.java:10 - Multi-line\n\tat org.apache.cassandra",
+			LineCount:  2,
+			Count:      3,
+		},
+	}
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		if err := WriteProtoEntry(&buf, entry); err != nil {
+			t.Fatalf("WriteProtoEntry() error = %v", err)
+		}
+	}
+
+	got, err := ReadProtoEntries(&buf)
+	if err != nil {
+		t.Fatalf("ReadProtoEntries() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("ReadProtoEntries() = %v, want %v", got, entries)
+	}
+}
+
+func TestReadProtoEntriesTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteProtoEntry(&buf, &LogEntry{LogLevel: INFO, Message: "truncated"}); err != nil {
+		t.Fatalf("WriteProtoEntry() error = %v", err)
+	}
+	truncated := bytes.NewReader(buf.Bytes()[:buf.Len()-3])
+
+	if _, err := ReadProtoEntries(truncated); err == nil {
+		t.Errorf("Expected an error for a truncated stream")
+	}
+}
@@ -0,0 +1,39 @@
+package wetlog
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+)
+
+var (
+	// signatureDateRegex matches the Cassandra timestamp embedded in a log line.
+	signatureDateRegex = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[\sT]\d{2}:\d{2}:\d{2}[,.]\d{3}`)
+	// signatureUUIDRegex matches UUIDs such as host IDs and table IDs.
+	signatureUUIDRegex = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	// signatureIPRegex matches IPv4 addresses, optionally with a port.
+	signatureIPRegex = regexp.MustCompile(`/?\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}(:\d+)?`)
+	// signatureHexRegex matches 0x prefixed hex values such as object addresses.
+	signatureHexRegex = regexp.MustCompile(`0x[0-9a-fA-F]+`)
+	// signatureNumberRegex matches any remaining run of digits.
+	signatureNumberRegex = regexp.MustCompile(`\d+`)
+)
+
+// NormalizeMessage reduces a log message to its signature by replacing the parts that vary between
+// occurrences of the same event (timestamps, UUIDs, IPs, hex values and numbers) with placeholders.
+func NormalizeMessage(msg string) string {
+	msg = signatureDateRegex.ReplaceAllString(msg, "<date>")
+	msg = signatureUUIDRegex.ReplaceAllString(msg, "<uuid>")
+	msg = signatureIPRegex.ReplaceAllString(msg, "<ip>")
+	msg = signatureHexRegex.ReplaceAllString(msg, "<hex>")
+	msg = signatureNumberRegex.ReplaceAllString(msg, "<n>")
+	return strings.Join(strings.Fields(msg), " ")
+}
+
+// Hash returns a stable hash of the entry's log level and normalized message, so entries describing the same event share a hash.
+func (e *LogEntry) Hash() string {
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "%d|%s", e.LogLevel, NormalizeMessage(e.Message))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
@@ -0,0 +1,48 @@
+package wetlog
+
+import "testing"
+
+func TestNormalizeMessage(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "timestamp and numbers",
+			input: "INFO  [CompactionExecutor:12] 2023-07-05 13:03:37,128  CompactionTask.java:241 - Compacted 4 sstables",
+			want:  "INFO [CompactionExecutor:<n>] <date> CompactionTask.java:<n> - Compacted <n> sstables",
+		},
+		{
+			name:  "ip address and uuid",
+			input: "Handshaking version with /10.0.0.1:7000 for table 5bc52802-de25-35ed-aeab-188eecebb090",
+			want:  "Handshaking version with <ip> for table <uuid>",
+		},
+		{
+			name:  "hex value",
+			input: "Allocated buffer at 0x7f3a2c",
+			want:  "Allocated buffer at <hex>",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := NormalizeMessage(tc.input); got != tc.want {
+				t.Errorf("NormalizeMessage() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	entry1 := &LogEntry{LogLevel: WARN, Message: "WARN  [main] 2023-07-05 13:03:37,128 Gossiper.java:1200 - Node /10.0.0.1 is down"}
+	entry2 := &LogEntry{LogLevel: WARN, Message: "WARN  [main] 2023-07-06 09:00:00,000 Gossiper.java:1200 - Node /10.0.0.2 is down"}
+	entry3 := &LogEntry{LogLevel: ERROR, Message: entry1.Message}
+
+	if entry1.Hash() != entry2.Hash() {
+		t.Errorf("Expected entries differing only by variable parts to share a hash")
+	}
+	if entry1.Hash() == entry3.Hash() {
+		t.Errorf("Expected entries with different log levels to have different hashes")
+	}
+}
@@ -0,0 +1,249 @@
+// Package wetlog parses Cassandra nodetool status output and system.log files into nodes and log entries.
+package wetlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LogLevel represents a log level as an iota integer constant. The iota starts at 0 and increments by 1 for each LogLevel higher.
+type LogLevel int
+
+const (
+	// DEBUG is the lowest log level in Cassandra and will have detailed information about Cassandra actions.
+	DEBUG LogLevel = iota // 0
+	// INFO is the second lowest log level and is typically used in Cassandra for informative actions.
+	INFO // 1
+	// WARN in Cassandra is used to indicate that something is not right, but Cassandra can still function.
+	WARN // 2
+	// ERROR is the highest log level in Cassandra and is used to indicate that the particular action taken by cassandra has failed.
+	ERROR //	3
+)
+
+// levelNames maps each LogLevel to the name Cassandra uses for it in the logs.
+var levelNames = map[LogLevel]string{
+	DEBUG: "DEBUG",
+	INFO:  "INFO",
+	WARN:  "WARN",
+	ERROR: "ERROR",
+}
+
+// ParseLogLevel parses a log level string into an iota.
+func ParseLogLevel(logLevelStr string) (LogLevel, error) {
+	switch logLevelStr {
+	case "DEBUG":
+		return DEBUG, nil
+	case "INFO":
+		return INFO, nil
+	case "WARN":
+		return WARN, nil
+	case "ERROR":
+		return ERROR, nil
+	default:
+		return 0, fmt.Errorf("Invalid log level: %s", logLevelStr)
+	}
+}
+
+// String returns the name of the log level as ParseLogLevel accepts it, e.g. "INFO", or "UNKNOWN(n)" for a value
+// outside of the known levels.
+func (l LogLevel) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", int(l))
+}
+
+// MarshalJSON encodes the log level as its name, e.g. "WARN".
+func (l LogLevel) MarshalJSON() ([]byte, error) {
+	name, ok := levelNames[l]
+	if !ok {
+		return nil, fmt.Errorf("Invalid log level: %d", int(l))
+	}
+	return json.Marshal(name)
+}
+
+// UnmarshalJSON decodes a log level from either its name, e.g. "WARN", or its integer value, e.g. 2. A null level is
+// rejected rather than decoded as DEBUG.
+func (l *LogLevel) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return fmt.Errorf("Invalid log level: null")
+	}
+	var value int
+	if err := json.Unmarshal(data, &value); err == nil {
+		if _, ok := levelNames[LogLevel(value)]; !ok {
+			return fmt.Errorf("Invalid log level: %d", value)
+		}
+		*l = LogLevel(value)
+		return nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("Invalid log level: %s", data)
+	}
+	level, err := ParseLogLevel(name)
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// LogEntry represents a log entry.
+type LogEntry struct {
+	LogLevel   LogLevel  `json:"level"`               // LogLevel is the log level of the entry.
+	Date       time.Time `json:"date"`                // Date is the date of the entry.
+	LineNumber int       `json:"line_number"`         // LineNumber is the line number of the entry.
+	NodeIP     string    `json:"node_ip"`             // NodeIP is the IP address of the node that generated the entry.
+	Datacenter string    `json:"datacenter"`          // Datacenter is the datacenter of the node that generated the entry.
+	FilePath   string    `json:"file_path"`           // FilePath is the path to the log file that generated the entry.
+	Message    string    `json:"message"`             // Message is the message of the entry.
+	RawLine    string    `json:"-"`                   // RawLine holds the lines of the entry as read from the log file.
+	LineCount  int       `json:"line_count"`          // LineCount is the number of lines of the entry, its first line included.
+	Count      int       `json:"count,omitempty"`     // Count is the number of occurrences collapsed into the entry by deduplication.
+	PrevLine   int       `json:"prev_line,omitempty"` // PrevLine is the line number of the previous entry of the same file, if any.
+	NextLine   int       `json:"next_line,omitempty"` // NextLine is the line number of the next entry of the same file, if any.
+
+	Metrics map[string]float64 `json:"metrics,omitempty"` // Metrics holds the values extracted by the selected metric patterns.
+	Fields  map[string]string  `json:"fields,omitempty"`  // Fields holds the key=value pairs of the message body, parsed with -kv.
+}
+
+// LogEntries is a pointer to a slice of LogEntry.
+type LogEntries []*LogEntry
+
+// Len returns the length of the LogEntries slice.
+func (s LogEntries) Len() int { return len(s) }
+
+// Swap swaps the elements at the given indices.
+func (s LogEntries) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// ByDate sorts LogEntries by date, see DateLess.
+type ByDate struct{ LogEntries }
+
+// ByLogLevel sorts LogEntries by log level, then by date.
+type ByLogLevel struct{ LogEntries }
+
+// ByLineNumber sorts LogEntries by line number, then by date.
+type ByLineNumber struct{ LogEntries }
+
+// ByLineCount sorts LogEntries by the number of lines of each entry, then by date.
+type ByLineCount struct{ LogEntries }
+
+// ByNodeIP sorts LogEntries by node IP, then by date.
+type ByNodeIP struct{ LogEntries }
+
+// Less returns true if the date of the LogEntry at index i is before the date of the LogEntry at index j.
+func (s ByDate) Less(i, j int) bool { return DateLess(s.LogEntries[i], s.LogEntries[j]) }
+
+// Less returns true if the log level of the LogEntry at index i is before the log level of the LogEntry at index j.
+func (s ByLogLevel) Less(i, j int) bool {
+	if s.LogEntries[i].LogLevel != s.LogEntries[j].LogLevel {
+		return s.LogEntries[i].LogLevel < s.LogEntries[j].LogLevel
+	}
+	return DateLess(s.LogEntries[i], s.LogEntries[j])
+}
+
+// Less returns true if the line number of the LogEntry at index i is before the line number of the LogEntry at index j.
+func (s ByLineNumber) Less(i, j int) bool {
+	if s.LogEntries[i].LineNumber != s.LogEntries[j].LineNumber {
+		return s.LogEntries[i].LineNumber < s.LogEntries[j].LineNumber
+	}
+	return DateLess(s.LogEntries[i], s.LogEntries[j])
+}
+
+// Less returns true if the LogEntry at index i has fewer lines than the LogEntry at index j.
+func (s ByLineCount) Less(i, j int) bool {
+	if s.LogEntries[i].LineCount != s.LogEntries[j].LineCount {
+		return s.LogEntries[i].LineCount < s.LogEntries[j].LineCount
+	}
+	return DateLess(s.LogEntries[i], s.LogEntries[j])
+}
+
+// Less returns true if the node IP of the LogEntry at index i is before the node IP of the LogEntry at index j.
+func (s ByNodeIP) Less(i, j int) bool {
+	a, b := s.LogEntries[i], s.LogEntries[j]
+	if a.NodeIP != b.NodeIP {
+		return NodeIPLess(a.NodeIP, b.NodeIP, net.ParseIP(a.NodeIP), net.ParseIP(b.NodeIP))
+	}
+	return DateLess(a, b)
+}
+
+// DateLess orders entries by date. Entries logged at the same time, which is common at millisecond resolution, are
+// ordered by node IP, then line number, then file path, so that sorting gives the same order on every run whatever the
+// order the nodes were scanned in. The other sorters fall back to it when their own key is equal.
+func DateLess(a, b *LogEntry) bool {
+	switch {
+	case !a.Date.Equal(b.Date):
+		return a.Date.Before(b.Date)
+	case a.NodeIP != b.NodeIP:
+		return NodeIPLess(a.NodeIP, b.NodeIP, net.ParseIP(a.NodeIP), net.ParseIP(b.NodeIP))
+	case a.LineNumber != b.LineNumber:
+		return a.LineNumber < b.LineNumber
+	default:
+		return a.FilePath < b.FilePath
+	}
+}
+
+// NodeIPLess orders node addresses by their parsed IP. Addresses that aren't valid IPs (e.g. hostnames) always sort after
+// valid IPs and are ordered lexicographically among themselves, so a single sort never mixes both schemes.
+func NodeIPLess(addr1, addr2 string, ip1, ip2 net.IP) bool {
+	switch {
+	case ip1 != nil && ip2 != nil:
+		return bytes.Compare(ip1.To16(), ip2.To16()) < 0
+	case ip1 != nil:
+		return true
+	case ip2 != nil:
+		return false
+	default:
+		return strings.Compare(addr1, addr2) < 0
+	}
+}
+
+// messageBodyRegex matches the prefix of a log line up to the "- " that follows the time and the source token, e.g.
+// "... 2023-07-14 16:00:00,000 Server.java:10 - ". Anchoring on the source token keeps dashes in thread names, such as
+// "[CompactionExecutor - 1]", from being taken as the separator.
+var messageBodyRegex = regexp.MustCompile(`\d{2}:\d{2}:\d{2}[,.]\d{3}(?:Z|[+-]\d{2}:?\d{2})?[ \t]+\S+[ \t]+-[ \t]?`)
+
+// Body returns the message of the entry without the level, thread, date and source prefix of its first line. Entries
+// without a source token followed by "- " are returned whole.
+func (e *LogEntry) Body() string {
+	loc := messageBodyRegex.FindStringIndex(e.Message)
+	if loc == nil {
+		return e.Message
+	}
+	return e.Message[loc[1]:]
+}
+
+// Raw returns the lines of the entry as read from the log file, or its message for entries that weren't parsed from
+// one, e.g. loaded with -input-json.
+func (e *LogEntry) Raw() string {
+	if e.RawLine == "" {
+		return e.Message
+	}
+	return e.RawLine
+}
+
+// sourceTokenRegex matches the source token between the time and the "- " separator, e.g. "GCInspector.java:282".
+var sourceTokenRegex = regexp.MustCompile(`\d{2}:\d{2}:\d{2}[,.]\d{3}(?:Z|[+-]\d{2}:?\d{2})?[ \t]+(\S+)[ \t]+-`)
+
+// Source returns the source token of the entry, the file and line that logged it, e.g. "GCInspector.java:282", or an
+// empty string if the message has none.
+func (e *LogEntry) Source() string {
+	match := sourceTokenRegex.FindStringSubmatch(e.Message)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// SourceFile returns the source file that logged the entry without its line number, e.g. "GCInspector.java", or an
+// empty string if the message has no source token.
+func (e *LogEntry) SourceFile() string {
+	file, _, _ := strings.Cut(e.Source(), ":")
+	return file
+}
@@ -0,0 +1,380 @@
+package wetlog
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogLevelString(t *testing.T) {
+	for _, level := range []LogLevel{DEBUG, INFO, WARN, ERROR} {
+		got, err := ParseLogLevel(level.String())
+		if err != nil {
+			t.Fatalf("ParseLogLevel(%q) error = %v", level.String(), err)
+		}
+		if got != level {
+			t.Errorf("Expected ParseLogLevel(%q) = %d, got %d", level.String(), int(level), int(got))
+		}
+	}
+
+	if got := LogLevel(7).String(); got != "UNKNOWN(7)" {
+		t.Errorf("Expected UNKNOWN(7) for an out-of-range level, got %q", got)
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	testCases := []struct {
+		name    string
+		input   string
+		want    LogLevel
+		wantErr bool
+	}{
+		{
+			name:    "DEBUG log level",
+			input:   "DEBUG",
+			want:    DEBUG,
+			wantErr: false,
+		},
+		{
+			name:    "INFO log level",
+			input:   "INFO",
+			want:    INFO,
+			wantErr: false,
+		},
+		{
+			name:    "WARN log level",
+			input:   "WARN",
+			want:    WARN,
+			wantErr: false,
+		},
+		{
+			name:    "ERROR log level",
+			input:   "ERROR",
+			want:    ERROR,
+			wantErr: false,
+		},
+		{
+			name:    "Invalid log level",
+			input:   "INVALID",
+			want:    0,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseLogLevel(tc.input)
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseLogLevel() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if got != tc.want {
+				t.Errorf("parseLogLevel() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestLen tests the Len() method of the LogEntries.
+func TestLen(t *testing.T) {
+	entry1 := &LogEntry{
+		LogLevel:   DEBUG,
+		Date:       time.Date(2023, 7, 14, 0, 0, 0, 0, time.UTC),
+		LineNumber: 1,
+		NodeIP:     "192.168.1.1",
+		FilePath:   "/var/log/test.log",
+		Message:    "Debug message 1",
+	}
+	entry2 := &LogEntry{
+		LogLevel:   INFO,
+		Date:       time.Date(2023, 7, 14, 1, 0, 0, 0, time.UTC),
+		LineNumber: 2,
+		NodeIP:     "192.168.1.2",
+		FilePath:   "/var/log/test.log",
+		Message:    "Info message 2",
+	}
+	logEntries := LogEntries{entry2, entry1}
+
+	if logEntries.Len() != 2 {
+		t.Fatalf("Expected Len() to return 2, but got %v", logEntries.Len())
+	}
+}
+
+// TestSwap tests the Swap() method of the LogEntries.
+func TestSwap(t *testing.T) {
+
+	entry1 := &LogEntry{
+		LogLevel:   DEBUG,
+		Date:       time.Date(2023, 7, 14, 0, 0, 0, 0, time.UTC),
+		LineNumber: 1,
+		NodeIP:     "192.168.1.1",
+		FilePath:   "/var/log/test.log",
+		Message:    "Debug message 1",
+	}
+	entry2 := &LogEntry{
+		LogLevel:   INFO,
+		Date:       time.Date(2023, 7, 14, 1, 0, 0, 0, time.UTC),
+		LineNumber: 2,
+		NodeIP:     "192.168.1.2",
+		FilePath:   "/var/log/test.log",
+		Message:    "Info message 2",
+	}
+
+	logEntries := LogEntries{entry2, entry1}
+	logEntries.Swap(0, 1)
+	if logEntries[0].Message != "Debug message 1" || logEntries[1].Message != "Info message 2" {
+		t.Fatalf("Swap() did not swap the entries correctly")
+	}
+}
+
+// TestByDate tests the sorting of LogEntries by date.
+func TestByDate(t *testing.T) {
+
+	entry1 := &LogEntry{
+		LogLevel:   DEBUG,
+		Date:       time.Date(2023, 7, 14, 0, 0, 0, 0, time.UTC),
+		LineNumber: 1,
+		NodeIP:     "192.168.1.1",
+		FilePath:   "/var/log/test.log",
+		Message:    "Debug message 1",
+	}
+	entry2 := &LogEntry{
+		LogLevel:   INFO,
+		Date:       time.Date(2023, 7, 14, 1, 0, 0, 0, time.UTC),
+		LineNumber: 2,
+		NodeIP:     "192.168.1.2",
+		FilePath:   "/var/log/test.log",
+		Message:    "Info message 2",
+	}
+
+	logEntries := LogEntries{entry2, entry1}
+	sort.Sort(ByDate{logEntries})
+	if !logEntries[0].Date.Before(logEntries[1].Date) {
+		t.Fatalf("ByDate sort failed")
+	}
+}
+
+// TestByLogLevel tests the sorting of LogEntries by log level.
+func TestByLogLevel(t *testing.T) {
+
+	entry1 := &LogEntry{
+		LogLevel:   DEBUG,
+		Date:       time.Date(2023, 7, 14, 0, 0, 0, 0, time.UTC),
+		LineNumber: 1,
+		NodeIP:     "192.168.1.1",
+		FilePath:   "/var/log/test.log",
+		Message:    "Debug message 1",
+	}
+	entry2 := &LogEntry{
+		LogLevel:   INFO,
+		Date:       time.Date(2023, 7, 14, 1, 0, 0, 0, time.UTC),
+		LineNumber: 2,
+		NodeIP:     "192.168.1.2",
+		FilePath:   "/var/log/test.log",
+		Message:    "Info message 2",
+	}
+
+	logEntries := LogEntries{entry2, entry1}
+	sort.Sort(ByLogLevel{logEntries})
+	if logEntries[0].LogLevel > logEntries[1].LogLevel {
+		t.Fatalf("ByLogLevel sort failed")
+	}
+}
+
+// TestByLineNumber tests the sorting of LogEntries by line number.
+func TestByLineNumber(t *testing.T) {
+
+	entry1 := &LogEntry{
+		LogLevel:   DEBUG,
+		Date:       time.Date(2023, 7, 14, 0, 0, 0, 0, time.UTC),
+		LineNumber: 1,
+		NodeIP:     "192.168.1.1",
+		FilePath:   "/var/log/test.log",
+		Message:    "Debug message 1",
+	}
+	entry2 := &LogEntry{
+		LogLevel:   INFO,
+		Date:       time.Date(2023, 7, 14, 1, 0, 0, 0, time.UTC),
+		LineNumber: 2,
+		NodeIP:     "192.168.1.2",
+		FilePath:   "/var/log/test.log",
+		Message:    "Info message 2",
+	}
+
+	logEntries := LogEntries{entry2, entry1}
+	sort.Sort(ByLineNumber{logEntries})
+	if logEntries[0].LineNumber > logEntries[1].LineNumber {
+		t.Fatalf("ByLineNumber sort failed")
+	}
+}
+
+// TestByNodeIP tests the sorting of LogEntries by node IP.
+func TestByNodeIP(t *testing.T) {
+
+	entry1 := &LogEntry{
+		LogLevel:   DEBUG,
+		Date:       time.Date(2023, 7, 14, 0, 0, 0, 0, time.UTC),
+		LineNumber: 1,
+		NodeIP:     "192.168.1.1",
+		FilePath:   "/var/log/test.log",
+		Message:    "Debug message 1",
+	}
+	entry2 := &LogEntry{
+		LogLevel:   INFO,
+		Date:       time.Date(2023, 7, 14, 1, 0, 0, 0, time.UTC),
+		LineNumber: 2,
+		NodeIP:     "192.168.1.2",
+		FilePath:   "/var/log/test.log",
+		Message:    "Info message 2",
+	}
+
+	logEntries := LogEntries{entry2, entry1}
+	sort.Sort(ByNodeIP{logEntries})
+	if logEntries[0].NodeIP > logEntries[1].NodeIP {
+		t.Fatalf("ByNodeIP sort failed")
+	}
+}
+
+func TestLogEntryBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "plain",
+			message: "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting",
+			want:    "Starting",
+		},
+		{
+			name:    "thread name with separator",
+			message: "INFO  [CompactionExecutor - 1] 2023-07-14 16:00:00,000 CompactionTask.java:255 - Compacted 4 sstables",
+			want:    "Compacted 4 sstables",
+		},
+		{
+			name:    "thread name with dash",
+			message: "INFO  [Solr TTL scheduler-0] 2023-07-05 13:03:37,128  AbstractSolrSecondaryIndex.java:1964 - Expired 3 documents",
+			want:    "Expired 3 documents",
+		},
+		{
+			name:    "source with dash",
+			message: "WARN  [main] 2023-07-14 16:00:00,000 dse-core.java:42 - Slow query - 500ms",
+			want:    "Slow query - 500ms",
+		},
+		{
+			name:    "multi-line",
+			message: "ERROR [main] 2023-07-14 16:00:00,000 Server.java:10 - Failed\n\tat org.apache.cassandra - Server",
+			want:    "Failed\n\tat org.apache.cassandra - Server",
+		},
+		{
+			name:    "no source token",
+			message: "WARN  2023-04-24 12:12:32,430 org.apache.hadoop.hive.conf.HiveConf: HiveConf expects INT type value",
+			want:    "WARN  2023-04-24 12:12:32,430 org.apache.hadoop.hive.conf.HiveConf: HiveConf expects INT type value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &LogEntry{Message: tt.message}
+			if got := entry.Body(); got != tt.want {
+				t.Errorf("Body() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogLevelUnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		name    string
+		input   string
+		want    LogLevel
+		wantErr bool
+	}{
+		{name: "name", input: `"WARN"`, want: WARN},
+		{name: "integer", input: `3`, want: ERROR},
+		{name: "zero integer", input: `0`, want: DEBUG},
+		{name: "unknown name", input: `"FATAL"`, wantErr: true},
+		{name: "out of range integer", input: `7`, wantErr: true},
+		{name: "wrong type", input: `true`, wantErr: true},
+		{name: "null", input: `null`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got LogLevel
+			err := json.Unmarshal([]byte(tc.input), &got)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("UnmarshalJSON() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && got != tc.want {
+				t.Errorf("UnmarshalJSON() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSourceFile(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{message: "INFO  [main] 2023-07-14 16:00:00,000 StorageService.java:1200 - Starting", want: "StorageService.java"},
+		{message: "WARN  [CompactionExecutor - 1] 2023-07-14T16:00:00.000Z CompactionTask.java:250 - Slow", want: "CompactionTask.java"},
+		{message: "INFO  [main] 2023-07-14 16:00:00,000 - No source", want: ""},
+	}
+
+	for _, tt := range tests {
+		entry := &LogEntry{Message: tt.message}
+		if got := entry.SourceFile(); got != tt.want {
+			t.Errorf("SourceFile() of %q = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestSortTieBreaks(t *testing.T) {
+	date := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	later := date.Add(time.Millisecond)
+	// every entry shares its primary key with another one
+	a := &LogEntry{Message: "a", LogLevel: WARN, Date: date, NodeIP: "10.0.0.2", LineNumber: 7, FilePath: "system.log", LineCount: 1}
+	b := &LogEntry{Message: "b", LogLevel: WARN, Date: date, NodeIP: "10.0.0.10", LineNumber: 3, FilePath: "system.log", LineCount: 1}
+	c := &LogEntry{Message: "c", LogLevel: INFO, Date: date, NodeIP: "10.0.0.10", LineNumber: 7, FilePath: "debug.log", LineCount: 2}
+	d := &LogEntry{Message: "d", LogLevel: INFO, Date: date, NodeIP: "10.0.0.10", LineNumber: 7, FilePath: "system.log", LineCount: 2}
+	e := &LogEntry{Message: "e", LogLevel: WARN, Date: later, NodeIP: "10.0.0.2", LineNumber: 3, FilePath: "system.log", LineCount: 1}
+
+	tests := []struct {
+		name string
+		sort func(LogEntries) sort.Interface
+		want LogEntries
+	}{
+		{name: "ByDate", sort: func(s LogEntries) sort.Interface { return ByDate{s} }, want: LogEntries{a, b, c, d, e}},
+		{name: "ByLogLevel", sort: func(s LogEntries) sort.Interface { return ByLogLevel{s} }, want: LogEntries{c, d, a, b, e}},
+		{name: "ByLineNumber", sort: func(s LogEntries) sort.Interface { return ByLineNumber{s} }, want: LogEntries{b, e, a, c, d}},
+		{name: "ByLineCount", sort: func(s LogEntries) sort.Interface { return ByLineCount{s} }, want: LogEntries{a, b, e, c, d}},
+		{name: "ByNodeIP", sort: func(s LogEntries) sort.Interface { return ByNodeIP{s} }, want: LogEntries{a, e, b, c, d}},
+	}
+
+	inputs := []LogEntries{{a, b, c, d, e}, {e, d, c, b, a}, {c, a, e, b, d}, {d, e, b, a, c}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, input := range inputs {
+				entries := append(LogEntries(nil), input...)
+				sort.Sort(tt.sort(entries))
+				if got, want := entryMessages(entries), entryMessages(tt.want); got != want {
+					t.Fatalf("Sorting %s gave %s, want %s", entryMessages(input), got, want)
+				}
+			}
+		})
+	}
+}
+
+// entryMessages joins the messages of entries, to compare their order.
+func entryMessages(entries LogEntries) string {
+	var b strings.Builder
+	for _, entry := range entries {
+		b.WriteString(entry.Message)
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+// NodeMatchCount is the number of entries of a node that match the filters.
+type NodeMatchCount struct {
+	Address string // Address is the address of the node.
+	Count   int    // Count is the number of matching entries.
+}
+
+// previewMatches counts, per node and sorted by address, the entries matching the queries and opts that remain after
+// filter, which is applied to the entries of all nodes at once like to the printed ones, e.g. by -dedup and -tail.
+func previewMatches(ctx context.Context, nodes []Node, topLevelDir string, queries []string, opts pipeline.ScanOptions, filter func(LogEntries) LogEntries) []NodeMatchCount {
+	nodeEntries := pipeline.CollectNodeEntries(ctx, nodes, topLevelDir, queries, opts)
+
+	// entries collapsed by -dedup count for the node of the one kept
+	var entries LogEntries
+	owners := make(map[*LogEntry]string)
+	for _, node := range nodes {
+		for _, entry := range nodeEntries[node.Address] {
+			owners[entry] = node.Address
+			entries = append(entries, entry)
+		}
+	}
+	matches := make(map[string]int, len(nodes))
+	for _, entry := range filter(entries) {
+		matches[owners[entry]]++
+	}
+
+	counts := make([]NodeMatchCount, 0, len(nodes))
+	for _, node := range nodes {
+		counts = append(counts, NodeMatchCount{Address: node.Address, Count: matches[node.Address]})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Address < counts[j].Address
+	})
+	return counts
+}
+
+// PrintMatchPreview writes the match count of each node to w, followed by the total.
+func PrintMatchPreview(w io.Writer, counts []NodeMatchCount) error {
+	total := 0
+	for _, count := range counts {
+		total += count.Count
+		if _, err := fmt.Fprintf(w, "%s: %d\n", count.Address, count.Count); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "total: %d\n", total)
+	return err
+}
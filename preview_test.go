@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+func TestPreviewMatches(t *testing.T) {
+	topLevelDir := t.TempDir()
+	writeSystemLog(t, topLevelDir, "10.0.0.1",
+		"WARN  [main] 2023-07-14 16:00:00,000 Server.java:10 - Timed out\n"+
+			"\tat org.apache.cassandra.Server.run(Server.java:10)\n"+
+			"WARN  [main] 2023-07-14 16:00:01,000 Server.java:10 - Timed out\n"+
+			"INFO  [main] 2023-07-14 16:00:02,000 Server.java:10 - Starting\n")
+	writeSystemLog(t, topLevelDir, "10.0.0.2",
+		"WARN  [main] 2023-07-14 16:00:00,000 Server.java:10 - Timed out\n"+
+			"\tat org.apache.cassandra.Server.run(Server.java:10)\n")
+	writeSystemLog(t, topLevelDir, "10.0.0.3", "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n")
+	nodes := []Node{{Address: "10.0.0.3"}, {Address: "10.0.0.1"}, {Address: "10.0.0.2"}}
+	queries := []string{"Timed out"}
+	filter := pipeline.EntryFilters{MinLines: 2}.Apply
+
+	counts := previewMatches(context.Background(), nodes, topLevelDir, queries, pipeline.ScanOptions{}, filter)
+
+	for _, count := range counts {
+		var emitted LogEntries
+		err := pipeline.StreamEntries(context.Background(), []Node{{Address: count.Address}}, topLevelDir, queries, pipeline.ScanOptions{}, pipeline.EntryBufferSize, func(entry *LogEntry) {
+			emitted = append(emitted, entry)
+		})
+		if err != nil {
+			t.Fatalf("pipeline.StreamEntries() error = %v", err)
+		}
+		if want := len(filter(emitted)); count.Count != want {
+			t.Errorf("Expected a preview of %d entries for %s like the emitted entries, got %d", want, count.Address, count.Count)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := PrintMatchPreview(&buf, counts); err != nil {
+		t.Fatalf("PrintMatchPreview() error = %v", err)
+	}
+	if want := "10.0.0.1: 1\n10.0.0.2: 1\n10.0.0.3: 0\ntotal: 2\n"; buf.String() != want {
+		t.Errorf("Expected preview %q, got %q", want, buf.String())
+	}
+}
+
+func TestRunMatchPreviewLikePrinted(t *testing.T) {
+	topLevelDir := t.TempDir()
+	writeSystemLog(t, topLevelDir, "10.0.0.1",
+		"WARN  [main] 2023-07-14 16:00:00,000 Server.java:10 - Timed out\n"+
+			"WARN  [main] 2023-07-14 16:00:05,000 Server.java:10 - Timed out\n"+
+			"INFO  [main] 2023-07-14 16:00:06,000 Server.java:10 - Starting\n")
+	writeSystemLog(t, topLevelDir, "10.0.0.2",
+		"WARN  [main] 2023-07-14 16:00:01,000 Server.java:10 - Timed out\n"+
+			"INFO  [main] 2023-07-14 16:00:07,000 Server.java:10 - Flushed 3 tables\n")
+	nodes := []Node{{Address: "10.0.0.1"}, {Address: "10.0.0.2"}}
+
+	tests := []struct {
+		name    string
+		filters pipeline.EntryFilters
+		tail    int
+		want    string
+	}{
+		{name: "dedup", filters: pipeline.EntryFilters{Dedup: true}, want: "10.0.0.1: 2\n10.0.0.2: 1\ntotal: 3\n"},
+		{name: "dedup window", filters: pipeline.EntryFilters{DedupWindow: 10 * time.Second}, want: "10.0.0.1: 2\n10.0.0.2: 2\ntotal: 4\n"},
+		{name: "tail", tail: 2, want: "10.0.0.1: 1\n10.0.0.2: 1\ntotal: 2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := scanSetup{ctx: context.Background(), nodes: nodes, topLevelDir: topLevelDir, opts: pipeline.ScanOptions{Deterministic: true}, filter: tt.filters.Apply, tail: tt.tail}
+			var buf bytes.Buffer
+			if err := runMatchPreview(s, &buf); err != nil {
+				t.Fatalf("runMatchPreview() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Expected preview %q, got %q", tt.want, buf.String())
+			}
+
+			// the entries printed without -match-preview
+			entries, err := pipeline.ScanEntries(context.Background(), nodes, topLevelDir, nil, s.opts, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			printed := pipeline.TailEntries(tt.filters.Apply(entries), tt.tail)
+			if want := fmt.Sprintf("total: %d\n", len(printed)); !strings.HasSuffix(buf.String(), want) {
+				t.Errorf("Expected the preview to end with %q like the printed entries, got %q", want, buf.String())
+			}
+		})
+	}
+}
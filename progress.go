@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// progressBarWidth is the number of characters between the brackets of the progress bar.
+const progressBarWidth = 40
+
+// Units counted by a progressBar.
+const (
+	progressBytes = "bytes" // progressBytes counts the bytes read from the local log files, see pipeline.LogBytes.
+	progressLogs  = "logs"  // progressLogs counts the remote logs read with -ssh, whose size isn't known up front.
+)
+
+// progressBar draws the share of the logs read on a terminal, redrawing the same line on each update. A nil
+// progressBar draws nothing.
+type progressBar struct {
+	mu    sync.Mutex
+	f     *os.File
+	unit  string
+	total int64
+	done  int64
+}
+
+// newProgressBar returns a progress bar drawn to f out of total units, or nil if terminal reports f isn't a terminal.
+func newProgressBar(f *os.File, total int64, unit string, terminal func(*os.File) bool) *progressBar {
+	if !terminal(f) {
+		return nil
+	}
+	return &progressBar{f: f, unit: unit, total: total}
+}
+
+// Add records n more units read and redraws the bar. It is safe for concurrent use.
+func (p *progressBar) Add(n int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+	p.draw()
+}
+
+// Finish ends the line of the progress bar.
+func (p *progressBar) Finish() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, _ = fmt.Fprintln(p.f)
+}
+
+func (p *progressBar) draw() {
+	percent := int64(100)
+	// a log growing during the scan may be read past the size it had up front
+	if p.total > 0 && p.done < p.total {
+		percent = p.done * 100 / p.total
+	}
+	filled := int(percent) * progressBarWidth / 100
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	_, _ = fmt.Fprintf(p.f, "\r[%s] %3d%% (%d/%d %s)", bar, percent, p.done, p.total, p.unit)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+func TestProgressBar(t *testing.T) {
+	tests := []struct {
+		name     string
+		terminal bool
+		adds     []int64
+		want     string
+	}{
+		{name: "terminal", terminal: true, adds: []int64{100, 100}, want: "\r[" + strings.Repeat("=", 20) + strings.Repeat(" ", 20) + "]  50% (100/200 bytes)" +
+			"\r[" + strings.Repeat("=", 40) + "] 100% (200/200 bytes)\n"},
+		{name: "read past the total", terminal: true, adds: []int64{300}, want: "\r[" + strings.Repeat("=", 40) + "] 100% (300/200 bytes)\n"},
+		{name: "not a terminal", terminal: false, adds: []int64{100, 100}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "stderr")
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			bar := newProgressBar(f, 200, progressBytes, func(*os.File) bool { return tt.terminal })
+			for _, n := range tt.adds {
+				bar.Add(n)
+			}
+			bar.Finish()
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expected progress output %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProgressBarReport(t *testing.T) {
+	s := newSlowSetup(t, 3, 0, 0)
+	path := filepath.Join(t.TempDir(), "stderr")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	total := pipeline.LogBytes(s.nodes, s.topLevelDir, s.opts)
+	s.progress = newProgressBar(f, total, progressBytes, func(*os.File) bool { return true })
+	s.opts.Progress = s.progress.Add
+
+	if err := runGroupByNode(s, &bytes.Buffer{}, FormatOptions{Format: FormatText}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("] 100%% (%d/%d bytes)\n", total, total); !strings.HasSuffix(string(got), want) {
+		t.Errorf("Expected the progress bar to end with %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github/kenjords/wetlog/pkg/wetlog"
+)
+
+// relevanceScore returns the total number of occurrences of the query terms in message, ignoring case if ignoreCase is
+// true.
+func relevanceScore(message string, queries []string, ignoreCase bool) int {
+	if ignoreCase {
+		message = strings.ToLower(message)
+	}
+	score := 0
+	for _, query := range queries {
+		if ignoreCase {
+			query = strings.ToLower(query)
+		}
+		if query != "" {
+			score += strings.Count(message, query)
+		}
+	}
+	return score
+}
+
+// relevanceRegexScore returns the total number of hits of the -regex expressions in message. Empty matches don't count.
+func relevanceRegexScore(message string, regexes []*regexp.Regexp) int {
+	score := 0
+	for _, re := range regexes {
+		for _, span := range re.FindAllStringIndex(message, -1) {
+			if span[0] != span[1] {
+				score++
+			}
+		}
+	}
+	return score
+}
+
+// byRelevance sorts LogEntries by their relevance score, highest first, then by date.
+type byRelevance struct {
+	LogEntries
+	scores map[*LogEntry]int
+}
+
+// Less returns true if the LogEntry at index i has a higher relevance score than the LogEntry at index j.
+func (s byRelevance) Less(i, j int) bool {
+	score1, score2 := s.scores[s.LogEntries[i]], s.scores[s.LogEntries[j]]
+	if score1 != score2 {
+		return score1 > score2
+	}
+	return wetlog.DateLess(s.LogEntries[i], s.LogEntries[j])
+}
+
+// newRelevanceSorter returns the sort.Interface ordering entries by the score of their message, e.g. its number of
+// query term occurrences from relevanceScore.
+func newRelevanceSorter(entries LogEntries, score func(message string) int) sort.Interface {
+	scores := make(map[*LogEntry]int, len(entries))
+	for _, entry := range entries {
+		scores[entry] = score(entry.Message)
+	}
+	return byRelevance{entries, scores}
+}
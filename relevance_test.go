@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+func TestRelevanceSorter(t *testing.T) {
+	entries := LogEntries{
+		{LineNumber: 1, Message: "Read timeout"},
+		{LineNumber: 2, Message: "Read timeout after timeout, dropping read"},
+		{LineNumber: 3, Message: "Compacted 4 sstables"},
+		{LineNumber: 4, Message: "Write timeout, read repair"},
+		{LineNumber: 5, Message: "Another timeout"},
+	}
+	queries := []string{"timeout", "read"}
+
+	score := func(message string) int { return relevanceScore(message, queries, false) }
+	sortWith(func(e LogEntries) sort.Interface { return newRelevanceSorter(e, score) }, false)(entries)
+
+	var got []int
+	for _, entry := range entries {
+		got = append(got, entry.LineNumber)
+	}
+	// "timeout" twice and "read" once beats one of each, ties keep their order
+	if want := []int{2, 4, 1, 5, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected line order %v, got %v", want, got)
+	}
+}
+
+func TestRelevanceScore(t *testing.T) {
+	if got := relevanceScore("timeout timeout read", []string{"timeout", "read", ""}, false); got != 3 {
+		t.Errorf("relevanceScore() = %d, want 3", got)
+	}
+}
+
+func TestRelevanceScoreIgnoreCase(t *testing.T) {
+	if got := relevanceScore("Timeout TIMEOUT timeout", []string{"timeout"}, true); got != 3 {
+		t.Errorf("relevanceScore() = %d, want 3", got)
+	}
+	if got := relevanceScore("Timeout TIMEOUT timeout", []string{"timeout"}, false); got != 1 {
+		t.Errorf("relevanceScore() = %d, want 1", got)
+	}
+}
+
+func TestRelevanceRegexScore(t *testing.T) {
+	regexes := []*regexp.Regexp{regexp.MustCompile(`GC in \d+ms`), regexp.MustCompile(`(?i)pause`), regexp.MustCompile(`x*`)}
+	if got := relevanceRegexScore("GC in 250ms, Pause of GC in 300ms after pause", regexes); got != 4 {
+		t.Errorf("relevanceRegexScore() = %d, want 4", got)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultRestartThreshold is the default number of startups within the -restart-loops window that flags a node.
+const defaultRestartThreshold = 3
+
+// startupBannerRegex matches the version banner Cassandra logs once every time it starts.
+var startupBannerRegex = regexp.MustCompile(`\bCassandra version: `)
+
+// RestartLoop is a series of startups of a node close enough together to look like a crash loop.
+type RestartLoop struct {
+	Starts []time.Time // Starts holds the dates of the startups of the loop, in date order.
+}
+
+// Duration returns the time between the first and the last startup of the loop.
+func (l RestartLoop) Duration() time.Duration { return l.Starts[len(l.Starts)-1].Sub(l.Starts[0]) }
+
+// startupDates returns the dates of the startup banners among the entries of a node, in date order.
+func startupDates(entries LogEntries) []time.Time {
+	var starts []time.Time
+	for _, entry := range entries {
+		if startupBannerRegex.MatchString(entry.Message) {
+			starts = append(starts, entry.Date)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	return starts
+}
+
+// findRestartLoops returns the restart loops of a node: the startups falling in a sliding window of the given width
+// holding at least threshold of them. Overlapping windows are merged into a single loop.
+func findRestartLoops(entries LogEntries, window time.Duration, threshold int) []RestartLoop {
+	starts := startupDates(entries)
+
+	// flag every startup of a window reaching the threshold
+	flagged := make([]bool, len(starts))
+	for first, last := 0, 0; last < len(starts); last++ {
+		for starts[last].Sub(starts[first]) > window {
+			first++
+		}
+		if last-first+1 >= threshold {
+			for i := first; i <= last; i++ {
+				flagged[i] = true
+			}
+		}
+	}
+
+	var loops []RestartLoop
+	var current []time.Time
+	for i, start := range starts {
+		if !flagged[i] || (len(current) > 0 && start.Sub(current[len(current)-1]) > window) {
+			if len(current) > 0 {
+				loops = append(loops, RestartLoop{Starts: current})
+			}
+			current = nil
+		}
+		if flagged[i] {
+			current = append(current, start)
+		}
+	}
+	if len(current) > 0 {
+		loops = append(loops, RestartLoop{Starts: current})
+	}
+	return loops
+}
+
+// PrintRestartLoops writes the restart loops of each node, sorted by node address, to w.
+func PrintRestartLoops(w io.Writer, nodeEntries map[string]LogEntries, window time.Duration, threshold int) error {
+	addrs := make([]string, 0, len(nodeEntries))
+	for addr := range nodeEntries {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	for _, addr := range addrs {
+		for _, loop := range findRestartLoops(nodeEntries[addr], window, threshold) {
+			starts := make([]string, 0, len(loop.Starts))
+			for _, start := range loop.Starts {
+				starts = append(starts, start.Format(time.RFC3339Nano))
+			}
+			if _, err := fmt.Fprintf(w, "%s: %d restarts in %s: %s\n", addr, len(loop.Starts), loop.Duration(), strings.Join(starts, ", ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+func TestPrintRestartLoops(t *testing.T) {
+	topLevelDir := t.TempDir()
+	nodes := []Node{{Address: "10.0.0.1"}, {Address: "10.0.0.2"}}
+	banner := " StorageService.java:200 - Cassandra version: 4.1.3\n"
+	writeSystemLog(t, topLevelDir, "10.0.0.1",
+		"INFO  [main] 2023-07-14 16:00:00,000"+banner+
+			"ERROR [main] 2023-07-14 16:00:30,000 CassandraDaemon.java:800 - Exception encountered during startup\n"+
+			"INFO  [main] 2023-07-14 16:01:00,000"+banner+
+			"INFO  [main] 2023-07-14 16:02:00,000"+banner+
+			"INFO  [main] 2023-07-14 16:03:30,000"+banner+
+			"INFO  [main] 2023-07-14 18:00:00,000"+banner)
+	// restarts hours apart are not a loop
+	writeSystemLog(t, topLevelDir, "10.0.0.2",
+		"INFO  [main] 2023-07-14 10:00:00,000"+banner+
+			"INFO  [main] 2023-07-14 13:00:00,000"+banner+
+			"INFO  [main] 2023-07-14 16:00:00,000"+banner)
+
+	nodeEntries := pipeline.CollectNodeEntries(context.Background(), nodes, topLevelDir, nil, pipeline.ScanOptions{})
+
+	loops := findRestartLoops(nodeEntries["10.0.0.1"], 5*time.Minute, 3)
+	if len(loops) != 1 {
+		t.Fatalf("Expected 1 restart loop, got %d", len(loops))
+	}
+	if len(loops[0].Starts) != 4 || loops[0].Duration() != 3*time.Minute+30*time.Second {
+		t.Errorf("Expected 4 restarts over 3m30s, got %d over %v", len(loops[0].Starts), loops[0].Duration())
+	}
+	if loops := findRestartLoops(nodeEntries["10.0.0.1"], 5*time.Minute, 5); len(loops) != 0 {
+		t.Errorf("Expected no restart loop above the number of restarts, got %d", len(loops))
+	}
+
+	var buf bytes.Buffer
+	if err := PrintRestartLoops(&buf, nodeEntries, 5*time.Minute, 3); err != nil {
+		t.Fatalf("PrintRestartLoops() error = %v", err)
+	}
+	want := "10.0.0.1: 4 restarts in 3m30s: 2023-07-14T16:00:00Z, 2023-07-14T16:01:00Z, 2023-07-14T16:02:00Z, 2023-07-14T16:03:30Z\n"
+	if buf.String() != want {
+		t.Errorf("PrintRestartLoops() = %q, want %q", buf.String(), want)
+	}
+}
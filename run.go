@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github/kenjords/wetlog/internal/pipeline"
+	"github/kenjords/wetlog/pkg/wetlog"
+)
+
+// runConfig holds the flags parsed by main that decide which nodes scanSetup.run scans and what it prints.
+type runConfig struct {
+	OutPath          string                        // OutPath is the -out file, empty for stdout.
+	Color            string                        // Color is auto, always or never, see -color.
+	Format           FormatOptions                 // Format controls how the entries are printed, its Color is set by run.
+	OutputBufferSize int                           // OutputBufferSize is the size in bytes of the output buffer, see -output-buffer-size.
+	Output           string                        // Output is OutputText or OutputJSON, see -output.
+	Limit            *throttle                     // Limit is the -throttle of the printed entries, nil for none.
+	InputJSON        string                        // InputJSON is the -input-json file read instead of scanning logs.
+	NodetoolFile     string                        // NodetoolFile is the -file nodetool status output.
+	ValidateStatus   bool                          // ValidateStatus exits with status 1 on an inconsistent nodetool status, see -validate-status.
+	ListDCs          bool                          // ListDCs prints the datacenters instead of scanning, see -list-dcs.
+	ListNodes        bool                          // ListNodes prints the selected nodes instead of scanning, see -list-nodes.
+	Selection        pipeline.NodeSelection        // Selection selects the nodes whose logs are scanned.
+	Dirs             []string                      // Dirs are the top-level directories, a second one with -diff.
+	NodePaths        map[string]string             // NodePaths are the -node-path log files, preferred to -recursive ones.
+	Recursive        bool                          // Recursive finds the log files of the nodes anywhere under the directory, see -recursive.
+	ErrorsOut        string                        // ErrorsOut is the -errors-out file, empty for none.
+	ProgressBar      bool                          // ProgressBar draws the progress of the scan on stderr, see -progress-bar.
+	Diff             bool                          // Diff prints the entries unique to each of the two directories, see -diff.
+	Gaps             time.Duration                 // Gaps prints the periods longer than this in which a node logged nothing, see -gaps. 0 for none.
+	RestartLoops     time.Duration                 // RestartLoops prints the nodes restarting too often within this window, see -restart-loops. 0 for none.
+	RestartThreshold int                           // RestartThreshold is the number of restarts making a loop, see -restart-threshold.
+	GroupByNode      bool                          // GroupByNode prints the entries of each node together, see -group-by-node.
+	MatchPreview     bool                          // MatchPreview prints the number of matches per node, see -match-preview.
+	ZeroNodes        bool                          // ZeroNodes prints the scanned nodes without matching entries, see -zero-nodes.
+	Benchmark        bool                          // Benchmark prints the throughput and timings of the scan instead of the entries, see -benchmark.
+	MergeSortBuffer  int                           // MergeSortBuffer is -merge-sort-buffer in MiB, used if StreamsSorted.
+	StreamsSorted    bool                          // StreamsSorted is true if the entries can be printed as they are sorted.
+	WarnFuture       bool                          // WarnFuture warns about entries dated in the future, see -warn-on-future-dates.
+	DropFuture       bool                          // DropFuture drops the entries dated in the future, see -drop-future.
+	FailLevel        *LogLevel                     // FailLevel is the -fail-level, nil for none.
+	Report           reportModes                   // Report selects the report printed instead of the entries.
+	FirstSource      bool                          // FirstSource prints only the earliest entry of each source file, see -first-per-source.
+	Summary          bool                          // Summary prints one JSON object holding a summary and the entries, see -summary.
+	NewSortFunc      func([]Node) func(LogEntries) // NewSortFunc returns the -sort of the entries given the parsed nodes.
+}
+
+// run scans the logs of the nodes cfg selects, or reads the -input-json entries, and prints them or the report cfg
+// selects. It returns the exit status, once the output is closed and the failed nodes are listed.
+func (s scanSetup) run(cfg runConfig) (exitCode int) {
+	// the results go to -out, closed before exiting with exitCode
+	out, err := openOutput(cfg.OutPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if out == os.Stdout {
+			return
+		}
+		if err := out.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+	colorOutput, err := useColor(cfg.Color, out, isTerminal)
+	if err != nil {
+		log.Fatal(err)
+	}
+	formatOpts := cfg.Format
+	formatOpts.Color = colorOutput
+
+	// stop waiting for or printing results as soon as the user interrupts
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	s.ctx = ctx
+
+	// nodes are parsed from the nodetool status output, the load sorter needs them all
+	var nodes []Node
+	var logEntries LogEntries
+	if cfg.InputJSON != "" {
+		logEntries, err = loadJSONEntries(cfg.InputJSON)
+		if err != nil {
+			log.Fatalf("Error while reading entries from %s: %v", cfg.InputJSON, err)
+		}
+	} else {
+		file, err := openNodetoolStatus(cfg.NodetoolFile)
+		if os.IsNotExist(err) {
+			log.Fatalf("File %s does not exist", cfg.NodetoolFile)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer func() {
+			err = file.Close()
+		}()
+
+		nodes, err = wetlog.ParseNodetoolStatus(file)
+		if err != nil {
+			log.Printf("Error while parsing the nodetool status output: %v", err)
+			syscall.Exit(1)
+		}
+
+		if cfg.ValidateStatus {
+			problems := pipeline.ValidateNodes(nodes)
+			for _, problem := range problems {
+				log.Printf("Invalid nodetool status: %v", problem)
+			}
+			if len(problems) > 0 {
+				syscall.Exit(1)
+			}
+		}
+
+		if cfg.ListDCs {
+			if err := PrintDatacenters(out, nodes); err != nil {
+				log.Fatal(err)
+			}
+			return exitCode
+		}
+
+		filteredNodes, err := pipeline.SelectNodes(nodes, cfg.Selection)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if cfg.ListNodes {
+			if err := PrintNodes(out, sortNodesByLoad(filteredNodes)); err != nil {
+				log.Fatal(err)
+			}
+			return exitCode
+		}
+		s.nodes = filteredNodes
+		// the top-level directory is the first argument, none with -ssh
+		if len(cfg.Dirs) > 0 {
+			s.topLevelDir = cfg.Dirs[0]
+		}
+
+		s.opts.NodePaths = cfg.NodePaths
+		if cfg.Recursive {
+			found, err := pipeline.FindNodeLogFiles(s.topLevelDir)
+			if err != nil {
+				log.Fatalf("Error while searching %s for log files: %v", s.topLevelDir, err)
+			}
+			// paths given with -node-path take precedence over discovered ones
+			for address, path := range cfg.NodePaths {
+				found[address] = path
+			}
+			s.opts.NodePaths = found
+		}
+
+		if cfg.ErrorsOut != "" {
+			errorsFile, err := os.Create(cfg.ErrorsOut)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer func() {
+				err = errorsFile.Close()
+			}()
+			s.opts.ErrorsOut = &syncWriter{w: errorsFile}
+		}
+
+		// nodes whose directory isn't named after their address are matched to the directory that looks like theirs
+		resolveNodeDirs := func(dir string) map[string]string {
+			if s.opts.SSH != nil || cfg.Recursive {
+				return nil
+			}
+			nodeDirs, ambiguous, err := pipeline.ReconcileNodeDirs(dir, filteredNodes)
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				log.Fatalf("Error while listing the node directories of %s: %v", dir, err)
+			}
+			addresses := make([]string, 0, len(ambiguous))
+			for address := range ambiguous {
+				addresses = append(addresses, address)
+			}
+			sort.Strings(addresses)
+			for _, address := range addresses {
+				log.Printf("Node %s matches several directories under %s, leaving it unresolved: %s", address, dir, strings.Join(ambiguous[address], ", "))
+			}
+			return nodeDirs
+		}
+		s.opts.NodeDirs = resolveNodeDirs(s.topLevelDir)
+		var afterDir string
+		var afterNodeDirs map[string]string
+		if cfg.Diff {
+			afterDir = cfg.Dirs[1]
+			afterNodeDirs = resolveNodeDirs(afterDir)
+		}
+
+		// the nodes that failed are listed together once the results are printed, rather than interleaved with them
+		s.opts.NodeErrors = &pipeline.NodeErrors{}
+		defer func() {
+			if err := pipeline.PrintFailedNodes(os.Stderr, s.opts.NodeErrors.Errors(), len(filteredNodes)); err != nil {
+				log.Print(err)
+			}
+		}()
+
+		if cfg.ProgressBar && s.opts.SSH != nil {
+			// the size of the remote logs isn't known before they are read
+			total := int64(len(filteredNodes))
+			if cfg.Diff {
+				total *= 2
+			}
+			bar := newProgressBar(os.Stderr, total, progressLogs, isTerminal)
+			s.progress = bar
+			s.opts.NodeDone = func(Node) { bar.Add(1) }
+		} else if cfg.ProgressBar {
+			total := pipeline.LogBytes(filteredNodes, s.topLevelDir, s.opts)
+			if cfg.Diff {
+				afterOpts := s.opts
+				afterOpts.NodeDirs = afterNodeDirs
+				total += pipeline.LogBytes(filteredNodes, afterDir, afterOpts)
+			}
+			if s.progress = newProgressBar(os.Stderr, total, progressBytes, isTerminal); s.progress != nil {
+				s.opts.Progress = s.progress.Add
+			}
+		}
+
+		// the modes below print a report instead of the entries
+		var modeErr error
+		isMode := true
+		switch {
+		case cfg.Diff:
+			modeErr = runDiff(s, out, afterDir, afterNodeDirs)
+		case cfg.Gaps > 0:
+			modeErr = runGaps(s, out, cfg.Gaps)
+		case cfg.RestartLoops > 0:
+			modeErr = runRestartLoops(s, out, cfg.RestartLoops, cfg.RestartThreshold)
+		case cfg.GroupByNode:
+			modeErr = writeBuffered(out, cfg.OutputBufferSize, func(w io.Writer) error { return runGroupByNode(s, w, formatOpts) })
+		case cfg.MatchPreview:
+			modeErr = runMatchPreview(s, out)
+		case cfg.ZeroNodes:
+			modeErr = runZeroNodes(s, out)
+		case cfg.Benchmark:
+			var report benchmarkReport
+			if report, modeErr = runBenchmark(s, cfg.NewSortFunc(nodes)); modeErr == nil {
+				modeErr = report.Write(out)
+			}
+		default:
+			isMode = false
+		}
+		if isMode {
+			if modeErr != nil {
+				log.Fatal(modeErr)
+			}
+			return exitCode
+		}
+
+		var skippedLines int64
+		s.opts.SkippedLines = &skippedLines
+		reportSkippedLines := func() {
+			// after a timeout, nodes may still be scanned in the background
+			if skipped := atomic.LoadInt64(&skippedLines); skipped > 0 {
+				log.Printf("Skipped %d lines belonging to no entry, -errors-out lists them", skipped)
+			}
+		}
+
+		if cfg.MergeSortBuffer > 0 && cfg.StreamsSorted {
+			now := time.Now()
+			sorted := s
+			sorted.filter = func(entries LogEntries) LogEntries {
+				entries = s.filter(entries)
+				if cfg.WarnFuture || cfg.DropFuture {
+					entries = checkFutureDates(os.Stderr, entries, now, cfg.DropFuture)
+				}
+				return entries
+			}
+			written, collected, err := runMergeSort(sorted, bufio.NewWriterSize(out, cfg.OutputBufferSize), cfg.MergeSortBuffer<<20, formatOpts, cfg.Limit)
+			s.progress.Finish()
+			reportSkippedLines()
+			if errors.Is(err, context.DeadlineExceeded) {
+				log.Print(timeoutNotice(s.timeout, collected))
+			} else if ctx.Err() != nil {
+				log.Printf("Interrupted, partial results: printed %d entries", written)
+				syscall.Exit(130)
+			} else if err != nil {
+				log.Fatal(err)
+			}
+			return exitCode
+		}
+
+		logEntries, err = pipeline.ScanEntries(ctx, s.nodes, s.topLevelDir, s.queries, s.opts, s.timeout)
+		s.progress.Finish()
+		reportSkippedLines()
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Print(timeoutNotice(s.timeout, len(logEntries)))
+		} else if err != nil {
+			log.Printf("Interrupted while scanning logs, no results were printed")
+			syscall.Exit(130)
+		}
+	}
+
+	logEntries = s.filter(logEntries)
+	if cfg.WarnFuture || cfg.DropFuture {
+		logEntries = checkFutureDates(os.Stderr, logEntries, time.Now(), cfg.DropFuture)
+	}
+	// the most recent entries are picked in date order, then sorted as requested below
+	logEntries = pipeline.TailEntries(logEntries, s.tail)
+	if cfg.FailLevel != nil {
+		exitCode = checkFailLevel(os.Stderr, logEntries, *cfg.FailLevel)
+	}
+
+	reported, err := runReport(out, logEntries, cfg.Report, formatOpts, cfg.OutputBufferSize)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if reported {
+		return exitCode
+	}
+
+	if cfg.FirstSource {
+		// firstPerSource already sorts its result by date
+		logEntries = firstPerSource(logEntries, cfg.Report.IgnoreCaseIDs)
+	} else {
+		cfg.NewSortFunc(nodes)(logEntries)
+	}
+
+	if cfg.Summary {
+		err := writeBuffered(out, cfg.OutputBufferSize, func(w io.Writer) error { return writeJSONSummary(w, logEntries, formatOpts) })
+		if err != nil {
+			log.Fatal(err)
+		}
+		return exitCode
+	}
+
+	if cfg.Output == OutputJSON {
+		err := writeBuffered(out, cfg.OutputBufferSize, func(w io.Writer) error { return writeJSONArray(w, logEntries, formatOpts) })
+		if err != nil {
+			log.Fatal(err)
+		}
+		return exitCode
+	}
+
+	written, err := writeEntries(ctx, bufio.NewWriterSize(out, cfg.OutputBufferSize), logEntries, formatOpts, cfg.Limit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if ctx.Err() != nil {
+		log.Printf("Interrupted, partial results: printed %d of %d entries", written, len(logEntries))
+		syscall.Exit(130)
+	}
+	return exitCode
+}
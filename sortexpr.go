@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github/kenjords/wetlog/internal/pipeline"
+	"github/kenjords/wetlog/pkg/wetlog"
+)
+
+// compareFunc compares two entries, returning a negative number if a sorts before b, a positive number if a sorts after
+// b and zero if they are equal.
+type compareFunc func(a, b *LogEntry) int
+
+// sortFields are the fields a sort expression can order by.
+var sortFields = map[string]compareFunc{
+	"date":       func(a, b *LogEntry) int { return a.Date.Compare(b.Date) },
+	"loglevel":   func(a, b *LogEntry) int { return int(a.LogLevel) - int(b.LogLevel) },
+	"linenumber": func(a, b *LogEntry) int { return a.LineNumber - b.LineNumber },
+	"linecount":  func(a, b *LogEntry) int { return a.LineCount - b.LineCount },
+	"nodeip": func(a, b *LogEntry) int {
+		ip1, ip2 := net.ParseIP(a.NodeIP), net.ParseIP(b.NodeIP)
+		switch {
+		case wetlog.NodeIPLess(a.NodeIP, b.NodeIP, ip1, ip2):
+			return -1
+		case wetlog.NodeIPLess(b.NodeIP, a.NodeIP, ip2, ip1):
+			return 1
+		default:
+			return 0
+		}
+	},
+}
+
+// sortFieldCompare returns the comparison for a sort field, including metric:<name> fields.
+func sortFieldCompare(field string) (compareFunc, bool) {
+	if metricName, found := strings.CutPrefix(field, "metric:"); found {
+		if _, ok := pipeline.FindMetricExtractor(metricName); !ok {
+			return nil, false
+		}
+		return func(a, b *LogEntry) int { return pipeline.CompareMetric(metricName, a, b) }, true
+	}
+	compare, ok := sortFields[field]
+	return compare, ok
+}
+
+// ParseSortExpr parses a comma-separated list of field:direction terms, e.g. "loglevel:desc,date:asc", into a function
+// that sorts entries by each field in turn, then like ByDate. The direction is optional and defaults to asc.
+func ParseSortExpr(expr string) (func(LogEntries), error) {
+	var compares []compareFunc
+	for _, term := range strings.Split(expr, ",") {
+		term = strings.TrimSpace(term)
+		field, desc := term, false
+		if i := strings.LastIndex(term, ":"); i >= 0 {
+			switch term[i+1:] {
+			case "asc":
+				field = term[:i]
+			case "desc":
+				field, desc = term[:i], true
+			}
+		}
+
+		compare, ok := sortFieldCompare(field)
+		if !ok {
+			return nil, fmt.Errorf("Invalid sort field: %s", field)
+		}
+		if desc {
+			asc := compare
+			compare = func(a, b *LogEntry) int { return asc(b, a) }
+		}
+		compares = append(compares, compare)
+	}
+
+	return func(entries LogEntries) {
+		sort.SliceStable(entries, func(i, j int) bool {
+			for _, compare := range compares {
+				if c := compare(entries[i], entries[j]); c != 0 {
+					return c < 0
+				}
+			}
+			return wetlog.DateLess(entries[i], entries[j])
+		})
+	}, nil
+}
+
+// sortExprMetrics returns the names of the metric:<name> fields of the sort expression expr.
+func sortExprMetrics(expr string) []string {
+	var names []string
+	for _, term := range strings.Split(expr, ",") {
+		term = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(term), ":asc"), ":desc")
+		if name, found := strings.CutPrefix(term, "metric:"); found {
+			names = append(names, name)
+		}
+	}
+	return names
+}
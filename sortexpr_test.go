@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSortExpr(t *testing.T) {
+	day := time.Date(2023, 7, 14, 0, 0, 0, 0, time.UTC)
+	newEntries := func() LogEntries {
+		return LogEntries{
+			{LogLevel: INFO, Date: day.Add(3 * time.Hour), LineNumber: 1, NodeIP: "10.0.0.2"},
+			{LogLevel: ERROR, Date: day.Add(2 * time.Hour), LineNumber: 2, NodeIP: "10.0.0.1"},
+			{LogLevel: INFO, Date: day.Add(1 * time.Hour), LineNumber: 3, NodeIP: "10.0.0.10"},
+			{LogLevel: ERROR, Date: day.Add(4 * time.Hour), LineNumber: 4, NodeIP: "10.0.0.1"},
+			{LogLevel: WARN, Date: day.Add(5 * time.Hour), LineNumber: 5, NodeIP: "10.0.0.2"},
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		expr        string
+		wantLines   []int
+		expectError bool
+	}{
+		{
+			name:      "level desc then date asc",
+			expr:      "loglevel:desc,date:asc",
+			wantLines: []int{2, 4, 5, 3, 1},
+		},
+		{
+			name:      "node ip then date desc",
+			expr:      "nodeip,date:desc",
+			wantLines: []int{4, 2, 5, 1, 3},
+		},
+		{
+			name:      "single field descending",
+			expr:      "linenumber:desc",
+			wantLines: []int{5, 4, 3, 2, 1},
+		},
+		{
+			name:      "ties broken by date",
+			expr:      "loglevel",
+			wantLines: []int{3, 1, 5, 2, 4},
+		},
+		{
+			name:        "invalid field",
+			expr:        "hostname:asc",
+			expectError: true,
+		},
+		{
+			name:        "invalid direction",
+			expr:        "date:sideways",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sortFunc, err := ParseSortExpr(tc.expr)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("ParseSortExpr() error = %v, expectError %v", err, tc.expectError)
+			}
+			if tc.expectError {
+				return
+			}
+
+			entries := newEntries()
+			sortFunc(entries)
+			for i, entry := range entries {
+				if entry.LineNumber != tc.wantLines[i] {
+					t.Errorf("Expected line %d at index %d, got %d", tc.wantLines[i], i, entry.LineNumber)
+				}
+			}
+		})
+	}
+}
+
+func TestSortExprMetrics(t *testing.T) {
+	got := sortExprMetrics("loglevel:desc, metric:gc_pause_ms:desc,metric:pending_tasks,date")
+	if want := []string{"gc_pause_ms", "pending_tasks"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected metrics %v, got %v", want, got)
+	}
+	if got := sortExprMetrics("date:asc"); got != nil {
+		t.Errorf("Expected no metrics, got %v", got)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+// firstPerSource returns the earliest entry of each distinct source file, sorted by date. Source files are compared
+// ignoring case if ignoreCase is true, and entries without a source file are left out.
+func firstPerSource(entries LogEntries, ignoreCase bool) LogEntries {
+	sorted := append(LogEntries(nil), entries...)
+	sort.Stable(ByDate{LogEntries: sorted})
+
+	var first LogEntries
+	seen := make(map[string]struct{})
+	for _, entry := range sorted {
+		source := entry.SourceFile()
+		if source == "" {
+			continue
+		}
+		key := pipeline.IdentifierKey(source, ignoreCase)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		first = append(first, entry)
+	}
+	return first
+}
+
+// SourceCount is the number of entries logged by a source file.
+type SourceCount struct {
+	Source string // Source is the source file, e.g. "GCInspector.java".
+	Count  int    // Count is the number of entries it logged.
+}
+
+// countSources returns the number of entries of each distinct source file, sorted by source file. Source files are
+// compared ignoring case if ignoreCase is true, keeping the spelling of their first entry, and entries without a source
+// file are left out.
+func countSources(entries LogEntries, ignoreCase bool) []SourceCount {
+	indexes := make(map[string]int)
+	var counts []SourceCount
+	for _, entry := range entries {
+		source := entry.SourceFile()
+		if source == "" {
+			continue
+		}
+		key := pipeline.IdentifierKey(source, ignoreCase)
+		i, ok := indexes[key]
+		if !ok {
+			i = len(counts)
+			indexes[key] = i
+			counts = append(counts, SourceCount{Source: source})
+		}
+		counts[i].Count++
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Source < counts[j].Source })
+	return counts
+}
+
+// PrintSources writes a line per source file with its number of entries to w.
+func PrintSources(w io.Writer, counts []SourceCount) error {
+	for _, count := range counts {
+		if _, err := fmt.Fprintf(w, "%s: %d\n", count.Source, count.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+func TestFirstPerSource(t *testing.T) {
+	start := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	entry := func(offset int, line int, source string) *LogEntry {
+		date := start.Add(time.Duration(offset) * time.Second)
+		return &LogEntry{LogLevel: INFO, Date: date, LineNumber: line,
+			Message: "INFO  [main] " + date.Format("2006-01-02 15:04:05,000") + " " + source + " - message"}
+	}
+	entries := LogEntries{
+		entry(3, 1, "GCInspector.java:282"),
+		entry(1, 2, "CompactionTask.java:250"),
+		entry(2, 3, "GCInspector.java:290"),
+		entry(4, 4, "CompactionTask.java:250"),
+		entry(5, 5, "gcinspector.java:282"),
+		{LogLevel: INFO, Date: start, LineNumber: 6, Message: "no source token"},
+	}
+
+	tests := []struct {
+		name       string
+		ignoreCase bool
+		want       []int
+	}{
+		{name: "case-sensitive", ignoreCase: false, want: []int{2, 3, 5}},
+		{name: "ignore case", ignoreCase: true, want: []int{2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := firstPerSource(entries, tt.ignoreCase)
+			if len(first) != len(tt.want) {
+				t.Fatalf("Expected %d entries, got %d", len(tt.want), len(first))
+			}
+			sources := make(map[string]struct{})
+			for i, entry := range first {
+				if entry.LineNumber != tt.want[i] {
+					t.Errorf("Expected the entry from line %d at index %d, got line %d", tt.want[i], i, entry.LineNumber)
+				}
+				if _, ok := sources[entry.SourceFile()]; ok {
+					t.Errorf("Expected one entry per source, got %s twice", entry.SourceFile())
+				}
+				sources[entry.SourceFile()] = struct{}{}
+			}
+		})
+	}
+}
+
+func TestCountSources(t *testing.T) {
+	topLevelDir := t.TempDir()
+	nodes := []Node{{Address: "10.0.0.1"}, {Address: "10.0.0.2"}}
+	writeSystemLog(t, topLevelDir, "10.0.0.1",
+		"INFO  [main] 2023-07-14 16:00:00,000 StorageService.java:100 - Starting\n"+
+			"WARN  [Service Thread] 2023-07-14 16:00:01,000 GCInspector.java:282 - G1 Young Generation GC in 523ms\n"+
+			"WARN  [Service Thread] 2023-07-14 16:00:02,000 GCInspector.java:282 - G1 Young Generation GC in 612ms\n"+
+			"INFO  [main] 2023-07-14 16:00:03,000 - No source\n")
+	writeSystemLog(t, topLevelDir, "10.0.0.2",
+		"INFO  [main] 2023-07-14 16:00:00,000 StorageService.java:100 - Starting\n"+
+			"INFO  [CompactionExecutor:1] 2023-07-14 16:00:04,000 CompactionTask.java:250 - Compacted\n"+
+			"WARN  [Service Thread] 2023-07-14 16:00:05,000 gcinspector.java:282 - Lowercased by a custom layout\n")
+
+	var entries LogEntries
+	for _, nodeEntries := range pipeline.CollectNodeEntries(context.Background(), nodes, topLevelDir, nil, pipeline.ScanOptions{}) {
+		entries = append(entries, nodeEntries...)
+	}
+	sort.Stable(ByDate{LogEntries: entries})
+
+	tests := []struct {
+		name       string
+		ignoreCase bool
+		want       []SourceCount
+	}{
+		{name: "case-sensitive", want: []SourceCount{{"CompactionTask.java", 1}, {"GCInspector.java", 2}, {"StorageService.java", 2}, {"gcinspector.java", 1}}},
+		{name: "ignore case", ignoreCase: true, want: []SourceCount{{"CompactionTask.java", 1}, {"GCInspector.java", 3}, {"StorageService.java", 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countSources(entries, tt.ignoreCase); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("countSources() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := PrintSources(&buf, countSources(entries, true)); err != nil {
+		t.Fatalf("PrintSources() error = %v", err)
+	}
+	if want := "CompactionTask.java: 1\nGCInspector.java: 3\nStorageService.java: 2\n"; buf.String() != want {
+		t.Errorf("PrintSources() = %q, want %q", buf.String(), want)
+	}
+}
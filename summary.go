@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Summary describes a set of entries for the -summary JSON output and -count.
+type Summary struct {
+	Total  int            `json:"total"`           // Total is the number of entries.
+	Levels map[string]int `json:"levels"`          // Levels counts the entries per log level name.
+	Nodes  map[string]int `json:"nodes"`           // Nodes counts the entries per node address.
+	Start  *time.Time     `json:"start,omitempty"` // Start is the date of the earliest entry, nil without entries.
+	End    *time.Time     `json:"end,omitempty"`   // End is the date of the latest entry, nil without entries.
+}
+
+// summarize counts the entries per level and node and finds the time range they cover.
+func summarize(entries LogEntries) Summary {
+	summary := Summary{
+		Total:  len(entries),
+		Levels: make(map[string]int),
+		Nodes:  make(map[string]int),
+	}
+	for _, entry := range entries {
+		summary.Levels[entry.LogLevel.String()]++
+		summary.Nodes[entry.NodeIP]++
+		if summary.Start == nil || entry.Date.Before(*summary.Start) {
+			start := entry.Date
+			summary.Start = &start
+		}
+		if summary.End == nil || entry.Date.After(*summary.End) {
+			end := entry.Date
+			summary.End = &end
+		}
+	}
+	return summary
+}
+
+// Write renders the summary to w as text for -count: the total, then the counts per level from DEBUG to ERROR and per
+// node by address, leaving out levels without entries.
+func (s Summary) Write(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "Total: %d\nLevels:\n", s.Total); err != nil {
+		return err
+	}
+	for _, level := range []LogLevel{DEBUG, INFO, WARN, ERROR} {
+		if count := s.Levels[level.String()]; count > 0 {
+			if _, err := fmt.Fprintf(w, "  %s: %d\n", level, count); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := fmt.Fprintln(w, "Nodes:"); err != nil {
+		return err
+	}
+	addrs := make([]string, 0, len(s.Nodes))
+	for addr := range s.Nodes {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	for _, addr := range addrs {
+		if _, err := fmt.Fprintf(w, "  %s: %d\n", addr, s.Nodes[addr]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSummaryOnly writes the summary of the entries to w as a single JSON object, without the entries.
+func writeSummaryOnly(w io.Writer, entries LogEntries) error {
+	return json.NewEncoder(w).Encode(summarize(entries))
+}
+
+// writeJSONSummary writes the entries to w as a single JSON object holding their summary and the entries formatted
+// with opts, {"summary":{...},"entries":[...]}.
+func writeJSONSummary(w io.Writer, entries LogEntries, opts FormatOptions) error {
+	payload := struct {
+		Summary Summary    `json:"summary"`
+		Entries LogEntries `json:"entries"`
+	}{summarize(entries), jsonEntries(entries, opts)}
+	return json.NewEncoder(w).Encode(payload)
+}
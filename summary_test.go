@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummaryWrite(t *testing.T) {
+	start := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	entries := LogEntries{
+		{LogLevel: WARN, Date: start, NodeIP: "10.0.0.2"},
+		{LogLevel: ERROR, Date: start, NodeIP: "10.0.0.1"},
+		{LogLevel: WARN, Date: start, NodeIP: "10.0.0.1"},
+		{LogLevel: DEBUG, Date: start, NodeIP: "10.0.0.10"},
+		{LogLevel: WARN, Date: start, NodeIP: "10.0.0.1"},
+	}
+
+	summary := summarize(entries)
+	if summary.Total != 5 {
+		t.Errorf("Expected a total of 5, got %d", summary.Total)
+	}
+	if want := map[string]int{"DEBUG": 1, "WARN": 3, "ERROR": 1}; !reflect.DeepEqual(summary.Levels, want) {
+		t.Errorf("Expected level counts %v, got %v", want, summary.Levels)
+	}
+	if want := map[string]int{"10.0.0.1": 3, "10.0.0.2": 1, "10.0.0.10": 1}; !reflect.DeepEqual(summary.Nodes, want) {
+		t.Errorf("Expected node counts %v, got %v", want, summary.Nodes)
+	}
+
+	var buf bytes.Buffer
+	if err := summary.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := "Total: 5\nLevels:\n  DEBUG: 1\n  WARN: 3\n  ERROR: 1\nNodes:\n  10.0.0.1: 3\n  10.0.0.10: 1\n  10.0.0.2: 1\n"
+	if buf.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := summarize(nil).Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if want := "Total: 0\nLevels:\nNodes:\n"; buf.String() != want {
+		t.Errorf("Expected %q without entries, got %q", want, buf.String())
+	}
+}
+
+func TestWriteSummaryOnly(t *testing.T) {
+	start := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	entries := LogEntries{
+		{LogLevel: ERROR, Date: start, NodeIP: "10.0.0.1", Message: "Exception thrown"},
+		{LogLevel: INFO, Date: start.Add(time.Minute), NodeIP: "10.0.0.2", Message: "Starting"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeSummaryOnly(&buf, entries); err != nil {
+		t.Fatalf("writeSummaryOnly() error = %v", err)
+	}
+
+	var decoded map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", buf.String(), err)
+	}
+	var keys []string
+	for key := range decoded {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if want := []string{"end", "levels", "nodes", "start", "total"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Expected only the summary keys %v, got %v", want, keys)
+	}
+	if strings.Contains(buf.String(), "Exception thrown") || strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("Expected a single summary line without entries, got %q", buf.String())
+	}
+
+	var summary Summary
+	if err := json.Unmarshal(buf.Bytes(), &summary); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", buf.String(), err)
+	}
+	if summary.Total != 2 || summary.Levels["ERROR"] != 1 || summary.Nodes["10.0.0.2"] != 1 || !summary.End.Equal(start.Add(time.Minute)) {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+}
+
+func TestWriteJSONSummary(t *testing.T) {
+	start := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+	entries := LogEntries{
+		{LogLevel: WARN, Date: start.Add(time.Minute), NodeIP: "10.0.0.1", Message: "Slow"},
+		{LogLevel: INFO, Date: start, NodeIP: "10.0.0.1", Message: "Starting"},
+		{LogLevel: WARN, Date: start.Add(2 * time.Minute), NodeIP: "10.0.0.2", Message: "Slow"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSONSummary(&buf, entries, FormatOptions{Format: FormatJSON}); err != nil {
+		t.Fatalf("writeJSONSummary() error = %v", err)
+	}
+
+	var payload struct {
+		Summary Summary           `json:"summary"`
+		Entries []json.RawMessage `json:"entries"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &payload); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", buf.String(), err)
+	}
+
+	if payload.Summary.Total != 3 || len(payload.Entries) != 3 {
+		t.Errorf("Expected 3 entries, got a total of %d and %d entries", payload.Summary.Total, len(payload.Entries))
+	}
+	if want := map[string]int{"INFO": 1, "WARN": 2}; !reflect.DeepEqual(payload.Summary.Levels, want) {
+		t.Errorf("Expected level counts %v, got %v", want, payload.Summary.Levels)
+	}
+	if want := map[string]int{"10.0.0.1": 2, "10.0.0.2": 1}; !reflect.DeepEqual(payload.Summary.Nodes, want) {
+		t.Errorf("Expected node counts %v, got %v", want, payload.Summary.Nodes)
+	}
+	if payload.Summary.Start == nil || !payload.Summary.Start.Equal(start) {
+		t.Errorf("Expected start %v, got %v", start, payload.Summary.Start)
+	}
+	if end := start.Add(2 * time.Minute); payload.Summary.End == nil || !payload.Summary.End.Equal(end) {
+		t.Errorf("Expected end %v, got %v", end, payload.Summary.End)
+	}
+}
+
+func TestSummarizeEmpty(t *testing.T) {
+	summary := summarize(nil)
+	if summary.Total != 0 || summary.Start != nil || summary.End != nil {
+		t.Errorf("Expected an empty summary, got %+v", summary)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Values of the -color flag.
+const (
+	ColorAuto   = "auto"   // ColorAuto colors the output only when it is a terminal.
+	ColorAlways = "always" // ColorAlways colors the output even when it is redirected.
+	ColorNever  = "never"  // ColorNever never colors the output.
+)
+
+// isTerminal returns true if f is an interactive terminal rather than a file or a pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// useColor reports whether output written to f should be colored in the given -color mode, using terminal to detect
+// whether f is a terminal. Every code path emitting ANSI codes must be gated by its result, so that redirected output
+// stays free of escape sequences with -color auto.
+func useColor(mode string, f *os.File, terminal func(*os.File) bool) (bool, error) {
+	switch mode {
+	case ColorAuto:
+		return terminal(f), nil
+	case ColorAlways:
+		return true, nil
+	case ColorNever:
+		return false, nil
+	default:
+		return false, fmt.Errorf("Invalid color mode: %s", mode)
+	}
+}
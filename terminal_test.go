@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if isTerminal(f) {
+		t.Errorf("Expected a regular file not to be a terminal")
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	terminal := func(*os.File) bool { return true }
+	redirected := func(*os.File) bool { return false }
+	tests := []struct {
+		mode     string
+		terminal func(*os.File) bool
+		want     bool
+		wantErr  bool
+	}{
+		{mode: ColorAuto, terminal: terminal, want: true},
+		{mode: ColorAuto, terminal: redirected, want: false},
+		{mode: ColorAlways, terminal: redirected, want: true},
+		{mode: ColorNever, terminal: terminal, want: false},
+		{mode: "sometimes", terminal: terminal, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := useColor(tt.mode, os.Stdout, tt.terminal)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("useColor(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("useColor(%q) = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestColorAutoRedirected(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	color, err := useColor(ColorAuto, w, isTerminal)
+	if err != nil {
+		t.Fatalf("useColor() error = %v", err)
+	}
+	if color {
+		t.Fatalf("Expected no color for a pipe with -color auto")
+	}
+
+	entry := &LogEntry{LogLevel: ERROR, NodeIP: "10.0.0.1", FilePath: "system.log", LineNumber: 3, Message: "Read timeout: 2 replicas timed out"}
+	opts := FormatOptions{Format: FormatText, Color: color, Highlight: highlightRegex([]string{"timeout"}, false)}
+	var buf bytes.Buffer
+	if err := FormatEntry(&buf, entry, opts); err != nil {
+		t.Fatalf("FormatEntry() error = %v", err)
+	}
+	if err := PrintBucketDetail(&buf, bucketEntries(LogEntries{entry}, time.Minute), 1, opts); err != nil {
+		t.Fatalf("PrintBucketDetail() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "\x1b") {
+		t.Errorf("Expected no escape sequences in redirected output, got %q", buf.String())
+	}
+}
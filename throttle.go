@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Values of the -throttle-mode flag.
+const (
+	ThrottleBuffer = "buffer" // ThrottleBuffer holds back the excess entries until their turn comes.
+	ThrottleDrop   = "drop"   // ThrottleDrop leaves out the entries exceeding the rate.
+)
+
+// throttle limits the rate at which entries are emitted by spacing them evenly. A nil throttle lets every entry through
+// at once.
+type throttle struct {
+	interval time.Duration                                   // interval is the minimum time between two entries.
+	drop     bool                                            // drop leaves out entries coming early instead of waiting.
+	next     time.Time                                       // next is the earliest time the next entry may be emitted.
+	now      func() time.Time                                // now returns the current time.
+	wait     func(ctx context.Context, d time.Duration) bool // wait sleeps for d, returning false if ctx is done first.
+}
+
+// newThrottle returns a throttle emitting at most perSecond entries per second, dropping the excess if drop is true.
+func newThrottle(perSecond int, drop bool) *throttle {
+	return &throttle{interval: time.Second / time.Duration(perSecond), drop: drop, now: time.Now, wait: sleepContext}
+}
+
+// sleepContext sleeps for d, returning false if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Allow reports whether the next entry may be emitted. Without drop, it waits for the entry's turn and only returns
+// false if ctx is done meanwhile.
+func (t *throttle) Allow(ctx context.Context) bool {
+	if t == nil {
+		return true
+	}
+	now := t.now()
+	if now.Before(t.next) {
+		if t.drop || !t.wait(ctx, t.next.Sub(now)) {
+			return false
+		}
+		now = t.next
+	}
+	t.next = now.Add(t.interval)
+	return true
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"testing"
+	"time"
+)
+
+// timedWriter records the time of the fake clock at every write.
+type timedWriter struct {
+	now    *time.Time
+	writes []time.Time
+}
+
+func (w *timedWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, *w.now)
+	return len(p), nil
+}
+
+func TestThrottle(t *testing.T) {
+	var entries LogEntries
+	for i := 0; i < 20; i++ {
+		entries = append(entries, &LogEntry{LogLevel: INFO, NodeIP: "10.0.0.1", FilePath: "system.log", LineNumber: i + 1, Message: "Starting"})
+	}
+
+	tests := []struct {
+		name        string
+		drop        bool
+		wantWritten int
+	}{
+		{name: "buffer", drop: false, wantWritten: 20},
+		{name: "drop", drop: true, wantWritten: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the fake clock only moves while the throttle waits, as if the entries arrived all at once
+			now := time.Date(2023, 7, 14, 16, 0, 0, 0, time.UTC)
+			limit := newThrottle(5, tt.drop)
+			limit.now = func() time.Time { return now }
+			limit.wait = func(_ context.Context, d time.Duration) bool {
+				now = now.Add(d)
+				return true
+			}
+
+			out := &timedWriter{now: &now}
+			written, err := writeEntries(context.Background(), bufio.NewWriter(out), entries, FormatOptions{Format: FormatText}, limit)
+			if err != nil {
+				t.Fatalf("writeEntries() error = %v", err)
+			}
+			if written != tt.wantWritten || len(out.writes) != tt.wantWritten {
+				t.Fatalf("Expected %d entries flushed one by one, got %d entries in %d writes", tt.wantWritten, written, len(out.writes))
+			}
+			for i := range out.writes {
+				inWindow := 0
+				for _, other := range out.writes[i:] {
+					if other.Sub(out.writes[i]) < time.Second {
+						inWindow++
+					}
+				}
+				if inWindow > 5 {
+					t.Fatalf("Expected at most 5 entries per second, got %d in the second after %s", inWindow, out.writes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestThrottleCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	limit := newThrottle(1, false)
+	if !limit.Allow(ctx) {
+		t.Fatalf("Expected the first entry to be let through")
+	}
+	if limit.Allow(ctx) {
+		t.Errorf("Expected waiting for the next entry to stop once the context is done")
+	}
+
+	var none *throttle
+	if !none.Allow(ctx) {
+		t.Errorf("Expected a nil throttle to let every entry through")
+	}
+}
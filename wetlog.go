@@ -0,0 +1,24 @@
+package main
+
+import "github/kenjords/wetlog/pkg/wetlog"
+
+// The parsing types live in pkg/wetlog so that other programs can import them, they are aliased here for brevity.
+type (
+	Node         = wetlog.Node
+	LogLevel     = wetlog.LogLevel
+	LogEntry     = wetlog.LogEntry
+	LogEntries   = wetlog.LogEntries
+	ByDate       = wetlog.ByDate
+	ByLogLevel   = wetlog.ByLogLevel
+	ByLineNumber = wetlog.ByLineNumber
+	ByLineCount  = wetlog.ByLineCount
+	ByNodeIP     = wetlog.ByNodeIP
+)
+
+// Log levels, see wetlog.LogLevel.
+const (
+	DEBUG = wetlog.DEBUG
+	INFO  = wetlog.INFO
+	WARN  = wetlog.WARN
+	ERROR = wetlog.ERROR
+)
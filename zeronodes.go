@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+// ZeroNode is a node that produced no matching entries.
+type ZeroNode struct {
+	Address   string // Address is the address of the node.
+	Missing   bool   // Missing is true if none of the log files of the node exist, false if they have no matching entries.
+	Unscanned bool   // Unscanned is true if -timeout stopped the scan before the logs of the node were read whole.
+}
+
+// findZeroNodes returns the nodes without entries in nodeEntries, telling apart the nodes whose log file is missing
+// from those whose log file has no matching entries. The nodes in unscanned come last, and the others are sorted by
+// address.
+func findZeroNodes(nodes []Node, topLevelDir string, opts pipeline.ScanOptions, nodeEntries map[string]LogEntries, unscanned map[string]bool) []ZeroNode {
+	var zeroNodes []ZeroNode
+	for _, node := range nodes {
+		if len(nodeEntries[node.Address]) > 0 {
+			continue
+		}
+		if unscanned[node.Address] {
+			zeroNodes = append(zeroNodes, ZeroNode{Address: node.Address, Unscanned: true})
+			continue
+		}
+		// a path the template can't build was reported by the scan, and counts as missing
+		logFiles, _ := pipeline.NodeLogFiles(node, topLevelDir, opts)
+		missing := true
+		for _, logFile := range logFiles {
+			if _, err := os.Stat(logFile); !os.IsNotExist(err) {
+				missing = false
+			}
+		}
+		zeroNodes = append(zeroNodes, ZeroNode{Address: node.Address, Missing: missing})
+	}
+
+	sort.Slice(zeroNodes, func(i, j int) bool {
+		if zeroNodes[i].Unscanned != zeroNodes[j].Unscanned {
+			return zeroNodes[j].Unscanned
+		}
+		return zeroNodes[i].Address < zeroNodes[j].Address
+	})
+	return zeroNodes
+}
+
+// PrintZeroNodes writes one line per node without matching entries to w, with the reason it has none.
+func PrintZeroNodes(w io.Writer, zeroNodes []ZeroNode) error {
+	for _, node := range zeroNodes {
+		reason := "no matching entries"
+		switch {
+		case node.Unscanned:
+			reason = "not scanned before -timeout"
+		case node.Missing:
+			reason = "log file missing"
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\n", node.Address, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
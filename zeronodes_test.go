@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github/kenjords/wetlog/internal/pipeline"
+)
+
+func TestFindZeroNodes(t *testing.T) {
+	topLevelDir := t.TempDir()
+	writeSystemLog(t, topLevelDir, "10.0.0.1", "WARN  [main] 2023-07-14 16:00:00,000 Server.java:10 - Dropped mutations\n")
+	writeSystemLog(t, topLevelDir, "10.0.0.2", "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n")
+	nodes := []Node{{Address: "10.0.0.3"}, {Address: "10.0.0.2"}, {Address: "10.0.0.1"}}
+
+	nodeEntries := pipeline.CollectNodeEntries(context.Background(), nodes, topLevelDir, []string{"Dropped"}, pipeline.ScanOptions{})
+	got := findZeroNodes(nodes, topLevelDir, pipeline.ScanOptions{}, nodeEntries, nil)
+	want := []ZeroNode{
+		{Address: "10.0.0.2", Missing: false},
+		{Address: "10.0.0.3", Missing: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("findZeroNodes() = %+v, want %+v", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := PrintZeroNodes(&buf, got); err != nil {
+		t.Fatalf("PrintZeroNodes() error = %v", err)
+	}
+	wantOutput := "10.0.0.2: no matching entries\n10.0.0.3: log file missing\n"
+	if buf.String() != wantOutput {
+		t.Errorf("PrintZeroNodes() = %q, want %q", buf.String(), wantOutput)
+	}
+}
+
+func TestFindZeroNodesUnscanned(t *testing.T) {
+	topLevelDir := t.TempDir()
+	writeSystemLog(t, topLevelDir, "10.0.0.1", "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n")
+	writeSystemLog(t, topLevelDir, "10.0.0.3", "INFO  [main] 2023-07-14 16:00:00,000 Server.java:10 - Starting\n")
+	nodes := []Node{{Address: "10.0.0.3"}, {Address: "10.0.0.2"}, {Address: "10.0.0.1"}}
+
+	// -timeout stopped the scan before 10.0.0.1 was read, and 10.0.0.3 has no matching entries
+	got := findZeroNodes(nodes, topLevelDir, pipeline.ScanOptions{}, map[string]LogEntries{}, map[string]bool{"10.0.0.1": true})
+	want := []ZeroNode{
+		{Address: "10.0.0.2", Missing: true},
+		{Address: "10.0.0.3", Missing: false},
+		{Address: "10.0.0.1", Unscanned: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("findZeroNodes() = %+v, want %+v", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := PrintZeroNodes(&buf, got); err != nil {
+		t.Fatalf("PrintZeroNodes() error = %v", err)
+	}
+	wantOutput := "10.0.0.2: log file missing\n10.0.0.3: no matching entries\n10.0.0.1: not scanned before -timeout\n"
+	if buf.String() != wantOutput {
+		t.Errorf("PrintZeroNodes() = %q, want %q", buf.String(), wantOutput)
+	}
+}